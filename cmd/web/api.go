@@ -0,0 +1,674 @@
+package web
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-track/internal/auth"
+	"go-track/internal/branchname"
+	"go-track/internal/model"
+	"go-track/internal/repo"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultPerPage = 50
+	maxPerPage     = 100
+	// maxPage keeps the offset of a page from overflowing.
+	maxPage = math.MaxInt32
+)
+
+type APIError struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+type apiErrorBody struct {
+	Error APIError `json:"error"`
+}
+
+// IsAPIRequest reports whether the request is for the JSON API, which answers
+// with JSON errors instead of text and redirects.
+func IsAPIRequest(c echo.Context) bool {
+	return strings.HasPrefix(c.Path(), "/api/")
+}
+
+// Fail answers with an error in the format of the route, JSON for the API and
+// text for the HTMX pages.
+func Fail(c echo.Context, status int, message string) error {
+	if IsAPIRequest(c) {
+		return c.JSON(status, apiErrorBody{APIError{Status: status, Message: message}})
+	}
+
+	return c.String(status, message)
+}
+
+// apiFail picks the status from the error. Errors of missing rows are not found.
+func apiFail(c echo.Context, status int, err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return Fail(c, http.StatusNotFound, "Not found")
+	}
+
+	return Fail(c, status, err.Error())
+}
+
+type apiPage[T any] struct {
+	Data    []T `json:"data"`
+	Page    int `json:"page"`
+	PerPage int `json:"perPage"`
+	Total   int `json:"total"`
+}
+
+// pageQuery reads the page and perPage query params.
+func pageQuery(c echo.Context) (int, int, error) {
+	page, perPage := 1, defaultPerPage
+
+	if p := c.QueryParam("page"); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 || n > maxPage {
+			return 0, 0, errors.New(fmt.Sprintf("page must be between 1 and %d", maxPage))
+		}
+		page = n
+	}
+	if p := c.QueryParam("perPage"); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 || n > maxPerPage {
+			return 0, 0, errors.New(fmt.Sprintf("perPage must be between 1 and %d", maxPerPage))
+		}
+		perPage = n
+	}
+
+	return page, perPage, nil
+}
+
+// newAPIPage returns the page of a list of total entries. Pages past the last
+// one are rejected, an empty list has an empty page 1.
+func newAPIPage[T any](data []T, page, perPage, total int) (apiPage[T], error) {
+	if lastPage := max(1, (total+perPage-1)/perPage); page > lastPage {
+		return apiPage[T]{}, errors.New(fmt.Sprintf("page must be at most %d", lastPage))
+	}
+
+	return apiPage[T]{
+		Data:    data,
+		Page:    page,
+		PerPage: perPage,
+		Total:   total,
+	}, nil
+}
+
+// paginate returns one page of all, from the page and perPage query params.
+// Lists kept in the database are paged by the queries instead.
+func paginate[T any](c echo.Context, all []T) (apiPage[T], error) {
+	page, perPage, err := pageQuery(c)
+	if err != nil {
+		return apiPage[T]{}, err
+	}
+
+	start := min((page-1)*perPage, len(all))
+	end := min(start+perPage, len(all))
+
+	return newAPIPage(all[start:end], page, perPage, len(all))
+}
+
+type APIProject struct {
+	Id         int     `json:"id"`
+	Name       string  `json:"name"`
+	Repository *string `json:"repository"`
+	Role       string  `json:"role,omitempty"`
+}
+
+type APIColumn struct {
+	Id        int    `json:"id"`
+	Name      string `json:"name"`
+	ItemCount int    `json:"itemCount"`
+}
+
+type APIIssue struct {
+	Id     int64  `json:"id"`
+	Number int    `json:"number"`
+	Url    string `json:"url"`
+}
+
+type APIPullRequest struct {
+	Id     int `json:"id"`
+	Number int `json:"number"`
+}
+
+// APIItem uses null for the GitHub links an item does not have yet.
+type APIItem struct {
+	Id          int             `json:"id"`
+	Name        string          `json:"name"`
+	ColumnID    int             `json:"columnId"`
+	Order       int             `json:"order"`
+	Issue       *APIIssue       `json:"issue"`
+	Branch      *string         `json:"branch"`
+	PullRequest *APIPullRequest `json:"pullRequest"`
+}
+
+func toAPIProject(proj model.Project, settings model.ProjectSettings, role string) APIProject {
+	p := APIProject{Id: proj.Id, Name: proj.Name, Role: role}
+	if settings.HasRepository() {
+		repository := settings.Owner + "/" + settings.Repo
+		p.Repository = &repository
+	}
+
+	return p
+}
+
+func toAPIItem(item model.Item) APIItem {
+	i := APIItem{
+		Id:       item.Id,
+		Name:     item.Name,
+		ColumnID: item.ColumnID,
+		Order:    item.ColumnOrder,
+	}
+	if item.IssueID != -1 {
+		i.Issue = &APIIssue{Id: item.IssueID, Number: item.IssueNumber, Url: item.IssueUrl}
+	}
+	if item.BranchName != "" {
+		branch := item.BranchName
+		i.Branch = &branch
+	}
+	if item.PullRequestNumber != -1 {
+		i.PullRequest = &APIPullRequest{Id: item.PullRequestID, Number: item.PullRequestNumber}
+	}
+
+	return i
+}
+
+// APIListProjectsHandler lists the projects the user has a role in. API tokens
+// only see the project they were created for. The roles are not kept in the
+// database, so the projects are paged after they are filtered.
+func (h *Handler) APIListProjectsHandler(c echo.Context) error {
+	session, _ := auth.SessionFromContext(c.Request().Context())
+	token, isToken := auth.APITokenFromContext(c.Request().Context())
+
+	all, err := h.projectRepo.GetAll()
+	if err != nil {
+		return apiFail(c, http.StatusInternalServerError, err)
+	}
+
+	projects := make([]APIProject, 0, len(all))
+	for _, proj := range all {
+		if isToken && token.ProjectID != proj.Id {
+			continue
+		}
+
+		role, err := h.memberRepo.GetRole(proj.Id, session.User())
+		if err != nil {
+			return apiFail(c, http.StatusInternalServerError, err)
+		}
+		if role == "" {
+			continue
+		}
+		if isToken && model.RoleAtLeast(role, token.MaxRole()) {
+			role = token.MaxRole()
+		}
+
+		settings, err := h.projectRepo.GetSettings(proj.Id)
+		if err != nil {
+			return apiFail(c, http.StatusInternalServerError, err)
+		}
+
+		projects = append(projects, toAPIProject(proj, settings, role))
+	}
+
+	page, err := paginate(c, projects)
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
+func (h *Handler) APIGetProjectHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	proj, err := h.projectRepo.GetProject(id)
+	if err != nil {
+		return apiFail(c, http.StatusInternalServerError, err)
+	}
+
+	settings, err := h.projectRepo.GetSettings(id)
+	if err != nil {
+		return apiFail(c, http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, toAPIProject(proj, settings, projectRole(c)))
+}
+
+func (h *Handler) APIListColumnsHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	page, perPage, err := pageQuery(c)
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	cols, total, err := h.columnRepo.GetPage(id, perPage, (page-1)*perPage)
+	if err != nil {
+		return apiFail(c, http.StatusInternalServerError, err)
+	}
+
+	columns := make([]APIColumn, len(cols))
+	for i, col := range cols {
+		columns[i] = APIColumn{Id: col.Id, Name: col.Name, ItemCount: len(col.Items)}
+	}
+
+	body, err := newAPIPage(columns, page, perPage, total)
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, body)
+}
+
+// APIListItemsHandler lists the items of the project ordered by column and
+// position, optionally only those in the column given by the column param.
+func (h *Handler) APIListItemsHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	colID := -1
+	if c.QueryParam("column") != "" {
+		colID, err = strconv.Atoi(c.QueryParam("column"))
+		if err != nil {
+			return Fail(c, http.StatusBadRequest, err.Error())
+		}
+	}
+
+	page, perPage, err := pageQuery(c)
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	all, total, err := h.itemRepo.GetPage(id, colID, perPage, (page-1)*perPage)
+	if err != nil {
+		return apiFail(c, http.StatusInternalServerError, err)
+	}
+
+	items := make([]APIItem, len(all))
+	for i, item := range all {
+		items[i] = toAPIItem(item)
+	}
+
+	body, err := newAPIPage(items, page, perPage, total)
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, body)
+}
+
+func (h *Handler) APIGetItemHandler(c echo.Context) error {
+	itemID, err := strconv.Atoi(c.Param("itemID"))
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	item, err := h.itemRepo.Get(itemID)
+	if err != nil {
+		return apiFail(c, http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, toAPIItem(item))
+}
+
+type APICreateItemRequest struct {
+	Name     string `json:"name"`
+	ColumnID int    `json:"columnId"`
+}
+
+func (h *Handler) APICreateItemHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	var req APICreateItemRequest
+	if err := c.Bind(&req); err != nil {
+		return Fail(c, http.StatusBadRequest, "The request body is not valid JSON")
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return Fail(c, http.StatusBadRequest, "name is required")
+	}
+
+	col, err := h.columnRepo.Get(req.ColumnID)
+	if err != nil || col.ProjectID != id {
+		return Fail(c, http.StatusBadRequest, "columnId is not a column of the project")
+	}
+
+	item, err := h.columnRepo.AddItem(req.Name, col.Id)
+	if err != nil {
+		return apiFail(c, http.StatusInternalServerError, err)
+	}
+	h.broadcastColumns(c, id, col.Id)
+
+	return c.JSON(http.StatusCreated, toAPIItem(item))
+}
+
+func (h *Handler) APIDeleteItemHandler(c echo.Context) error {
+	itemID, err := strconv.Atoi(c.Param("itemID"))
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	item, err := h.itemRepo.Get(itemID)
+	if err != nil {
+		return apiFail(c, http.StatusInternalServerError, err)
+	}
+
+	col, err := h.columnRepo.RemoveItem(item.Id, item.ColumnID)
+	if err != nil {
+		return apiFail(c, http.StatusInternalServerError, err)
+	}
+	h.broadcastColumns(c, col.ProjectID, col.Id)
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+type APIMoveItemRequest struct {
+	Direction string `json:"direction"`
+}
+
+// APIMoveItemHandler moves the item, and runs the workflow of the column it
+// enters like the board does. Steps needing input, like creating a branch, are
+// left to their own endpoints.
+func (h *Handler) APIMoveItemHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+	itemID, err := strconv.Atoi(c.Param("itemID"))
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	var req APIMoveItemRequest
+	if err := c.Bind(&req); err != nil {
+		return Fail(c, http.StatusBadRequest, "The request body is not valid JSON")
+	}
+
+	oldItem, err := h.itemRepo.Get(itemID)
+	if err != nil {
+		return apiFail(c, http.StatusInternalServerError, err)
+	}
+
+	item, err := h.itemRepo.Move(id, itemID, req.Direction)
+	if err != nil {
+		return apiFail(c, http.StatusBadRequest, err)
+	}
+	h.broadcastColumns(c, id, oldItem.ColumnID, item.ColumnID)
+
+	if item.ColumnID != oldItem.ColumnID {
+		item, _, err = h.userItemRepo(c).EnterColumn(id, item)
+		if errors.Is(err, repo.ErrNoRepository) {
+			return Fail(c, http.StatusBadRequest, err.Error())
+		}
+		if err != nil {
+			return apiFail(c, http.StatusBadGateway, err)
+		}
+	}
+
+	return c.JSON(http.StatusOK, toAPIItem(item))
+}
+
+func (h *Handler) APICreateIssueHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+	itemID, err := strconv.Atoi(c.Param("itemID"))
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	settings, err := h.repositorySettings(id)
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	item, err := h.itemRepo.Get(itemID)
+	if err != nil {
+		return apiFail(c, http.StatusInternalServerError, err)
+	}
+	if item.IssueID != -1 {
+		return Fail(c, http.StatusConflict, "The item already has an issue")
+	}
+
+	item, err = h.userItemRepo(c).CreateIssue(settings.Owner, settings.Repo, item)
+	if err != nil {
+		return apiFail(c, http.StatusBadGateway, err)
+	}
+	h.broadcastColumns(c, id, item.ColumnID)
+
+	return c.JSON(http.StatusCreated, toAPIItem(item))
+}
+
+type APICreateBranchRequest struct {
+	// Name defaults to the branch template of the project.
+	Name string `json:"name,omitempty"`
+	// Source defaults to the default branch.
+	Source string `json:"source,omitempty"`
+	// DraftPullRequest opens a draft pull request for the new branch. GitHub
+	// needs a commit to open it, so an empty commit is added to the branch.
+	// The branch is deleted again when the pull request can not be opened.
+	DraftPullRequest bool   `json:"draftPullRequest,omitempty"`
+	DraftBase        string `json:"draftBase,omitempty"`
+}
+
+func (h *Handler) APICreateBranchHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+	itemID, err := strconv.Atoi(c.Param("itemID"))
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	var req APICreateBranchRequest
+	if err := c.Bind(&req); err != nil {
+		return Fail(c, http.StatusBadRequest, "The request body is not valid JSON")
+	}
+
+	settings, err := h.repositorySettings(id)
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	item, err := h.itemRepo.Get(itemID)
+	if err != nil {
+		return apiFail(c, http.StatusInternalServerError, err)
+	}
+	if item.BranchName != "" {
+		return Fail(c, http.StatusConflict, "The item already has a branch")
+	}
+
+	if req.Name == "" {
+		req.Name = branchname.FromTemplate(settings.BranchTemplate, item)
+	}
+	if err := branchname.Validate(req.Name); err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	if req.Source == "" {
+		req.Source, err = h.branchRepo.GetDefaultName(settings.Owner, settings.Repo, settings)
+		if err != nil {
+			return apiFail(c, http.StatusBadGateway, err)
+		}
+	}
+
+	source, err := h.branchRepo.Get(settings.Owner, settings.Repo, req.Source)
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	items := h.userItemRepo(c)
+	if req.DraftPullRequest {
+		if req.DraftBase == "" {
+			req.DraftBase = req.Source
+		}
+		item, err = items.CreateBranchWithDraftPullRequest(settings.Owner, settings.Repo, req.Name, source.Sha, req.DraftBase, itemID)
+	} else {
+		item, err = items.CreateBranch(settings.Owner, settings.Repo, req.Name, source.Sha, itemID)
+	}
+	if err != nil {
+		return apiFail(c, http.StatusBadGateway, err)
+	}
+	h.broadcastColumns(c, id, item.ColumnID)
+
+	return c.JSON(http.StatusCreated, toAPIItem(item))
+}
+
+type APICreatePullRequestRequest struct {
+	// Head defaults to the branch of the item.
+	Head string `json:"head,omitempty"`
+	// Base defaults to the default branch.
+	Base string `json:"base,omitempty"`
+}
+
+// APICreatePullRequestHandler opens a pull request for the item, or marks its
+// draft pull request ready for review.
+func (h *Handler) APICreatePullRequestHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+	itemID, err := strconv.Atoi(c.Param("itemID"))
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	var req APICreatePullRequestRequest
+	if err := c.Bind(&req); err != nil {
+		return Fail(c, http.StatusBadRequest, "The request body is not valid JSON")
+	}
+
+	settings, err := h.repositorySettings(id)
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	item, err := h.itemRepo.Get(itemID)
+	if err != nil {
+		return apiFail(c, http.StatusInternalServerError, err)
+	}
+
+	items := h.userItemRepo(c)
+	if item.PullRequestNumber != -1 {
+		item, err = items.MarkReadyForReview(settings.Owner, settings.Repo, itemID)
+		if err != nil {
+			return apiFail(c, http.StatusBadGateway, err)
+		}
+		h.broadcastColumns(c, id, item.ColumnID)
+
+		return c.JSON(http.StatusOK, toAPIItem(item))
+	}
+
+	if req.Head == "" {
+		req.Head = item.BranchName
+	}
+	if req.Head == "" {
+		return Fail(c, http.StatusBadRequest, "The item has no branch, create one or give a head branch")
+	}
+	if req.Base == "" {
+		req.Base, err = h.branchRepo.GetDefaultName(settings.Owner, settings.Repo, settings)
+		if err != nil {
+			return apiFail(c, http.StatusBadGateway, err)
+		}
+	}
+
+	item, err = items.CreatePullRequest(settings.Owner, settings.Repo, req.Head, req.Base, false, itemID)
+	if err != nil {
+		return apiFail(c, http.StatusBadGateway, err)
+	}
+	h.broadcastColumns(c, id, item.ColumnID)
+
+	return c.JSON(http.StatusCreated, toAPIItem(item))
+}
+
+type APIMergeRequest struct {
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message,omitempty"`
+	// Method defaults to the merge method of the project.
+	Method string `json:"method,omitempty"`
+	// Sha is the head the merge was checked at. The merge fails when the pull
+	// request has moved on.
+	Sha           string `json:"sha"`
+	DeleteBranch  bool   `json:"deleteBranch,omitempty"`
+	OverrideBlock bool   `json:"overrideBlock,omitempty"`
+}
+
+func (h *Handler) APIMergeHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+	itemID, err := strconv.Atoi(c.Param("itemID"))
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	var req APIMergeRequest
+	if err := c.Bind(&req); err != nil {
+		return Fail(c, http.StatusBadRequest, "The request body is not valid JSON")
+	}
+
+	if req.Sha == "" {
+		return Fail(c, http.StatusBadRequest, "The head SHA of the pull request is required")
+	}
+
+	settings, err := h.repositorySettings(id)
+	if err != nil {
+		return Fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	item, err := h.itemRepo.Get(itemID)
+	if err != nil {
+		return apiFail(c, http.StatusInternalServerError, err)
+	}
+	if item.PullRequestNumber == -1 {
+		return Fail(c, http.StatusBadRequest, "The item has no pull request")
+	}
+
+	if req.Method == "" {
+		req.Method = settings.MergeMethod
+	}
+	if !model.IsMergeMethod(req.Method) {
+		return Fail(c, http.StatusBadRequest, fmt.Sprintf("Unknown merge method: '%s'", req.Method))
+	}
+	if !mergeMethodAllowed(c, settings, req.Method) {
+		return Fail(c, http.StatusForbidden, fmt.Sprintf("Only admins of the project can merge with another method than '%s'", settings.MergeMethod))
+	}
+
+	readiness, err := h.prRepo.GetMergeReadiness(settings.Owner, settings.Repo, item.PullRequestNumber)
+	if err != nil {
+		return apiFail(c, http.StatusBadGateway, err)
+	}
+
+	if readiness.Blocked() && !(req.OverrideBlock && isProjectAdmin(c)) {
+		return Fail(c, http.StatusConflict, fmt.Sprintf("Merging is blocked: %s", strings.Join(readiness.BlockedReasons, " ")))
+	}
+
+	item, err = h.userItemRepo(c).MergePullRequest(settings.Owner, settings.Repo, req.Title, req.Message, req.Method, req.Sha, item.PullRequestNumber, req.DeleteBranch, itemID)
+	if err != nil {
+		return apiFail(c, http.StatusBadGateway, err)
+	}
+	h.broadcastColumns(c, id, item.ColumnID)
+
+	return c.JSON(http.StatusOK, toAPIItem(item))
+}
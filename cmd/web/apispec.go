@@ -0,0 +1,207 @@
+package web
+
+import (
+	"go-track/internal/auth"
+	"go-track/internal/model"
+	"go-track/internal/openapi"
+	"net/http"
+	"strconv"
+)
+
+const OpenAPIPath = "/api/openapi.json"
+
+func intParam(name, in, description string) openapi.Parameter {
+	return openapi.Parameter{
+		Name:        name,
+		In:          in,
+		Description: description,
+		Required:    in == "path",
+		Schema:      &openapi.Schema{Type: "integer"},
+	}
+}
+
+func bounded(min, max int) *openapi.Schema {
+	s := &openapi.Schema{Type: "integer", Minimum: &min}
+	if max > 0 {
+		s.Maximum = &max
+	}
+	return s
+}
+
+var (
+	projectParam = intParam("id", "path", "The id of the project")
+	itemParam    = intParam("itemID", "path", "The id of the item")
+	pageParams   = []openapi.Parameter{
+		{Name: "page", In: "query", Description: "The page to return, starting at 1", Schema: bounded(1, maxPage)},
+		{Name: "perPage", In: "query", Description: "The number of results per page", Schema: bounded(1, maxPerPage)},
+	}
+)
+
+// jsonBody describes a JSON request body, which can be left out when all its
+// properties are optional.
+func jsonBody(v any) *openapi.RequestBody {
+	schema := openapi.SchemaOf(v)
+	return &openapi.RequestBody{Required: len(schema.Required) > 0, Content: openapi.JSON(schema)}
+}
+
+// responses describes the success response, and errors in the API error format
+// for every other status.
+func responses(status int, description string, schema *openapi.Schema) map[string]openapi.Response {
+	success := openapi.Response{Description: description}
+	if schema != nil {
+		success.Content = openapi.JSON(schema)
+	}
+
+	return map[string]openapi.Response{
+		strconv.Itoa(status): success,
+		"default": {
+			Description: "The request failed",
+			Content:     openapi.JSON(openapi.SchemaOf(apiErrorBody{})),
+		},
+	}
+}
+
+// APIDocument describes the JSON API. It is served for clients and used to
+// validate the requests and responses of the API.
+func APIDocument() *openapi.Document {
+	doc := &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title:       "go-track API",
+			Version:     "1",
+			Description: "Errors are returned as {\"error\": {\"status\", \"message\"}}. Lists are paginated with the page and perPage query parameters.",
+		},
+		Components: openapi.Components{
+			SecuritySchemes: map[string]openapi.SecurityScheme{
+				"apiToken": {
+					Type:        "http",
+					Scheme:      "bearer",
+					Description: "A personal API token, created on the API tokens page",
+				},
+				"session": {
+					Type:        "apiKey",
+					In:          "cookie",
+					Name:        auth.SessionCookie,
+					Description: "The session of the browser. Requests changing state also need the " + auth.CSRFHeader + " header",
+				},
+			},
+		},
+		Security: []openapi.Requirement{{"apiToken": {}}, {"session": {}}},
+	}
+
+	project := openapi.SchemaOf(APIProject{})
+	item := openapi.SchemaOf(APIItem{})
+
+	doc.Add(http.MethodGet, OpenAPIPath, &openapi.Operation{
+		OperationID: "getOpenAPIDocument",
+		Summary:     "This document",
+		Tags:        []string{"meta"},
+		Responses:   responses(http.StatusOK, "The OpenAPI document", &openapi.Schema{Type: "object"}),
+		Security:    []openapi.Requirement{{}},
+	})
+
+	doc.Add(http.MethodGet, "/api/v1/projects", &openapi.Operation{
+		OperationID: "listProjects",
+		Summary:     "List the projects the user has a role in",
+		Tags:        []string{"projects"},
+		Parameters:  pageParams,
+		Responses:   responses(http.StatusOK, "A page of projects", openapi.SchemaOf(apiPage[APIProject]{})),
+	})
+	doc.Add(http.MethodGet, "/api/v1/projects/:id", &openapi.Operation{
+		OperationID: "getProject",
+		Summary:     "Get a project",
+		Tags:        []string{"projects"},
+		Parameters:  []openapi.Parameter{projectParam},
+		Responses:   responses(http.StatusOK, "The project", project),
+	})
+	doc.Add(http.MethodGet, "/api/v1/projects/:id/columns", &openapi.Operation{
+		OperationID: "listColumns",
+		Summary:     "List the columns of a project",
+		Tags:        []string{"columns"},
+		Parameters:  append([]openapi.Parameter{projectParam}, pageParams...),
+		Responses:   responses(http.StatusOK, "A page of columns", openapi.SchemaOf(apiPage[APIColumn]{})),
+	})
+
+	doc.Add(http.MethodGet, "/api/v1/projects/:id/items", &openapi.Operation{
+		OperationID: "listItems",
+		Summary:     "List the items of a project, by column and position",
+		Tags:        []string{"items"},
+		Parameters: append([]openapi.Parameter{
+			projectParam,
+			intParam("column", "query", "Only list the items of the column"),
+		}, pageParams...),
+		Responses: responses(http.StatusOK, "A page of items", openapi.SchemaOf(apiPage[APIItem]{})),
+	})
+	doc.Add(http.MethodPost, "/api/v1/projects/:id/items", &openapi.Operation{
+		OperationID: "createItem",
+		Summary:     "Add an item to a column",
+		Tags:        []string{"items"},
+		Parameters:  []openapi.Parameter{projectParam},
+		RequestBody: jsonBody(APICreateItemRequest{}),
+		Responses:   responses(http.StatusCreated, "The new item", item),
+	})
+	doc.Add(http.MethodGet, "/api/v1/projects/:id/items/:itemID", &openapi.Operation{
+		OperationID: "getItem",
+		Summary:     "Get an item",
+		Tags:        []string{"items"},
+		Parameters:  []openapi.Parameter{projectParam, itemParam},
+		Responses:   responses(http.StatusOK, "The item", item),
+	})
+	doc.Add(http.MethodDelete, "/api/v1/projects/:id/items/:itemID", &openapi.Operation{
+		OperationID: "deleteItem",
+		Summary:     "Delete an item",
+		Tags:        []string{"items"},
+		Parameters:  []openapi.Parameter{projectParam, itemParam},
+		Responses:   responses(http.StatusNoContent, "The item was deleted", nil),
+	})
+
+	move := jsonBody(APIMoveItemRequest{})
+	move.Content["application/json"].Schema.Properties["direction"].Enum = []string{"up", "down", "left", "right"}
+	doc.Add(http.MethodPost, "/api/v1/projects/:id/items/:itemID/move", &openapi.Operation{
+		OperationID: "moveItem",
+		Summary:     "Move an item within its column or to the next one, running the workflow of the column like the board",
+		Tags:        []string{"items"},
+		Parameters:  []openapi.Parameter{projectParam, itemParam},
+		RequestBody: move,
+		Responses:   responses(http.StatusOK, "The moved item", item),
+	})
+
+	doc.Add(http.MethodPost, "/api/v1/projects/:id/items/:itemID/issue", &openapi.Operation{
+		OperationID: "createIssue",
+		Summary:     "Open an issue for the item",
+		Tags:        []string{"github"},
+		Parameters:  []openapi.Parameter{projectParam, itemParam},
+		Responses:   responses(http.StatusCreated, "The item with its issue", item),
+	})
+	doc.Add(http.MethodPost, "/api/v1/projects/:id/items/:itemID/branch", &openapi.Operation{
+		OperationID: "createBranch",
+		Summary:     "Create a branch for the item, and optionally a draft pull request",
+		Tags:        []string{"github"},
+		Parameters:  []openapi.Parameter{projectParam, itemParam},
+		RequestBody: jsonBody(APICreateBranchRequest{}),
+		Responses:   responses(http.StatusCreated, "The item with its branch", item),
+	})
+	pullRequest := responses(http.StatusCreated, "The item with its new pull request", item)
+	pullRequest["200"] = openapi.Response{Description: "The item with its pull request marked ready for review", Content: openapi.JSON(item)}
+	doc.Add(http.MethodPost, "/api/v1/projects/:id/items/:itemID/pull-request", &openapi.Operation{
+		OperationID: "createPullRequest",
+		Summary:     "Open a pull request for the item, or mark its draft ready for review",
+		Tags:        []string{"github"},
+		Parameters:  []openapi.Parameter{projectParam, itemParam},
+		RequestBody: jsonBody(APICreatePullRequestRequest{}),
+		Responses:   pullRequest,
+	})
+
+	merge := jsonBody(APIMergeRequest{})
+	merge.Content["application/json"].Schema.Properties["method"].Enum = model.MergeMethods
+	doc.Add(http.MethodPost, "/api/v1/projects/:id/items/:itemID/merge", &openapi.Operation{
+		OperationID: "mergePullRequest",
+		Summary:     "Merge the pull request of the item",
+		Tags:        []string{"github"},
+		Parameters:  []openapi.Parameter{projectParam, itemParam},
+		RequestBody: merge,
+		Responses:   responses(http.StatusOK, "The item", item),
+	})
+
+	return doc
+}
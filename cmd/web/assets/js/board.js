@@ -0,0 +1,38 @@
+/**
+ * Keeps the board up to date with the changes of others. The server sends the
+ * HTML of every column an item changed in, which replaces the column with the
+ * same id.
+ */
+(function () {
+  const container = document.getElementById("columns-container");
+  if (!container || !window.EventSource) {
+    return;
+  }
+
+  let disconnected = false;
+  const source = new EventSource(container.dataset.events);
+
+  source.addEventListener("column", (event) => {
+    const template = document.createElement("template");
+    template.innerHTML = event.data.trim();
+    const column = template.content.firstElementChild;
+    if (!column || !document.getElementById(column.id)) {
+      return;
+    }
+
+    htmx.swap(`#${column.id}`, column.outerHTML, { swapStyle: "outerHTML" });
+  });
+
+  source.addEventListener("error", () => {
+    disconnected = true;
+  });
+
+  // changes made while the stream was down were missed, so load the whole
+  // board once it is back
+  source.addEventListener("open", () => {
+    if (disconnected) {
+      disconnected = false;
+      htmx.ajax("GET", container.dataset.columns, container);
+    }
+  });
+})();
@@ -1,43 +1,131 @@
 package web
 
 import (
+	"errors"
 	view "go-track/cmd/web/view"
 	"go-track/internal/auth"
+	"go-track/internal/model"
+	"go-track/internal/repo"
+	"log"
 	"net/http"
-	"time"
+	"net/url"
 
 	"github.com/a-h/templ"
 	"github.com/labstack/echo/v4"
 )
 
+const oauthStateCookie = "oauthState"
+
+// SignInHandler starts the OAuth flow. The state and PKCE verifier are kept in
+// a short lived signed cookie, which is checked when GitHub calls back.
 func (h *Handler) SignInHandler(c echo.Context) error {
-	authUrl := h.authRepo.GetAuthUrl()
+	state, err := auth.NewOAuthState(c.QueryParam("redirect"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	stateString, err := auth.SignOAuthState(state)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	http.SetCookie(c.Response().Writer, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    stateString,
+		MaxAge:   int(auth.OAuthStateLifetime.Seconds()),
+		Path:     "/auth",
+		HttpOnly: true,
+		Secure:   SecureCookies(c),
+		SameSite: http.SameSiteLaxMode,
+	})
 
-	return view.SignIn(templ.SafeURL(authUrl)).Render(c.Request().Context(), c.Response().Writer)
+	authUrl := h.authRepo.GetAuthUrl(state.Nonce, state.CodeChallenge())
+
+	return view.SignIn(templ.SafeURL(authUrl), c.QueryParam("error")).Render(c.Request().Context(), c.Response().Writer)
+}
+
+func signInError(c echo.Context, message string, redirect string) error {
+	query := url.Values{}
+	query.Set("error", message)
+	if redirect != "" && redirect != "/" {
+		query.Set("redirect", redirect)
+	}
+
+	return c.Redirect(http.StatusTemporaryRedirect, "/sign-in?"+query.Encode())
 }
 
 func (h *Handler) GithubAuthCallbackHandler(c echo.Context) error {
-	code := c.QueryParam("code")
+	stateCookie, err := c.Request().Cookie(oauthStateCookie)
+	if err != nil {
+		return signInError(c, "Your sign in expired. Please try again.", "")
+	}
+
+	http.SetCookie(c.Response().Writer, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    "",
+		MaxAge:   -1,
+		Path:     "/auth",
+		HttpOnly: true,
+		Secure:   SecureCookies(c),
+		SameSite: http.SameSiteLaxMode,
+	})
 
-	user, err := h.authRepo.AuthorizeUser(code)
+	state, err := auth.ParseOAuthState(stateCookie.Value)
+	if err != nil {
+		return signInError(c, "Your sign in expired. Please try again.", "")
+	}
+
+	if !state.Matches(c.QueryParam("state")) {
+		return signInError(c, "Your sign in could not be verified. Please try again.", state.Redirect)
+	}
+
+	if c.QueryParam("error") != "" {
+		return signInError(c, c.QueryParam("error_description"), state.Redirect)
+	}
+
+	user, err := h.authRepo.AuthorizeUser(c.QueryParam("code"), state.Verifier)
+	if err != nil {
+		log.Printf("Signing in with GitHub failed: %s\n", err)
+		return signInError(c, "Signing in with GitHub failed. Please try again.", state.Redirect)
+	}
+
+	err = h.accessRepo.Check(user)
+	if errors.Is(err, repo.ErrAccessDenied) {
+		// the GitHub token of the user is not kept when they may not sign in
+		err = h.sessionRepo.RevokeAll(user)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+		return c.Redirect(http.StatusTemporaryRedirect, "/not-authorized")
+	}
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
 
-	jwtString, err := auth.SignAuthSession(auth.AuthSession{
-		Username: user.Username,
-		OrgUrl:   user.OrgUrl,
-	})
+	jwtString, err := h.sessionRepo.Create(user, c.Request().UserAgent())
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
 
-	http.SetCookie(c.Response().Writer, &http.Cookie{
-		Name:   "authSession",
-		Value:  jwtString,
-		MaxAge: int(24 * time.Hour),
-		Path:   "/",
-	})
+	setSessionCookie(c, jwtString, int(auth.SessionLifetime().Seconds()))
+
+	return c.Redirect(http.StatusTemporaryRedirect, state.Redirect)
+}
+
+func (h *Handler) NotAuthorizedHandler(c echo.Context) error {
+	c.Response().WriteHeader(http.StatusForbidden)
+	return view.NotAuthorized().Render(c.Request().Context(), c.Response().Writer)
+}
+
+// isProjectAdmin reports whether the signed in user is an admin of the project
+// of the request, which allows overriding merge blocks.
+func isProjectAdmin(c echo.Context) bool {
+	return projectRole(c) == model.RoleAdmin
+}
 
-	return c.Redirect(http.StatusTemporaryRedirect, "/")
+// mergeMethodAllowed reports whether the user may merge with the method. The
+// method of the project is used when none is given, and only admins may pick
+// another one.
+func mergeMethodAllowed(c echo.Context, settings model.ProjectSettings, method string) bool {
+	return method == "" || method == settings.MergeMethod || isProjectAdmin(c)
 }
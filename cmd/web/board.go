@@ -0,0 +1,42 @@
+package web
+
+import (
+	"bytes"
+	view "go-track/cmd/web/view"
+	"log"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ColumnEvent carries the HTML of a column, to be swapped in for the column
+// with the same id.
+const ColumnEvent = "column"
+
+// Broadcaster sends board updates to everyone with the project open.
+type Broadcaster interface {
+	Broadcast(projID int, name, data string)
+}
+
+// broadcastColumns sends the columns as they are now to everyone with the
+// project open, after items in them changed. The change itself has succeeded,
+// so failing to send it is only logged.
+func (h *Handler) broadcastColumns(c echo.Context, projID int, colIDs ...int) {
+	slices.Sort(colIDs)
+	for _, colID := range slices.Compact(colIDs) {
+		col, err := h.columnRepo.Get(colID)
+		if err != nil {
+			log.Printf("Getting column %d to broadcast failed: %s\n", colID, err)
+			continue
+		}
+
+		var html bytes.Buffer
+		err = view.ProjectColumn(col).Render(c.Request().Context(), &html)
+		if err != nil {
+			log.Printf("Rendering column %d to broadcast failed: %s\n", colID, err)
+			continue
+		}
+
+		h.board.Broadcast(projID, ColumnEvent, html.String())
+	}
+}
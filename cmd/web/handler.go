@@ -1,25 +1,90 @@
 package web
 
 import (
+	"go-track/internal/auth"
 	"go-track/internal/db"
 	"go-track/internal/github"
+	"go-track/internal/model"
 	"go-track/internal/repo"
+	"log"
+
+	"github.com/labstack/echo/v4"
 )
 
 type Handler struct {
+	db db.DatabaseFacade
+	gh github.GithubService
+
 	projectRepo repo.ProjectRepository
 	columnRepo  repo.ColumnRepository
 	itemRepo    repo.ItemRepository
 	branchRepo  repo.BranchRepository
+	prRepo      repo.PullRequestRepository
 	authRepo    repo.AuthRepository
+	installRepo repo.InstallationRepository
+	memberRepo  repo.MemberRepository
+	sessionRepo repo.SessionRepository
+	tokenRepo   repo.APITokenRepository
+	accessRepo  repo.AccessRepository
+	healthRepo  repo.HealthRepository
+
+	board Broadcaster
 }
 
-func NewHandler(db db.DatabaseFacade, gh github.GithubService) *Handler {
+func NewHandler(db db.DatabaseFacade, gh github.GithubService, sessions repo.SessionRepository, apiTokens repo.APITokenRepository, access repo.AccessRepository, board Broadcaster) *Handler {
 	return &Handler{
+		db: db,
+		gh: gh,
+
 		projectRepo: repo.NewProjectRepo(db),
 		columnRepo:  repo.NewColumnRepo(db),
 		itemRepo:    repo.NewItemRepo(db, gh),
 		branchRepo:  repo.NewBranchRepo(gh),
-		authRepo:    repo.NewAuthRepo(gh),
+		prRepo:      repo.NewPullRequestRepo(gh),
+		authRepo:    repo.NewAuthRepo(db, gh),
+		installRepo: repo.NewInstallationRepo(gh),
+		memberRepo:  repo.NewMemberRepo(db, gh),
+		sessionRepo: sessions,
+		tokenRepo:   apiTokens,
+		accessRepo:  access,
+		healthRepo:  repo.NewHealthRepo(db, gh),
+
+		board: board,
+	}
+}
+
+// repositorySettings returns the settings of the project, failing when no
+// GitHub repository has been picked for the project yet.
+func (h *Handler) repositorySettings(projID int) (model.ProjectSettings, error) {
+	settings, err := h.projectRepo.GetSettings(projID)
+	if err != nil {
+		return model.ProjectSettings{}, err
+	}
+
+	if !settings.HasRepository() {
+		return model.ProjectSettings{}, repo.ErrNoRepository
+	}
+
+	return settings, nil
+}
+
+// userItemRepo returns an item repo acting as the signed in user, so issues and
+// pull requests are authored by the user and the permissions of the user apply.
+// The app is used when the user has no usable GitHub token.
+func (h *Handler) userItemRepo(c echo.Context) repo.ItemRepository {
+	session, ok := auth.SessionFromContext(c.Request().Context())
+	if !ok {
+		return h.itemRepo
 	}
+
+	token, err := h.authRepo.GetUserToken(session.User())
+	if err != nil {
+		log.Printf("Getting GitHub token for user %s failed: %s\n", session.Username, err)
+		return h.itemRepo
+	}
+	if token == "" {
+		return h.itemRepo
+	}
+
+	return repo.NewItemRepo(h.db, h.gh.WithUserToken(token))
 }
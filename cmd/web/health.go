@@ -0,0 +1,28 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type healthStatus struct {
+	Status string `json:"status"`
+}
+
+// HealthzHandler tells that the process is up. It checks nothing else, so a
+// dependency being down does not get the app restarted.
+func (h *Handler) HealthzHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, healthStatus{Status: "ok"})
+}
+
+// ReadyzHandler tells whether the app can serve requests, with the health of
+// each dependency. It answers 503 when a dependency is failing.
+func (h *Handler) ReadyzHandler(c echo.Context) error {
+	readiness := h.healthRepo.Readiness()
+	if !readiness.Ready() {
+		return c.JSON(http.StatusServiceUnavailable, readiness)
+	}
+
+	return c.JSON(http.StatusOK, readiness)
+}
@@ -0,0 +1,149 @@
+package web
+
+import (
+	"fmt"
+	view "go-track/cmd/web/view"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (h *Handler) LinkBranchModalHandler(c echo.Context) error {
+	return h.renderLinkModal(c, "branch", "Link existing branch to '%s'")
+}
+
+func (h *Handler) LinkPRModalHandler(c echo.Context) error {
+	return h.renderLinkModal(c, "pr", "Link existing pull request to '%s'")
+}
+
+func (h *Handler) renderLinkModal(c echo.Context, kind string, titleFormat string) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	itemID, err := strconv.Atoi(c.Param("itemID"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	item, err := h.itemRepo.Get(itemID)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	cols, err := h.columnRepo.GetForProject(id)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	endpoint := fmt.Sprintf("/project/%d/items/%d/link/%s", id, itemID, kind)
+	modalState := view.ModalState{
+		Show:            true,
+		Title:           fmt.Sprintf(titleFormat, item.Name),
+		Body:            view.LinkModalBody(endpoint+"/search", kind),
+		Endpoint:        endpoint,
+		TargetElementID: "columns-container",
+	}
+
+	return view.ProjectColumns(cols, modalState).Render(c.Request().Context(), c.Response().Writer)
+}
+
+func (h *Handler) SearchBranchesHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	settings, err := h.repositorySettings(id)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	branches, err := h.branchRepo.Search(settings.Owner, settings.Repo, c.QueryParam("q"))
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	return view.LinkBranchResults(branches).Render(c.Request().Context(), c.Response().Writer)
+}
+
+func (h *Handler) SearchPRsHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	settings, err := h.repositorySettings(id)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	prs, err := h.prRepo.Search(settings.Owner, settings.Repo, c.QueryParam("q"))
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	return view.LinkPRResults(prs).Render(c.Request().Context(), c.Response().Writer)
+}
+
+func (h *Handler) LinkBranchHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	itemID, err := strconv.Atoi(c.Param("itemID"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	name := c.FormValue("branch-name")
+	if name == "" {
+		return c.String(http.StatusBadRequest, "No branch selected")
+	}
+
+	settings, err := h.repositorySettings(id)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	item, err := h.itemRepo.LinkBranch(settings.Owner, settings.Repo, name, itemID)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	h.broadcastColumns(c, id, item.ColumnID)
+
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/%d/columns", id))
+}
+
+func (h *Handler) LinkPRHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	itemID, err := strconv.Atoi(c.Param("itemID"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	pullNumber, err := strconv.Atoi(c.FormValue("pull-number"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "No pull request selected")
+	}
+
+	settings, err := h.repositorySettings(id)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	item, err := h.itemRepo.LinkPullRequest(settings.Owner, settings.Repo, pullNumber, itemID)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	h.broadcastColumns(c, id, item.ColumnID)
+
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/%d/columns", id))
+}
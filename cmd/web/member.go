@@ -0,0 +1,187 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	view "go-track/cmd/web/view"
+	"go-track/internal/auth"
+	"go-track/internal/model"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+const projectRoleKey = "projectRole"
+
+var errWrongProject = errors.New("The item does not belong to the project")
+
+// RequireRole only lets users with at least the given role in the project of
+// the request through. The role is kept on the context for the handlers.
+// Requests with an API token are limited to its project and scope.
+func (h *Handler) RequireRole(min string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			session, ok := auth.SessionFromContext(c.Request().Context())
+			if !ok {
+				return Fail(c, http.StatusUnauthorized, "Sign in to continue")
+			}
+
+			projID, err := h.requestProject(c)
+			if err != nil {
+				return Fail(c, http.StatusNotFound, err.Error())
+			}
+
+			role, err := h.memberRepo.GetRole(projID, session.User())
+			if err != nil {
+				return Fail(c, http.StatusInternalServerError, err.Error())
+			}
+
+			if token, ok := auth.APITokenFromContext(c.Request().Context()); ok {
+				if token.ProjectID != projID {
+					return Fail(c, http.StatusForbidden, "The API token is not valid for this project")
+				}
+				if model.RoleAtLeast(role, token.MaxRole()) {
+					role = token.MaxRole()
+				}
+			}
+
+			if !model.RoleAtLeast(role, min) {
+				return Fail(c, http.StatusForbidden, fmt.Sprintf("You need to be %s of the project to do this", min))
+			}
+
+			c.Set(projectRoleKey, role)
+
+			return next(c)
+		}
+	}
+}
+
+// requestProject finds the project a request is for from the project, column
+// and item in the route. All of them must agree, so an item can not be changed
+// through a project the user has access to when it belongs to another.
+func (h *Handler) requestProject(c echo.Context) (int, error) {
+	projID := -1
+	if c.Param("id") != "" {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return 0, err
+		}
+		projID = id
+	}
+
+	colID := -1
+	colParam := c.Param("colID")
+	if colParam == "" && c.Request().Method == http.MethodPost {
+		colParam = c.FormValue("column")
+	}
+	if colParam != "" {
+		id, err := strconv.Atoi(colParam)
+		if err != nil {
+			return 0, err
+		}
+		colID = id
+	}
+
+	if c.Param("itemID") != "" {
+		itemID, err := strconv.Atoi(c.Param("itemID"))
+		if err != nil {
+			return 0, err
+		}
+
+		item, err := h.itemRepo.Get(itemID)
+		if err != nil {
+			return 0, err
+		}
+
+		if colID != -1 && colID != item.ColumnID {
+			return 0, errWrongProject
+		}
+		colID = item.ColumnID
+	}
+
+	if colID != -1 {
+		col, err := h.columnRepo.Get(colID)
+		if err != nil {
+			return 0, err
+		}
+
+		if projID != -1 && projID != col.ProjectID {
+			return 0, errWrongProject
+		}
+		projID = col.ProjectID
+	}
+
+	if projID == -1 {
+		return 0, errors.New("The request is not for a project")
+	}
+
+	return projID, nil
+}
+
+func projectRole(c echo.Context) string {
+	role, _ := c.Get(projectRoleKey).(string)
+	return role
+}
+
+func (h *Handler) ProjectMembersHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	proj, err := h.projectRepo.GetProject(id)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	members, err := h.memberRepo.List(id)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	return view.ProjectMembersPage(proj, members).Render(c.Request().Context(), c.Response().Writer)
+}
+
+func (h *Handler) SaveProjectMemberHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	message := "Member saved"
+	_, err = h.memberRepo.Save(model.Member{
+		ProjectID: id,
+		Username:  c.FormValue("username"),
+		Role:      c.FormValue("role"),
+	})
+	if err != nil {
+		message = err.Error()
+	}
+
+	return h.renderProjectMembers(c, id, message)
+}
+
+func (h *Handler) RemoveProjectMemberHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	message := "Member removed"
+	err = h.memberRepo.Remove(id, c.Param("username"))
+	if err != nil {
+		message = err.Error()
+	}
+
+	return h.renderProjectMembers(c, id, message)
+}
+
+func (h *Handler) renderProjectMembers(c echo.Context, projID int, message string) error {
+	members, err := h.memberRepo.List(projID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	return view.ProjectMembers(projID, members, message).Render(c.Request().Context(), c.Response().Writer)
+}
@@ -3,6 +3,8 @@ package web
 import (
 	"fmt"
 	view "go-track/cmd/web/view"
+	"go-track/internal/auth"
+	"go-track/internal/branchname"
 	"go-track/internal/model"
 	"log"
 	"net/http"
@@ -23,11 +25,21 @@ func (h *Handler) ProjectPageHandler(c echo.Context) error {
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
 
+	// API tokens have no user profile, the header then only shows the login
+	session, _ := auth.SessionFromContext(c.Request().Context())
+	user := session.User()
+	if session.UserID != 0 {
+		user, err = h.authRepo.GetUser(session.UserID)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, err.Error())
+		}
+	}
+
 	modalState := view.ModalState{
 		Show: false,
 	}
 
-	return view.ProjectPage(proj, modalState).Render(c.Request().Context(), c.Response().Writer)
+	return view.ProjectPage(proj, user, modalState).Render(c.Request().Context(), c.Response().Writer)
 }
 
 func (h *Handler) ProjectColumnsHandler(c echo.Context) error {
@@ -69,10 +81,11 @@ func (h *Handler) MoveProjectItemHandler(c echo.Context) error {
 	if err != nil {
 		return c.String(http.StatusBadRequest, err.Error())
 	}
+	h.broadcastColumns(c, id, oldItem.ColumnID, movedItem.ColumnID)
 
 	modalState := view.ModalState{Show: false}
 	if movedItem.ColumnID != oldItem.ColumnID {
-		modalState, err = h.itemEnter(id, movedItem.ColumnID, movedItem)
+		modalState, err = h.itemEnter(c, id, movedItem)
 		if err != nil {
 			return c.String(http.StatusInternalServerError, err.Error())
 		}
@@ -86,105 +99,110 @@ func (h *Handler) MoveProjectItemHandler(c echo.Context) error {
 	return view.ProjectColumns(cols, modalState).Render(c.Request().Context(), c.Response().Writer)
 }
 
-func (h *Handler) itemEnter(projID, colID int, item model.Item) (view.ModalState, error) {
-	col, err := h.columnRepo.Get(colID)
+// itemEnter runs the workflow of the column the item was moved into, and
+// returns the modal asking for what the step needs from the user.
+func (h *Handler) itemEnter(c echo.Context, projID int, item model.Item) (view.ModalState, error) {
+	item, step, err := h.userItemRepo(c).EnterColumn(projID, item)
 	if err != nil {
 		return view.ModalState{}, err
 	}
 
 	var modalState view.ModalState
-	switch strings.ToLower(col.Name) {
-	case "backlog":
-		modalState = view.ModalState{Show: false}
-		break
-	case "todo":
-		// create new issue
-		if item.IssueID == -1 {
-			_, err := h.itemRepo.CreateIssue("TobiasTheDanish", "go-track", item)
-			if err != nil {
-				return view.ModalState{}, err
-			}
-		}
-		modalState = view.ModalState{Show: false}
-		break
-	case "in progress":
+	switch step {
+	case model.StepBranch:
 		// create branch for issue
 		if item.BranchName == "" {
-			branches, err := h.branchRepo.GetAll("TobiasTheDanish", "go-track")
+			settings, err := h.projectRepo.GetSettings(projID)
 			if err != nil {
 				return view.ModalState{}, err
 			}
 
-			dropdownItems := make([]view.DropdownItem, len(branches), len(branches))
-
-			for i, branch := range branches {
-				dropdownItems[i] = view.DropdownItem{
-					Value: branch.Sha,
-					Name:  branch.Name,
-				}
+			defaultBranch, dropdownItems, err := h.branchDropdown(settings)
+			if err != nil {
+				return view.ModalState{}, err
 			}
 
 			modalState = view.ModalState{
 				Show:            true,
 				Title:           fmt.Sprintf("Create branch for '%s'", item.Name),
-				Body:            view.CreateBranchModalBody(dropdownItems...),
+				Body:            view.CreateBranchModalBody(branchname.FromTemplate(settings.BranchTemplate, item), defaultBranch, settings.DraftPullRequests, dropdownItems...),
 				Endpoint:        fmt.Sprintf("/project/%d/items/%d/branch", projID, item.Id),
 				TargetElementID: "columns-container",
 			}
-		} else {
-			modalState = view.ModalState{Show: false}
 		}
-		break
-	case "ready for pull request":
-		// create pr for branch
-		if item.BranchName != "" {
-			branches, err := h.branchRepo.GetAll("TobiasTheDanish", "go-track")
+	case model.StepReview:
+		// create pr for branch, draft prs were marked ready for review
+		if item.PullRequestNumber == -1 && item.BranchName != "" {
+			settings, err := h.projectRepo.GetSettings(projID)
 			if err != nil {
 				return view.ModalState{}, err
 			}
 
-			dropdownItems := make([]view.DropdownItem, len(branches), len(branches))
-
-			for i, branch := range branches {
-				dropdownItems[i] = view.DropdownItem{
-					Value: branch.Name,
-					Name:  branch.Name,
-				}
+			defaultBranch, dropdownItems, err := h.branchDropdown(settings)
+			if err != nil {
+				return view.ModalState{}, err
 			}
 
 			modalState = view.ModalState{
 				Show:            true,
 				Title:           fmt.Sprintf("Create pull request for branch '%s'", item.BranchName),
-				Body:            view.CreatePRModalBody(item.BranchName, dropdownItems...),
+				Body:            view.CreatePRModalBody(item.BranchName, defaultBranch, dropdownItems...),
 				Endpoint:        fmt.Sprintf("/project/%d/items/%d/pr", projID, item.Id),
 				TargetElementID: "columns-container",
 			}
-
-		} else {
-			modalState = view.ModalState{Show: false}
 		}
-		break
-	case "done":
+	case model.StepMerge:
 		// close pr and issue
 		if item.PullRequestNumber != -1 {
-			title := fmt.Sprintf("Merge pull request #%d from TobiasTheDanish/%s", item.PullRequestNumber, item.BranchName)
+			settings, err := h.projectRepo.GetSettings(projID)
+			if err != nil {
+				return view.ModalState{}, err
+			}
+
+			title := fmt.Sprintf("Merge pull request #%d from %s/%s", item.PullRequestNumber, settings.Owner, item.BranchName)
+
+			readiness, err := h.prRepo.GetMergeReadiness(settings.Owner, settings.Repo, item.PullRequestNumber)
+			if err != nil {
+				return view.ModalState{}, err
+			}
 
 			modalState = view.ModalState{
 				Show:            true,
 				Title:           fmt.Sprintf("Merge pull request for branch '%s'", item.BranchName),
-				Body:            view.MergePRModalBody(title, item.BranchName, item.PullRequestNumber),
+				Body:            view.MergePRModalBody(title, item.BranchName, item.PullRequestNumber, readiness, settings.MergeMethod, isProjectAdmin(c)),
 				Endpoint:        fmt.Sprintf("/project/%d/items/%d/merge", projID, item.Id),
 				TargetElementID: "columns-container",
 			}
-		} else {
-			modalState = view.ModalState{Show: false}
 		}
-		break
 	}
 
 	return modalState, nil
 }
 
+// branchDropdown returns the default branch and the branches of the repository
+// of the project, for picking the source or base of a branch.
+func (h *Handler) branchDropdown(settings model.ProjectSettings) (string, []view.DropdownItem, error) {
+	branches, err := h.branchRepo.GetAll(settings.Owner, settings.Repo)
+	if err != nil {
+		return "", nil, err
+	}
+
+	defaultBranch, err := h.branchRepo.GetDefaultName(settings.Owner, settings.Repo, settings)
+	if err != nil {
+		return "", nil, err
+	}
+
+	dropdownItems := make([]view.DropdownItem, len(branches), len(branches))
+	for i, branch := range branches {
+		dropdownItems[i] = view.DropdownItem{
+			Value: branch.Name,
+			Name:  branch.Name,
+		}
+	}
+
+	return defaultBranch, dropdownItems, nil
+}
+
 func (h *Handler) ProjectItemHandler(c echo.Context) error {
 	name := c.FormValue("name")
 	if len(name) == 0 {
@@ -205,10 +223,46 @@ func (h *Handler) ProjectItemHandler(c echo.Context) error {
 	if err != nil {
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
+	h.broadcastColumns(c, col.ProjectID, col.Id)
 
 	return view.ProjectItem(col.ProjectID, item).Render(c.Request().Context(), c.Response().Writer)
 }
 
+func (h *Handler) ItemChecksHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	itemID, err := strconv.Atoi(c.Param("itemID"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	item, err := h.itemRepo.Get(itemID)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if item.BranchName == "" {
+		return c.String(http.StatusOK, "")
+	}
+
+	settings, err := h.repositorySettings(id)
+	if err != nil {
+		return c.String(http.StatusOK, "")
+	}
+
+	// the branch is often gone after a merge, the card then shows no badge
+	checks, err := h.branchRepo.GetChecks(settings.Owner, settings.Repo, item.BranchName)
+	if err != nil {
+		log.Printf("Getting checks of branch %s failed: %s\n", item.BranchName, err)
+		return c.String(http.StatusOK, "")
+	}
+
+	return view.CheckStatusBadge(checks).Render(c.Request().Context(), c.Response().Writer)
+}
+
 func (h *Handler) CreateBranchHandler(c echo.Context) error {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -221,12 +275,46 @@ func (h *Handler) CreateBranchHandler(c echo.Context) error {
 	}
 
 	name := c.FormValue("branch-name")
-	sha := c.FormValue("branch-sha")
+	source := c.FormValue("branch-source")
+	draftPR := c.FormValue("draft-pr")
+	draftBase := c.FormValue("draft-base-branch")
+
+	if err := branchname.Validate(name); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	settings, err := h.repositorySettings(id)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if source == "" {
+		source, err = h.branchRepo.GetDefaultName(settings.Owner, settings.Repo, settings)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	// resolve the tip of the source branch now, as it might have moved since the modal was shown
+	sourceBranch, err := h.branchRepo.Get(settings.Owner, settings.Repo, source)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
 
-	_, err = h.itemRepo.CreateBranch("TobiasTheDanish", "go-track", name, sha, itemID)
+	items := h.userItemRepo(c)
+	var item model.Item
+	if draftPR == "on" {
+		if draftBase == "" {
+			draftBase = source
+		}
+		item, err = items.CreateBranchWithDraftPullRequest(settings.Owner, settings.Repo, name, sourceBranch.Sha, draftBase, itemID)
+	} else {
+		item, err = items.CreateBranch(settings.Owner, settings.Repo, name, sourceBranch.Sha, itemID)
+	}
 	if err != nil {
 		return c.String(http.StatusBadRequest, err.Error())
 	}
+	h.broadcastColumns(c, id, item.ColumnID)
 
 	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/%d/columns", id))
 }
@@ -245,11 +333,17 @@ func (h *Handler) CreatePRHandler(c echo.Context) error {
 	head := c.FormValue("head-branch")
 	base := c.FormValue("base-branch")
 
-	_, err = h.itemRepo.CreatePullRequest("TobiasTheDanish", "go-track", head, base, itemID)
+	settings, err := h.repositorySettings(id)
 	if err != nil {
 		return c.String(http.StatusBadRequest, err.Error())
 	}
 
+	item, err := h.userItemRepo(c).CreatePullRequest(settings.Owner, settings.Repo, head, base, false, itemID)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	h.broadcastColumns(c, id, item.ColumnID)
+
 	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/%d/columns", id))
 }
 
@@ -269,14 +363,52 @@ func (h *Handler) MergePRHandler(c echo.Context) error {
 		return c.String(http.StatusBadRequest, err.Error())
 	}
 
+	// only the pull request of the item may be merged through it
+	item, err := h.itemRepo.Get(itemID)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if item.PullRequestNumber == -1 {
+		return c.String(http.StatusBadRequest, "The item has no pull request")
+	}
+	if pullNumber != item.PullRequestNumber {
+		return c.String(http.StatusConflict, fmt.Sprintf("The pull request of the item is #%d, not #%d", item.PullRequestNumber, pullNumber))
+	}
+
 	title := c.FormValue("commit-title")
 	message := c.FormValue("commit-message")
 	deleteBranch := c.FormValue("delete-branch")
+	method := c.FormValue("merge-method")
+	headSha := c.FormValue("head-sha")
+	overrideBlock := c.FormValue("override-block")
+
+	if headSha == "" {
+		return c.String(http.StatusBadRequest, "The head SHA of the pull request is required")
+	}
+
+	settings, err := h.repositorySettings(id)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	readiness, err := h.prRepo.GetMergeReadiness(settings.Owner, settings.Repo, item.PullRequestNumber)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	if !mergeMethodAllowed(c, settings, method) {
+		return c.String(http.StatusForbidden, fmt.Sprintf("Only admins of the project can merge with another method than '%s'", settings.MergeMethod))
+	}
+
+	if readiness.Blocked() && !(overrideBlock == "on" && isProjectAdmin(c)) {
+		return c.String(http.StatusConflict, fmt.Sprintf("Merging is blocked: %s", strings.Join(readiness.BlockedReasons, " ")))
+	}
 
-	_, err = h.itemRepo.MergePullRequest("TobiasTheDanish", "go-track", title, message, pullNumber, deleteBranch == "on", itemID)
+	item, err = h.userItemRepo(c).MergePullRequest(settings.Owner, settings.Repo, title, message, method, headSha, item.PullRequestNumber, deleteBranch == "on", itemID)
 	if err != nil {
 		return c.String(http.StatusBadRequest, err.Error())
 	}
+	h.broadcastColumns(c, id, item.ColumnID)
 
 	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/%d/columns", id))
 }
@@ -297,6 +429,7 @@ func (h *Handler) DeleteProjectItemHandler(c echo.Context) error {
 	if err != nil {
 		return c.String(http.StatusBadRequest, fmt.Sprintf("Could not delete item: %s", err.Error()))
 	}
+	h.broadcastColumns(c, column.ProjectID, column.Id)
 
 	return view.ProjectColumn(column).Render(c.Request().Context(), c.Response().Writer)
 }
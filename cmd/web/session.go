@@ -0,0 +1,127 @@
+package web
+
+import (
+	view "go-track/cmd/web/view"
+	"go-track/internal/auth"
+	"go-track/internal/repo"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SecureCookies reports whether cookies must only be sent over HTTPS. TLS is
+// usually terminated by a proxy, so the scheme comes from X-Forwarded-Proto.
+// COOKIE_SECURE overrides it for proxies that do not set the header.
+func SecureCookies(c echo.Context) bool {
+	if secure, err := strconv.ParseBool(os.Getenv("COOKIE_SECURE")); err == nil {
+		return secure
+	}
+
+	return c.Scheme() == "https"
+}
+
+func setSessionCookie(c echo.Context, value string, maxAge int) {
+	http.SetCookie(c.Response().Writer, auth.NewSessionCookie(value, maxAge, SecureCookies(c)))
+}
+
+// redirectAfterSignOut sends the browser to the sign in page. HTMX requests
+// get an HX-Redirect, so the whole page is replaced instead of a fragment.
+func redirectAfterSignOut(c echo.Context) error {
+	if c.Request().Header.Get("HX-Request") == "true" {
+		c.Response().Header().Set("HX-Redirect", "/sign-in")
+		return c.NoContent(http.StatusOK)
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/sign-in")
+}
+
+func (h *Handler) SignOutHandler(c echo.Context) error {
+	session, _ := auth.SessionFromContext(c.Request().Context())
+
+	err := h.sessionRepo.Revoke(session.User(), session.SessionID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	setSessionCookie(c, "", -1)
+
+	return redirectAfterSignOut(c)
+}
+
+// SignOutEverywhereHandler revokes every session of the signed in user,
+// including the current one.
+func (h *Handler) SignOutEverywhereHandler(c echo.Context) error {
+	session, _ := auth.SessionFromContext(c.Request().Context())
+
+	err := h.sessionRepo.RevokeAll(session.User())
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	setSessionCookie(c, "", -1)
+
+	return redirectAfterSignOut(c)
+}
+
+func (h *Handler) SessionsHandler(c echo.Context) error {
+	session, _ := auth.SessionFromContext(c.Request().Context())
+
+	sessions, err := h.sessionRepo.List(session.User())
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	return view.SessionsPage(sessions, session.SessionID, repo.IsInstanceAdmin(session.Username)).Render(c.Request().Context(), c.Response().Writer)
+}
+
+func (h *Handler) RevokeSessionHandler(c echo.Context) error {
+	session, _ := auth.SessionFromContext(c.Request().Context())
+
+	sessionID := c.Param("sessionID")
+	if sessionID == session.SessionID {
+		return c.String(http.StatusBadRequest, "Use sign out to end the current session")
+	}
+
+	err := h.sessionRepo.Revoke(session.User(), sessionID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	sessions, err := h.sessionRepo.List(session.User())
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	return view.Sessions(sessions, session.SessionID).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// RevokeUserSessionsHandler lets instance admins sign another user out
+// everywhere, for example when their account has been compromised.
+func (h *Handler) RevokeUserSessionsHandler(c echo.Context) error {
+	session, _ := auth.SessionFromContext(c.Request().Context())
+	if !repo.IsInstanceAdmin(session.Username) {
+		return c.String(http.StatusForbidden, "Only instance admins can sign out other users")
+	}
+
+	username := strings.TrimSpace(c.FormValue("username"))
+	if username == "" {
+		return view.RevokeUserSessionsMessage("Enter the GitHub username to sign out").Render(c.Request().Context(), c.Response().Writer)
+	}
+
+	// the login may have been used by more than one GitHub account, the one
+	// who signed in last is signed out
+	user, err := h.authRepo.GetUserByLogin(username)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	err = h.sessionRepo.RevokeAll(user)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	return view.RevokeUserSessionsMessage(username+" has been signed out everywhere").Render(c.Request().Context(), c.Response().Writer)
+}
@@ -0,0 +1,137 @@
+package web
+
+import (
+	"fmt"
+	view "go-track/cmd/web/view"
+	"go-track/internal/auth"
+	"go-track/internal/model"
+	"go-track/internal/repo"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (h *Handler) ProjectSettingsHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	proj, err := h.projectRepo.GetProject(id)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	settings, err := h.projectRepo.GetSettings(id)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	picker, err := h.repositoryPicker(c, settings)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	return view.ProjectSettingsPage(proj, settings, picker).Render(c.Request().Context(), c.Response().Writer)
+}
+
+func (h *Handler) UpdateProjectSettingsHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	settings, err := h.projectRepo.GetSettings(id)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	picker, err := h.repositoryPicker(c, settings)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	settings.MergeMethod = c.FormValue("merge-method")
+	settings.DraftPullRequests = c.FormValue("draft-pull-requests") == "on"
+	settings.RolesFromRepository = c.FormValue("roles-from-repo") == "on"
+	settings.BranchTemplate = c.FormValue("branch-template")
+	settings.DefaultBranch = strings.TrimSpace(c.FormValue("default-branch"))
+
+	repository := c.FormValue("repository")
+	if repository != "" {
+		if !picker.Contains(repository) {
+			return c.String(http.StatusBadRequest, fmt.Sprintf("The GitHub App is not installed on '%s'", repository))
+		}
+		settings.Owner, settings.Repo, _ = strings.Cut(repository, "/")
+	}
+
+	if settings.DefaultBranch != "" {
+		if !settings.HasRepository() {
+			return c.String(http.StatusBadRequest, repo.ErrNoRepository.Error())
+		}
+
+		_, err = h.branchRepo.Get(settings.Owner, settings.Repo, settings.DefaultBranch)
+		if err != nil {
+			return c.String(http.StatusBadRequest, fmt.Sprintf("Default branch '%s' could not be found", settings.DefaultBranch))
+		}
+	}
+
+	settings, err = h.projectRepo.UpdateSettings(settings)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	return view.ProjectSettingsForm(settings, picker, "Settings saved").Render(c.Request().Context(), c.Response().Writer)
+}
+
+// repositoryPicker lists the repositories the GitHub App is installed on that
+// the signed in user has access to, which are the repositories the user can
+// connect the project to. Users without a GitHub token only get the current
+// repository of the project, which is kept even when the user can not see it.
+func (h *Handler) repositoryPicker(c echo.Context, settings model.ProjectSettings) (view.RepositoryPicker, error) {
+	installUrl, err := h.installRepo.GetInstallUrl()
+	if err != nil {
+		return view.RepositoryPicker{}, err
+	}
+
+	picker := view.RepositoryPicker{
+		Repositories: make([]view.DropdownItem, 0),
+		InstallUrl:   installUrl,
+	}
+	if settings.HasRepository() {
+		current := settings.Owner + "/" + settings.Repo
+		picker.Repositories = append(picker.Repositories, view.DropdownItem{Value: current, Name: current})
+	}
+
+	session, ok := auth.SessionFromContext(c.Request().Context())
+	if !ok {
+		return picker, nil
+	}
+
+	token, err := h.authRepo.GetUserToken(session.User())
+	if err != nil {
+		return view.RepositoryPicker{}, err
+	}
+	if token == "" {
+		return picker, nil
+	}
+
+	repositories, err := h.installRepo.ListRepositories(session.User(), token)
+	if err != nil {
+		return view.RepositoryPicker{}, err
+	}
+
+	for _, r := range repositories {
+		if picker.Contains(r.FullName()) {
+			continue
+		}
+		picker.Repositories = append(picker.Repositories, view.DropdownItem{
+			Value: r.FullName(),
+			Name:  r.FullName(),
+		})
+	}
+
+	return picker, nil
+}
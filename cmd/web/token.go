@@ -0,0 +1,89 @@
+package web
+
+import (
+	"fmt"
+	view "go-track/cmd/web/view"
+	"go-track/internal/auth"
+	"go-track/internal/model"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (h *Handler) APITokensHandler(c echo.Context) error {
+	session, _ := auth.SessionFromContext(c.Request().Context())
+
+	tokens, err := h.tokenRepo.List(session.User())
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	return view.APITokensPage(tokens).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// CreateAPITokenHandler creates a token for a project the user has access to.
+// The secret is only shown in the response.
+func (h *Handler) CreateAPITokenHandler(c echo.Context) error {
+	session, _ := auth.SessionFromContext(c.Request().Context())
+
+	projID, err := strconv.Atoi(c.FormValue("project"))
+	if err != nil {
+		return h.renderAPITokens(c, "", "Enter the id of the project")
+	}
+
+	lifetime, err := strconv.Atoi(c.FormValue("expires"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	token := model.APIToken{
+		UserID:    session.UserID,
+		Username:  session.Username,
+		Name:      c.FormValue("name"),
+		ProjectID: projID,
+		Scope:     c.FormValue("scope"),
+	}
+
+	role, err := h.memberRepo.GetRole(projID, session.User())
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	if !model.RoleAtLeast(role, token.MaxRole()) {
+		return h.renderAPITokens(c, "", fmt.Sprintf("You need to be %s of project %d to create this token", token.MaxRole(), projID))
+	}
+
+	secret, _, err := h.tokenRepo.Create(token, lifetime)
+	if err != nil {
+		return h.renderAPITokens(c, "", err.Error())
+	}
+
+	return h.renderAPITokens(c, secret, "")
+}
+
+func (h *Handler) RevokeAPITokenHandler(c echo.Context) error {
+	session, _ := auth.SessionFromContext(c.Request().Context())
+
+	id, err := strconv.Atoi(c.Param("tokenID"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	err = h.tokenRepo.Revoke(session.User(), id)
+	if err != nil {
+		return h.renderAPITokens(c, "", err.Error())
+	}
+
+	return h.renderAPITokens(c, "", "")
+}
+
+func (h *Handler) renderAPITokens(c echo.Context, secret string, message string) error {
+	session, _ := auth.SessionFromContext(c.Request().Context())
+
+	tokens, err := h.tokenRepo.List(session.User())
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	return view.APITokens(tokens, secret, message).Render(c.Request().Context(), c.Response().Writer)
+}
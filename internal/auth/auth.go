@@ -3,6 +3,7 @@ package auth
 import (
 	"errors"
 	"fmt"
+	"go-track/internal/model"
 	"net/http"
 	"os"
 	"strings"
@@ -11,15 +12,34 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func sign(claims jwt.Claims) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+// SessionCookie is the name of the cookie holding the signed session.
+const SessionCookie = "authSession"
 
-	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+// NewSessionCookie returns the session cookie. A negative maxAge deletes it.
+func NewSessionCookie(value string, maxAge int, secure bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     SessionCookie,
+		Value:    value,
+		MaxAge:   maxAge,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	}
 }
 
+// AuthSession is signed into the session cookie. SessionID refers to the
+// session stored server side, which is checked so sessions can be revoked.
+// UserID is 0 for API tokens created before tokens were tied to the user id.
 type AuthSession struct {
-	Username string `json:"username"`
-	OrgUrl   string `json:"orgUrl"`
+	SessionID string `json:"sessionId"`
+	UserID    int    `json:"userId"`
+	Username  string `json:"username"`
+}
+
+// User returns the user of the session, of which only the id and login are known.
+func (s AuthSession) User() model.User {
+	return model.User{Id: s.UserID, Login: s.Username}
 }
 
 type sessionClaims struct {
@@ -41,13 +61,14 @@ func GetJWTString(h http.Header) (string, error) {
 	return authSplit[1], nil
 }
 
-func SignAuthSession(session AuthSession) (string, error) {
-	now := time.Now()
+// SignAuthSession signs the session into a token expiring at expiresAt, which
+// is the expiry of the session stored server side.
+func SignAuthSession(session AuthSession, expiresAt time.Time) (string, error) {
 	claims := sessionClaims{
 		session,
 		jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    os.Getenv("JWT_ISSUER"),
 		},
 	}
@@ -56,27 +77,18 @@ func SignAuthSession(session AuthSession) (string, error) {
 }
 
 func ParseAuthJWT(jwtString string) (AuthSession, error) {
-	token, err := jwt.ParseWithClaims(jwtString, &sessionClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
+	token, err := jwt.ParseWithClaims(jwtString, &sessionClaims{}, verifyKey, jwt.WithExpirationRequired(), jwt.WithIssuedAt())
 	if err != nil {
 		return AuthSession{}, err
-	} else if claims, ok := token.Claims.(*sessionClaims); ok {
-		issuer := claims.Issuer
-		if issuer != os.Getenv("JWT_ISSUER") {
-			return AuthSession{}, errors.New("Invalid JWT. Issuer.")
-		}
-		expiresAt := claims.ExpiresAt
-		if expiresAt.Before(time.Now()) {
-			return AuthSession{}, errors.New("Invalid JWT. Expired.")
-		}
-		issuedAt := claims.IssuedAt
-		if !expiresAt.Equal(issuedAt.Add(24 * time.Hour)) {
-			return AuthSession{}, errors.New("Invalid JWT.")
-		}
+	}
 
-		return claims.Session, nil
-	} else {
+	claims, ok := token.Claims.(*sessionClaims)
+	if !ok {
 		return AuthSession{}, errors.New("Invalid JWT claims type")
 	}
+	if claims.Issuer != os.Getenv("JWT_ISSUER") {
+		return AuthSession{}, errors.New("Invalid JWT. Issuer.")
+	}
+
+	return claims.Session, nil
 }
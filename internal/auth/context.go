@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"context"
+	"go-track/internal/model"
+)
+
+type sessionKey struct{}
+
+// WithSession returns a context carrying the session of the signed in user.
+func WithSession(ctx context.Context, session AuthSession) context.Context {
+	return context.WithValue(ctx, sessionKey{}, session)
+}
+
+// SessionFromContext returns the session put in the context by the
+// authentication middleware.
+func SessionFromContext(ctx context.Context) (AuthSession, bool) {
+	session, ok := ctx.Value(sessionKey{}).(AuthSession)
+	return session, ok
+}
+
+type apiTokenKey struct{}
+
+// WithAPIToken marks the request as authenticated with an API token instead of
+// a browser session.
+func WithAPIToken(ctx context.Context, token model.APIToken) context.Context {
+	return context.WithValue(ctx, apiTokenKey{}, token)
+}
+
+func APITokenFromContext(ctx context.Context) (model.APIToken, bool) {
+	token, ok := ctx.Value(apiTokenKey{}).(model.APIToken)
+	return token, ok
+}
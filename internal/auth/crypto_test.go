@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	t.Setenv("TOKEN_ENCRYPTION_KEY", "key")
+
+	encrypted, err := Encrypt("ghu_token")
+	if err != nil {
+		t.Fatalf("Could not encrypt: %s\n", err)
+	}
+	if encrypted == "ghu_token" {
+		t.Fatalf("Expected the token to be encrypted\n")
+	}
+
+	decrypted, err := Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("Could not decrypt: %s\n", err)
+	}
+	if decrypted != "ghu_token" {
+		t.Fatalf("Expected 'ghu_token', got '%s'\n", decrypted)
+	}
+
+	t.Setenv("TOKEN_ENCRYPTION_KEY", "other key")
+	if _, err := Decrypt(encrypted); err == nil {
+		t.Fatalf("Expected decrypting with another key to fail\n")
+	}
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"os"
+)
+
+// CSRFHeader is the header HTMX sends the CSRF token in.
+const CSRFHeader = "X-CSRF-Token"
+
+// CSRFField is the form field holding the CSRF token for forms posted without HTMX.
+const CSRFField = "_csrf"
+
+// CSRFToken derives the CSRF token of a session. It is bound to the session id,
+// so it does not have to be stored and stops working once the session ends.
+func CSRFToken(sessionID string) string {
+	mac := hmac.New(sha256.New, []byte(csrfSecret()))
+	mac.Write([]byte("csrf:" + sessionID))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// csrfSecret is read from CSRF_SECRET, so it is not shared with the JWT keys or
+// the key encrypting the GitHub tokens.
+func csrfSecret() string {
+	return os.Getenv("CSRF_SECRET")
+}
+
+// CheckCSRFSecret fails when CSRF_SECRET is not set, since the tokens could
+// then be derived from the session id alone.
+func CheckCSRFSecret() error {
+	if csrfSecret() == "" {
+		return errors.New("CSRF_SECRET is not set")
+	}
+
+	return nil
+}
+
+func ValidCSRFToken(sessionID string, token string) bool {
+	if sessionID == "" || token == "" || csrfSecret() == "" {
+		return false
+	}
+
+	return hmac.Equal([]byte(CSRFToken(sessionID)), []byte(token))
+}
+
+// CSRFTokenFromContext returns the CSRF token of the signed in user, or an
+// empty string on public pages.
+func CSRFTokenFromContext(ctx context.Context) string {
+	session, ok := SessionFromContext(ctx)
+	if !ok || session.SessionID == "" {
+		return ""
+	}
+
+	return CSRFToken(session.SessionID)
+}
@@ -0,0 +1,45 @@
+package auth
+
+import "testing"
+
+func TestCSRFToken(t *testing.T) {
+	t.Setenv("CSRF_SECRET", "secret")
+
+	token := CSRFToken("session-1")
+	if !ValidCSRFToken("session-1", token) {
+		t.Fatal("Expected token to be valid for its session")
+	}
+	if ValidCSRFToken("session-2", token) {
+		t.Fatal("Expected token to be invalid for another session")
+	}
+	if ValidCSRFToken("", CSRFToken("")) {
+		t.Fatal("Expected token to be invalid without a session")
+	}
+}
+
+func TestCSRFTokenWithoutSecret(t *testing.T) {
+	t.Setenv("CSRF_SECRET", "")
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("TOKEN_ENCRYPTION_KEY", "key")
+
+	if err := CheckCSRFSecret(); err == nil {
+		t.Fatal("Expected a missing CSRF_SECRET to be reported")
+	}
+	if ValidCSRFToken("session-1", CSRFToken("session-1")) {
+		t.Fatal("Expected tokens to be refused without a CSRF secret")
+	}
+}
+
+func TestCSRFTokenSurvivesJWTKeyRotation(t *testing.T) {
+	t.Setenv("CSRF_SECRET", "secret")
+	t.Setenv("JWT_SECRET", "legacy")
+	t.Setenv("JWT_KEYS", "old:HS256:old-secret")
+	token := CSRFToken("session-1")
+
+	// a deployment moving to the key set drops the legacy secret
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("JWT_KEYS", "new:HS512:new-secret")
+	if !ValidCSRFToken("session-1", token) {
+		t.Fatal("Expected the CSRF token to not depend on the JWT keys")
+	}
+}
@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// legacyKeyID is the id of the key from JWT_SECRET. Tokens signed before key
+// ids were added have no kid header, they are verified with this key.
+const legacyKeyID = "default"
+
+const defaultSessionLifetime = 24 * time.Hour
+
+type jwtKey struct {
+	id     string
+	method jwt.SigningMethod
+	// sign is nil for keys that are only kept to verify older tokens.
+	sign   any
+	verify any
+}
+
+// keySet holds the keys tokens are signed and verified with. One key signs new
+// tokens, every key verifies, so a key can be rotated without signing out
+// everyone: add the new key, make it the signing key, and remove the old key
+// once the tokens signed with it have expired.
+type keySet struct {
+	keys    map[string]jwtKey
+	signing string
+}
+
+var keySetCache struct {
+	mu     sync.Mutex
+	config string
+	keys   keySet
+}
+
+// loadKeySet reads the keys from the environment. JWT_KEYS is a comma separated
+// list of kid:algorithm:key entries, where the key is the secret for HMAC
+// algorithms and the path of a PEM file for RSA, ECDSA and EdDSA. A public key
+// only verifies. JWT_SIGNING_KEY picks the signing key and defaults to the
+// first entry. JWT_SECRET is an HS256 key with the id "default".
+func loadKeySet() (keySet, error) {
+	config := strings.Join([]string{os.Getenv("JWT_KEYS"), os.Getenv("JWT_SIGNING_KEY"), os.Getenv("JWT_SECRET")}, "\x00")
+
+	keySetCache.mu.Lock()
+	defer keySetCache.mu.Unlock()
+
+	if keySetCache.keys.keys != nil && keySetCache.config == config {
+		return keySetCache.keys, nil
+	}
+
+	set, err := parseKeySet(os.Getenv("JWT_KEYS"), os.Getenv("JWT_SIGNING_KEY"), os.Getenv("JWT_SECRET"))
+	if err != nil {
+		return keySet{}, err
+	}
+
+	keySetCache.config = config
+	keySetCache.keys = set
+
+	return set, nil
+}
+
+func parseKeySet(keys string, signing string, legacySecret string) (keySet, error) {
+	set := keySet{keys: make(map[string]jwtKey), signing: signing}
+
+	for _, entry := range strings.Split(keys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 {
+			return keySet{}, errors.New(fmt.Sprintf("Invalid JWT key '%s', expected kid:algorithm:key", parts[0]))
+		}
+
+		key, err := parseKey(parts[0], parts[1], parts[2])
+		if err != nil {
+			return keySet{}, err
+		}
+		if _, ok := set.keys[key.id]; ok {
+			return keySet{}, errors.New(fmt.Sprintf("The JWT key id '%s' is used twice", key.id))
+		}
+
+		set.keys[key.id] = key
+		if set.signing == "" {
+			set.signing = key.id
+		}
+	}
+
+	if legacySecret != "" {
+		if _, ok := set.keys[legacyKeyID]; !ok {
+			set.keys[legacyKeyID] = jwtKey{
+				id:     legacyKeyID,
+				method: jwt.SigningMethodHS256,
+				sign:   []byte(legacySecret),
+				verify: []byte(legacySecret),
+			}
+		}
+		if set.signing == "" {
+			set.signing = legacyKeyID
+		}
+	}
+
+	key, ok := set.keys[set.signing]
+	if !ok {
+		return keySet{}, errors.New("No JWT signing key, set JWT_KEYS or JWT_SECRET")
+	}
+	if key.sign == nil {
+		return keySet{}, errors.New(fmt.Sprintf("The JWT signing key '%s' is a public key", key.id))
+	}
+
+	return set, nil
+}
+
+func parseKey(id string, alg string, value string) (jwtKey, error) {
+	method := jwt.GetSigningMethod(alg)
+	if method == nil || alg == "none" {
+		return jwtKey{}, errors.New(fmt.Sprintf("Unsupported algorithm '%s' for JWT key '%s'", alg, id))
+	}
+
+	key := jwtKey{id: id, method: method}
+	if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+		if value == "" {
+			return jwtKey{}, errors.New(fmt.Sprintf("The JWT key '%s' has no secret", id))
+		}
+		key.sign = []byte(value)
+		key.verify = []byte(value)
+		return key, nil
+	}
+
+	pem, err := os.ReadFile(value)
+	if err != nil {
+		return jwtKey{}, errors.Join(errors.New(fmt.Sprintf("Reading JWT key '%s' failed", id)), err)
+	}
+
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		if private, err := jwt.ParseRSAPrivateKeyFromPEM(pem); err == nil {
+			key.sign, key.verify = private, &private.PublicKey
+		} else {
+			key.verify, err = jwt.ParseRSAPublicKeyFromPEM(pem)
+			if err != nil {
+				return jwtKey{}, errors.Join(errors.New(fmt.Sprintf("Parsing JWT key '%s' failed", id)), err)
+			}
+		}
+	case *jwt.SigningMethodECDSA:
+		if private, err := jwt.ParseECPrivateKeyFromPEM(pem); err == nil {
+			key.sign, key.verify = private, &private.PublicKey
+		} else {
+			key.verify, err = jwt.ParseECPublicKeyFromPEM(pem)
+			if err != nil {
+				return jwtKey{}, errors.Join(errors.New(fmt.Sprintf("Parsing JWT key '%s' failed", id)), err)
+			}
+		}
+	case *jwt.SigningMethodEd25519:
+		if private, err := jwt.ParseEdPrivateKeyFromPEM(pem); err == nil {
+			key.sign, key.verify = private, private.(crypto.Signer).Public()
+		} else {
+			key.verify, err = jwt.ParseEdPublicKeyFromPEM(pem)
+			if err != nil {
+				return jwtKey{}, errors.Join(errors.New(fmt.Sprintf("Parsing JWT key '%s' failed", id)), err)
+			}
+		}
+	default:
+		return jwtKey{}, errors.New(fmt.Sprintf("Unsupported algorithm '%s' for JWT key '%s'", alg, id))
+	}
+
+	return key, nil
+}
+
+func sign(claims jwt.Claims) (string, error) {
+	set, err := loadKeySet()
+	if err != nil {
+		return "", err
+	}
+
+	key := set.keys[set.signing]
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+
+	return token.SignedString(key.sign)
+}
+
+// verifyKey finds the key a token was signed with. The algorithm must match
+// the key, so a public key can not be used as an HMAC secret.
+func verifyKey(t *jwt.Token) (interface{}, error) {
+	set, err := loadKeySet()
+	if err != nil {
+		return nil, err
+	}
+
+	id, _ := t.Header["kid"].(string)
+	if id == "" {
+		id = legacyKeyID
+	}
+
+	key, ok := set.keys[id]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Invalid JWT. Unknown key '%s'.", id))
+	}
+	if t.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("Invalid JWT. Signing method.")
+	}
+
+	return key.verify, nil
+}
+
+// SessionLifetime is how long a session lasts without being used, from
+// JWT_SESSION_LIFETIME. Sessions in use are renewed before they expire.
+func SessionLifetime() time.Duration {
+	lifetime, err := time.ParseDuration(os.Getenv("JWT_SESSION_LIFETIME"))
+	if err != nil || lifetime <= 0 {
+		return defaultSessionLifetime
+	}
+
+	return lifetime
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestKeyRotation(t *testing.T) {
+	t.Setenv("JWT_ISSUER", "go-track")
+	t.Setenv("JWT_SECRET", "legacy")
+	session := AuthSession{SessionID: "1", Username: "octocat"}
+	expiresAt := time.Now().Add(time.Hour)
+
+	// Tokens signed before key ids were added have no kid header.
+	unversioned := jwt.NewWithClaims(jwt.SigningMethodHS256, sessionClaims{session, jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		Issuer:    "go-track",
+	}})
+	legacy, err := unversioned.SignedString([]byte("legacy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("JWT_KEYS", "old:HS256:old-secret")
+	old, err := SignAuthSession(session, expiresAt)
+	if err != nil {
+		t.Fatalf("Could not sign session: %s\n", err)
+	}
+
+	t.Setenv("JWT_KEYS", "new:HS512:new-secret,old:HS256:old-secret")
+	current, err := SignAuthSession(session, expiresAt)
+	if err != nil {
+		t.Fatalf("Could not sign session: %s\n", err)
+	}
+
+	for name, token := range map[string]string{"legacy": legacy, "old": old, "new": current} {
+		if parsed, err := ParseAuthJWT(token); err != nil || parsed != session {
+			t.Fatalf("Expected %s token to be valid: %+v %s\n", name, parsed, err)
+		}
+	}
+
+	t.Setenv("JWT_KEYS", "new:HS512:new-secret")
+	t.Setenv("JWT_SECRET", "")
+	if _, err := ParseAuthJWT(old); err == nil {
+		t.Fatal("Expected a token signed with a removed key to be rejected")
+	}
+	if _, err := ParseAuthJWT(current); err != nil {
+		t.Fatalf("Expected token to be valid: %s\n", err)
+	}
+}
+
+func TestAsymmetricKeys(t *testing.T) {
+	t.Setenv("JWT_ISSUER", "go-track")
+	t.Setenv("JWT_SECRET", "")
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateDER, _ := x509.MarshalPKCS8PrivateKey(private)
+	publicDER, _ := x509.MarshalPKIXPublicKey(public)
+
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "private.pem")
+	publicPath := filepath.Join(dir, "public.pem")
+	os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600)
+	os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0600)
+
+	t.Setenv("JWT_KEYS", "ed:EdDSA:"+privatePath)
+	signed, err := SignAuthSession(AuthSession{Username: "octocat"}, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Could not sign session: %s\n", err)
+	}
+
+	t.Setenv("JWT_KEYS", "hmac:HS256:secret,ed:EdDSA:"+publicPath)
+	if _, err := ParseAuthJWT(signed); err != nil {
+		t.Fatalf("Expected token to be verified with the public key: %s\n", err)
+	}
+
+	t.Setenv("JWT_KEYS", "ed:EdDSA:"+publicPath)
+	if _, err := SignAuthSession(AuthSession{}, time.Now()); err == nil {
+		t.Fatal("Expected signing with a public key to fail")
+	}
+}
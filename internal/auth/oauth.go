@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const OAuthStateLifetime = 10 * time.Minute
+
+// OAuthState is kept in a signed cookie while the user signs in with GitHub.
+// Nonce is sent as the state parameter, Verifier is the PKCE code verifier and
+// Redirect is the page the user asked for before signing in.
+type OAuthState struct {
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	Redirect string `json:"redirect"`
+}
+
+type oauthStateClaims struct {
+	State OAuthState `json:"oauthState"`
+	jwt.RegisteredClaims
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// NewOAuthState creates a state with a random nonce and PKCE verifier. The
+// redirect is dropped unless it is a path on this site.
+func NewOAuthState(redirect string) (OAuthState, error) {
+	nonce, err := randomString(24)
+	if err != nil {
+		return OAuthState{}, err
+	}
+
+	verifier, err := randomString(48)
+	if err != nil {
+		return OAuthState{}, err
+	}
+
+	if !IsLocalRedirect(redirect) {
+		redirect = "/"
+	}
+
+	return OAuthState{
+		Nonce:    nonce,
+		Verifier: verifier,
+		Redirect: redirect,
+	}, nil
+}
+
+// IsLocalRedirect reports whether redirect is a path on this site, so it can
+// not be used to send the user somewhere else after signing in. Browsers
+// ignore tabs and newlines in urls and treat backslashes like slashes, so
+// those are rejected both as written and once unescaped.
+func IsLocalRedirect(redirect string) bool {
+	u, err := url.Parse(redirect)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.User != nil || u.Opaque != "" {
+		return false
+	}
+
+	return isLocalPath(redirect) && isLocalPath(u.Path)
+}
+
+func isLocalPath(path string) bool {
+	return strings.HasPrefix(path, "/") &&
+		!strings.HasPrefix(path, "//") &&
+		!strings.HasPrefix(path, "/\\") &&
+		!strings.ContainsFunc(path, func(r rune) bool { return unicode.IsControl(r) || unicode.IsSpace(r) })
+}
+
+// CodeChallenge returns the S256 PKCE code challenge of the state's verifier.
+func (s OAuthState) CodeChallenge() string {
+	sum := sha256.Sum256([]byte(s.Verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Matches compares the state parameter returned by GitHub with the nonce.
+func (s OAuthState) Matches(state string) bool {
+	return s.Nonce != "" && subtle.ConstantTimeCompare([]byte(s.Nonce), []byte(state)) == 1
+}
+
+func SignOAuthState(state OAuthState) (string, error) {
+	now := time.Now()
+	claims := oauthStateClaims{
+		state,
+		jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(OAuthStateLifetime)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    os.Getenv("JWT_ISSUER"),
+			Subject:   "oauth-state",
+		},
+	}
+
+	return sign(claims)
+}
+
+func ParseOAuthState(jwtString string) (OAuthState, error) {
+	token, err := jwt.ParseWithClaims(jwtString, &oauthStateClaims{}, verifyKey, jwt.WithExpirationRequired(), jwt.WithSubject("oauth-state"))
+	if err != nil {
+		return OAuthState{}, err
+	}
+
+	claims, ok := token.Claims.(*oauthStateClaims)
+	if !ok {
+		return OAuthState{}, errors.New("Invalid JWT claims type")
+	}
+	if claims.Issuer != os.Getenv("JWT_ISSUER") {
+		return OAuthState{}, errors.New("Invalid JWT. Issuer.")
+	}
+
+	return claims.State, nil
+}
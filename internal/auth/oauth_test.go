@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"testing"
+)
+
+func TestOAuthStateRoundTrip(t *testing.T) {
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("JWT_ISSUER", "go-track")
+
+	state, err := NewOAuthState("/1?tab=board")
+	if err != nil {
+		t.Fatalf("Could not create state: %s\n", err)
+	}
+
+	signed, err := SignOAuthState(state)
+	if err != nil {
+		t.Fatalf("Could not sign state: %s\n", err)
+	}
+
+	parsed, err := ParseOAuthState(signed)
+	if err != nil {
+		t.Fatalf("Could not parse state: %s\n", err)
+	}
+	if parsed != state || !parsed.Matches(state.Nonce) || parsed.Matches("other") {
+		t.Fatalf("Expected %+v, got %+v\n", state, parsed)
+	}
+
+	t.Setenv("JWT_SECRET", "rotated")
+	if _, err := ParseOAuthState(signed); err == nil {
+		t.Fatalf("Expected a state signed with another secret to be rejected\n")
+	}
+}
+
+func TestOAuthStateRedirect(t *testing.T) {
+	for redirect, expected := range map[string]string{
+		"/1":                  "/1",
+		"":                    "/",
+		"https://example.com": "/",
+		"//example.com":       "/",
+		"/\\example.com":      "/",
+		"/%09/example.com":    "/",
+		"/\t/example.com":     "/",
+		"/\n/example.com":     "/",
+		"/ /example.com":      "/",
+		"/%2F/example.com":    "/",
+		"/%5Cexample.com":     "/",
+		"/\x00":               "/",
+		"/1?tab=settings":     "/1?tab=settings",
+	} {
+		state, err := NewOAuthState(redirect)
+		if err != nil {
+			t.Fatalf("Could not create state: %s\n", err)
+		}
+		if state.Redirect != expected {
+			t.Errorf("Expected redirect '%s' to become '%s', got '%s'\n", redirect, expected, state.Redirect)
+		}
+	}
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// the example from RFC 7636, appendix B
+	state := OAuthState{Verifier: "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"}
+
+	if challenge := state.CodeChallenge(); challenge != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Fatalf("Unexpected code challenge: %s\n", challenge)
+	}
+}
@@ -0,0 +1,164 @@
+package branchname
+
+import (
+	"errors"
+	"fmt"
+	"go-track/internal/model"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const defaultType = "feature"
+
+const maxSlugLength = 48
+
+var (
+	typePrefix    = regexp.MustCompile(`^\s*([A-Za-z]+)(\([^)]*\))?!?:\s*`)
+	nonSlugChars  = regexp.MustCompile(`[^a-z0-9]+`)
+	repeatedSlash = regexp.MustCompile(`/{2,}`)
+	repeatedDash  = regexp.MustCompile(`-{2,}`)
+)
+
+// ligatures are the letters that do not decompose into a base letter and marks.
+var ligatures = strings.NewReplacer("ß", "ss", "æ", "ae", "œ", "oe", "ø", "o", "đ", "d", "ł", "l", "þ", "th")
+
+// FromTemplate generates a branch name for the item. The template supports the
+// placeholders {type}, {issue_number}, {item_id} and {slug}. The type is taken
+// from a conventional commit style prefix of the item name, e.g. "fix: ...".
+func FromTemplate(template string, item model.Item) string {
+	if template == "" {
+		template = model.DefaultBranchTemplate
+	}
+
+	itemType := defaultType
+	title := item.Name
+	if match := typePrefix.FindStringSubmatch(title); match != nil {
+		itemType = strings.ToLower(match[1])
+		title = title[len(match[0]):]
+	}
+
+	issueNumber := ""
+	if item.IssueNumber > 0 {
+		issueNumber = strconv.Itoa(item.IssueNumber)
+	}
+
+	name := strings.NewReplacer(
+		"{type}", itemType,
+		"{issue_number}", issueNumber,
+		"{item_id}", strconv.Itoa(item.Id),
+		"{slug}", Slugify(title),
+	).Replace(template)
+
+	return sanitize(name)
+}
+
+// Slugify lowercases s, strips the accents of letters and replaces everything
+// but letters and digits with dashes, so the result is always a valid single
+// component of a git ref.
+func Slugify(s string) string {
+	slug := nonSlugChars.ReplaceAllString(transliterate(strings.ToLower(s)), "-")
+	slug = strings.Trim(slug, "-")
+
+	if len(slug) > maxSlugLength {
+		slug = strings.TrimRight(slug[:maxSlugLength], "-")
+	}
+
+	return slug
+}
+
+// transliterate decomposes letters like 'ü' into 'u' and a combining mark, and
+// drops the marks.
+func transliterate(s string) string {
+	decomposed := norm.NFKD.String(ligatures.Replace(s))
+
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, decomposed)
+}
+
+// sanitize cleans up the separators left behind by empty placeholders and
+// removes anything git does not allow in a ref name.
+func sanitize(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(" ~^:?*[\\", r) {
+			return '-'
+		}
+		return r
+	}, name)
+	name = strings.ReplaceAll(name, "@{", "-")
+	name = repeatedSlash.ReplaceAllString(name, "/")
+
+	components := strings.Split(name, "/")
+	cleaned := make([]string, 0, len(components))
+	for _, c := range components {
+		for strings.Contains(c, "..") {
+			c = strings.ReplaceAll(c, "..", ".")
+		}
+		c = repeatedDash.ReplaceAllString(c, "-")
+		// trimming may leave another '.lock' behind, as in 'a.lock.lock' or 'a.lock-'
+		for {
+			trimmed := strings.Trim(strings.TrimSuffix(c, ".lock"), "-.")
+			if trimmed == c {
+				break
+			}
+			c = trimmed
+		}
+		if c != "" {
+			cleaned = append(cleaned, c)
+		}
+	}
+
+	return strings.Join(cleaned, "/")
+}
+
+// Validate checks name against the rules of git check-ref-format for branches.
+func Validate(name string) error {
+	if name == "" {
+		return errors.New("Branch name can not be empty")
+	}
+	if name == "@" {
+		return errors.New("Branch name can not be '@'")
+	}
+	if strings.HasPrefix(name, "-") {
+		return errors.New("Branch name can not start with '-'")
+	}
+	if strings.HasSuffix(name, ".") {
+		return errors.New("Branch name can not end with '.'")
+	}
+	if strings.Contains(name, "..") {
+		return errors.New("Branch name can not contain '..'")
+	}
+	if strings.Contains(name, "@{") {
+		return errors.New("Branch name can not contain '@{'")
+	}
+
+	for _, r := range name {
+		if r < 0x20 || r == 0x7f {
+			return errors.New("Branch name can not contain control characters")
+		}
+		if strings.ContainsRune(" ~^:?*[\\", r) {
+			return errors.New(fmt.Sprintf("Branch name can not contain '%c'", r))
+		}
+	}
+
+	for _, c := range strings.Split(name, "/") {
+		if c == "" {
+			return errors.New("Branch name can not contain empty components")
+		}
+		if strings.HasPrefix(c, ".") {
+			return errors.New("Branch name components can not start with '.'")
+		}
+		if strings.HasSuffix(c, ".lock") {
+			return errors.New("Branch name components can not end with '.lock'")
+		}
+	}
+
+	return nil
+}
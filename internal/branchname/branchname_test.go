@@ -0,0 +1,49 @@
+package branchname
+
+import (
+	"go-track/internal/model"
+	"testing"
+)
+
+func TestFromTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		item     model.Item
+		expected string
+	}{
+		{"", model.Item{Id: 3, Name: "Add login page", IssueNumber: 12}, "feature/12-add-login-page"},
+		{"", model.Item{Id: 3, Name: "fix(auth): Token expires too early!", IssueNumber: 7}, "fix/7-token-expires-too-early"},
+		{"", model.Item{Id: 3, Name: "Add login page", IssueNumber: -1}, "feature/add-login-page"},
+		{"{item_id}/{slug}", model.Item{Id: 3, Name: "Ünïcode & spaces", IssueNumber: -1}, "3/unicode-spaces"},
+		{"{slug}", model.Item{Id: 3, Name: "Crème brûlée für Straße", IssueNumber: -1}, "creme-brulee-fur-strasse"},
+		{"users/{slug}.lock", model.Item{Id: 3, Name: "..", IssueNumber: -1}, "users"},
+		{"{slug}.lock.lock", model.Item{Id: 3, Name: "Add login", IssueNumber: -1}, "add-login"},
+		{"{slug}.lock-/x", model.Item{Id: 3, Name: "Add login", IssueNumber: -1}, "add-login/x"},
+	}
+
+	for _, test := range tests {
+		actual := FromTemplate(test.template, test.item)
+		if actual != test.expected {
+			t.Errorf("FromTemplate(%q, %q) = %q, expected %q", test.template, test.item.Name, actual, test.expected)
+		}
+		if err := Validate(actual); err != nil {
+			t.Errorf("FromTemplate(%q, %q) generated invalid name %q: %s", test.template, test.item.Name, actual, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []string{"main", "feature/12-add-login", "release/v1.2.0", "a@b"}
+	for _, name := range valid {
+		if err := Validate(name); err != nil {
+			t.Errorf("Validate(%q) returned error: %s", name, err)
+		}
+	}
+
+	invalid := []string{"", "@", "-main", "main.", "a..b", "a@{b", "has space", "a:b", "a//b", "a/", ".hidden/x", "x/branch.lock", "a\\b"}
+	for _, name := range invalid {
+		if err := Validate(name); err == nil {
+			t.Errorf("Validate(%q) expected an error", name)
+		}
+	}
+}
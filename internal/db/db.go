@@ -6,20 +6,59 @@ import (
 	"fmt"
 	"go-track/internal/model"
 	"os"
+	"time"
 
 	_ "github.com/tursodatabase/libsql-client-go/libsql"
 )
 
 type DatabaseFacade interface {
+	Ping() error
+
 	GetProject(id int) (model.Project, error)
+	GetProjects() ([]model.Project, error)
+	GetProjectSettings(projectID int) (model.ProjectSettings, error)
+	UpdateProjectSettings(settings model.ProjectSettings) (model.ProjectSettings, error)
 	GetColumnsForProject(projectID int) ([]model.Column, error)
+	GetColumnsPage(projectID, limit, offset int) ([]model.Column, int, error)
 	GetColumn(id int) (model.Column, error)
 	AddItemToColumn(name string, columnID int) (model.Item, error)
 	GetNextItemColumnOrder(columnID int) (int, error)
 
 	GetItem(id int) (model.Item, error)
+	GetItemsPage(projectID, columnID, limit, offset int) ([]model.Item, int, error)
 	UpdateItem(id int, item model.Item) (model.Item, error)
 	DeleteItem(itemID int) error
+
+	GetProjectMembers(projectID int) ([]model.Member, error)
+	GetProjectMember(projectID int, user model.User) (model.Member, error)
+	SaveProjectMember(member model.Member) (model.Member, error)
+	DeleteProjectMember(projectID int, username string) error
+
+	UpsertUser(user model.User) (model.User, error)
+	GetUser(id int) (model.User, error)
+	GetUserByLogin(login string) (model.User, error)
+	TouchUser(id int, lastSeenAt time.Time) error
+	LinkProjectMembers(user model.User) error
+
+	CreateSession(session model.Session) error
+	GetSession(id string) (model.Session, error)
+	GetSessionsForUser(user model.User) ([]model.Session, error)
+	TouchSession(id string, lastSeenAt time.Time) error
+	ExtendSession(id string, expiresAt time.Time) error
+	RevokeSession(id string, revokedAt time.Time) error
+	RevokeSessionsForUser(user model.User, revokedAt time.Time) error
+	DeleteSessionsExpiredBefore(before time.Time) error
+
+	CreateAPIToken(token model.APIToken) (model.APIToken, error)
+	GetAPITokenByHash(hash string) (model.APIToken, error)
+	GetAPITokensForUser(user model.User) ([]model.APIToken, error)
+	TouchAPIToken(id int, lastUsedAt time.Time) error
+	RevokeAPIToken(id int, user model.User, revokedAt time.Time) error
+	RevokeAPITokensForUser(user model.User, revokedAt time.Time) error
+
+	GetUserToken(user model.User) (model.UserToken, error)
+	SaveUserToken(token model.UserToken) error
+	DeleteUserToken(user model.User) error
 }
 
 type database struct {
@@ -35,9 +74,22 @@ func New() (DatabaseFacade, error) {
 		return nil, errors.New(fmt.Sprintf("Could not open database connection: %s\n", err.Error()))
 	}
 
-	return &database{
+	facade := &database{
 		db: db,
-	}, nil
+	}
+
+	if err := facade.migrate(); err != nil {
+		return nil, err
+	}
+
+	return Instrument(facade), nil
+}
+
+// Ping checks the connection with a query, as connections are only made once
+// they are needed.
+func (db *database) Ping() error {
+	var one int
+	return db.db.QueryRow("SELECT 1").Scan(&one)
 }
 
 func (db *database) GetProject(id int) (model.Project, error) {
@@ -58,6 +110,385 @@ func (db *database) GetProject(id int) (model.Project, error) {
 	return proj, nil
 }
 
+// GetProjects returns every project without its columns, ordered by id.
+func (db *database) GetProjects() ([]model.Project, error) {
+	rows, err := db.db.Query("SELECT id, name FROM `gt_project` ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := make([]model.Project, 0)
+	for rows.Next() {
+		var proj model.Project
+		if err := rows.Scan(&proj.Id, &proj.Name); err != nil {
+			return nil, err
+		}
+		projects = append(projects, proj)
+	}
+
+	return projects, rows.Err()
+}
+
+func (db *database) GetProjectSettings(projectID int) (model.ProjectSettings, error) {
+	row := db.db.QueryRow("SELECT project_id, gh_owner, gh_repo, merge_method, draft_pull_requests, branch_template, default_branch, roles_from_repo FROM `gt_project_settings` WHERE project_id=?", projectID)
+
+	var settings model.ProjectSettings
+	if err := row.Scan(&settings.ProjectID, &settings.Owner, &settings.Repo, &settings.MergeMethod, &settings.DraftPullRequests, &settings.BranchTemplate, &settings.DefaultBranch, &settings.RolesFromRepository); err != nil {
+		if err == sql.ErrNoRows {
+			return model.DefaultProjectSettings(projectID), nil
+		}
+		return model.ProjectSettings{}, err
+	}
+
+	return settings, nil
+}
+
+func (db *database) UpdateProjectSettings(settings model.ProjectSettings) (model.ProjectSettings, error) {
+	row := db.db.QueryRow("INSERT INTO `gt_project_settings` (project_id, gh_owner, gh_repo, merge_method, draft_pull_requests, branch_template, default_branch, roles_from_repo) VALUES (?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT(project_id) DO UPDATE SET gh_owner=excluded.gh_owner, gh_repo=excluded.gh_repo, merge_method=excluded.merge_method, draft_pull_requests=excluded.draft_pull_requests, branch_template=excluded.branch_template, default_branch=excluded.default_branch, roles_from_repo=excluded.roles_from_repo RETURNING project_id, gh_owner, gh_repo, merge_method, draft_pull_requests, branch_template, default_branch, roles_from_repo", settings.ProjectID, settings.Owner, settings.Repo, settings.MergeMethod, settings.DraftPullRequests, settings.BranchTemplate, settings.DefaultBranch, settings.RolesFromRepository)
+
+	var updated model.ProjectSettings
+	if err := row.Scan(&updated.ProjectID, &updated.Owner, &updated.Repo, &updated.MergeMethod, &updated.DraftPullRequests, &updated.BranchTemplate, &updated.DefaultBranch, &updated.RolesFromRepository); err != nil {
+		return model.ProjectSettings{}, err
+	}
+
+	return updated, nil
+}
+
+func (db *database) GetProjectMembers(projectID int) ([]model.Member, error) {
+	rows, err := db.db.Query("SELECT project_id, username, role, COALESCE(user_id, 0) FROM `gt_project_member` WHERE project_id=? ORDER BY username", projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make([]model.Member, 0)
+	for rows.Next() {
+		var member model.Member
+		if err := rows.Scan(&member.ProjectID, &member.Username, &member.Role, &member.UserID); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// ownedBy matches the rows of a user, see model.User.Owns. It takes the id and
+// the login of the user as arguments.
+const ownedBy = "(user_id=? OR (user_id IS NULL AND username=?))"
+
+// nullableID stores the id of an unknown user as NULL.
+func nullableID(id int) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// GetProjectMember returns a member without a role when the user is not a
+// member of the project.
+func (db *database) GetProjectMember(projectID int, user model.User) (model.Member, error) {
+	row := db.db.QueryRow("SELECT project_id, username, role, COALESCE(user_id, 0) FROM `gt_project_member` WHERE project_id=? AND "+ownedBy, projectID, user.Id, user.Login)
+
+	member := model.Member{ProjectID: projectID, Username: user.Login}
+	if err := row.Scan(&member.ProjectID, &member.Username, &member.Role, &member.UserID); err != nil {
+		if err == sql.ErrNoRows {
+			return member, nil
+		}
+		return model.Member{}, err
+	}
+
+	return member, nil
+}
+
+func (db *database) SaveProjectMember(member model.Member) (model.Member, error) {
+	row := db.db.QueryRow("INSERT INTO `gt_project_member` (project_id, username, role, user_id) VALUES (?, ?, ?, (SELECT id FROM `gt_user` WHERE login=? ORDER BY last_seen_at DESC LIMIT 1)) ON CONFLICT(project_id, username) DO UPDATE SET role=excluded.role, user_id=excluded.user_id RETURNING project_id, username, role, COALESCE(user_id, 0)", member.ProjectID, member.Username, member.Role, member.Username)
+
+	var saved model.Member
+	if err := row.Scan(&saved.ProjectID, &saved.Username, &saved.Role, &saved.UserID); err != nil {
+		return model.Member{}, err
+	}
+
+	return saved, nil
+}
+
+func (db *database) DeleteProjectMember(projectID int, username string) error {
+	_, err := db.db.Exec("DELETE FROM `gt_project_member` WHERE project_id=? AND username=?", projectID, username)
+
+	return err
+}
+
+const userColumns = "id, github_id, login, name, avatar_url, created_at, last_seen_at"
+
+func scanUser(row scanner) (model.User, error) {
+	var user model.User
+	var createdAt, lastSeenAt int64
+	if err := row.Scan(&user.Id, &user.GithubID, &user.Login, &user.Name, &user.AvatarUrl, &createdAt, &lastSeenAt); err != nil {
+		return model.User{}, err
+	}
+
+	user.CreatedAt = fromUnix(createdAt)
+	user.LastSeenAt = fromUnix(lastSeenAt)
+
+	return user, nil
+}
+
+// UpsertUser creates the user or updates the profile of the user with the same
+// GitHub id, so renamed GitHub accounts keep their id.
+func (db *database) UpsertUser(user model.User) (model.User, error) {
+	row := db.db.QueryRow("INSERT INTO `gt_user` (github_id, login, name, avatar_url, created_at, last_seen_at) VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT(github_id) DO UPDATE SET login=excluded.login, name=excluded.name, avatar_url=excluded.avatar_url, last_seen_at=excluded.last_seen_at RETURNING "+userColumns, user.GithubID, user.Login, user.Name, user.AvatarUrl, toUnix(user.CreatedAt), toUnix(user.LastSeenAt))
+
+	return scanUser(row)
+}
+
+func (db *database) GetUser(id int) (model.User, error) {
+	row := db.db.QueryRow("SELECT "+userColumns+" FROM `gt_user` WHERE id=?", id)
+
+	return scanUser(row)
+}
+
+// GetUserByLogin returns the user who signed in last with the login, or a user
+// without an id when nobody has.
+func (db *database) GetUserByLogin(login string) (model.User, error) {
+	row := db.db.QueryRow("SELECT "+userColumns+" FROM `gt_user` WHERE login=? ORDER BY last_seen_at DESC LIMIT 1", login)
+
+	user, err := scanUser(row)
+	if err == sql.ErrNoRows {
+		return model.User{Login: login}, nil
+	}
+
+	return user, err
+}
+
+func (db *database) TouchUser(id int, lastSeenAt time.Time) error {
+	_, err := db.db.Exec("UPDATE `gt_user` SET last_seen_at=? WHERE id=?", toUnix(lastSeenAt), id)
+
+	return err
+}
+
+// LinkProjectMembers points the memberships added for the login of the user to
+// the user, and renames the memberships of the user when the login changed.
+func (db *database) LinkProjectMembers(user model.User) error {
+	_, err := db.db.Exec("UPDATE OR IGNORE `gt_project_member` SET user_id=?, username=? WHERE user_id=? OR (user_id IS NULL AND username=?)", user.Id, user.Login, user.Id, user.Login)
+
+	return err
+}
+
+func (db *database) CreateSession(session model.Session) error {
+	_, err := db.db.Exec("INSERT INTO `gt_session` (id, user_id, username, user_agent, created_at, last_seen_at, expires_at, revoked_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)", session.Id, nullableID(session.UserID), session.Username, session.UserAgent, toUnix(session.CreatedAt), toUnix(session.LastSeenAt), toUnix(session.ExpiresAt), toUnix(session.RevokedAt))
+
+	return err
+}
+
+const sessionColumns = "id, COALESCE(user_id, 0), username, user_agent, created_at, last_seen_at, expires_at, revoked_at"
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSession(row scanner) (model.Session, error) {
+	var session model.Session
+	var createdAt, lastSeenAt, expiresAt, revokedAt int64
+	if err := row.Scan(&session.Id, &session.UserID, &session.Username, &session.UserAgent, &createdAt, &lastSeenAt, &expiresAt, &revokedAt); err != nil {
+		return model.Session{}, err
+	}
+
+	session.CreatedAt = fromUnix(createdAt)
+	session.LastSeenAt = fromUnix(lastSeenAt)
+	session.ExpiresAt = fromUnix(expiresAt)
+	session.RevokedAt = fromUnix(revokedAt)
+
+	return session, nil
+}
+
+func (db *database) GetSession(id string) (model.Session, error) {
+	row := db.db.QueryRow("SELECT "+sessionColumns+" FROM `gt_session` WHERE id=?", id)
+
+	return scanSession(row)
+}
+
+// GetSessionsForUser returns the sessions of the user that have not been revoked, newest first.
+func (db *database) GetSessionsForUser(user model.User) ([]model.Session, error) {
+	rows, err := db.db.Query("SELECT "+sessionColumns+" FROM `gt_session` WHERE "+ownedBy+" AND revoked_at=0 ORDER BY created_at DESC", user.Id, user.Login)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]model.Session, 0)
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+func (db *database) TouchSession(id string, lastSeenAt time.Time) error {
+	_, err := db.db.Exec("UPDATE `gt_session` SET last_seen_at=? WHERE id=?", toUnix(lastSeenAt), id)
+
+	return err
+}
+
+func (db *database) ExtendSession(id string, expiresAt time.Time) error {
+	_, err := db.db.Exec("UPDATE `gt_session` SET expires_at=? WHERE id=?", toUnix(expiresAt), id)
+
+	return err
+}
+
+func (db *database) RevokeSession(id string, revokedAt time.Time) error {
+	_, err := db.db.Exec("UPDATE `gt_session` SET revoked_at=? WHERE id=? AND revoked_at=0", toUnix(revokedAt), id)
+
+	return err
+}
+
+func (db *database) RevokeSessionsForUser(user model.User, revokedAt time.Time) error {
+	_, err := db.db.Exec("UPDATE `gt_session` SET revoked_at=? WHERE "+ownedBy+" AND revoked_at=0", toUnix(revokedAt), user.Id, user.Login)
+
+	return err
+}
+
+func (db *database) DeleteSessionsExpiredBefore(before time.Time) error {
+	_, err := db.db.Exec("DELETE FROM `gt_session` WHERE expires_at<?", toUnix(before))
+
+	return err
+}
+
+const apiTokenColumns = "id, COALESCE(user_id, 0), username, name, project_id, scope, token_hash, created_at, expires_at, last_used_at, revoked_at"
+
+func (db *database) CreateAPIToken(token model.APIToken) (model.APIToken, error) {
+	row := db.db.QueryRow("INSERT INTO `gt_api_token` (user_id, username, name, project_id, scope, token_hash, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING "+apiTokenColumns, nullableID(token.UserID), token.Username, token.Name, token.ProjectID, token.Scope, token.TokenHash, toUnix(token.CreatedAt), toUnix(token.ExpiresAt))
+
+	return scanAPIToken(row)
+}
+
+func scanAPIToken(row scanner) (model.APIToken, error) {
+	var token model.APIToken
+	var createdAt, expiresAt, lastUsedAt, revokedAt int64
+	if err := row.Scan(&token.Id, &token.UserID, &token.Username, &token.Name, &token.ProjectID, &token.Scope, &token.TokenHash, &createdAt, &expiresAt, &lastUsedAt, &revokedAt); err != nil {
+		return model.APIToken{}, err
+	}
+
+	token.CreatedAt = fromUnix(createdAt)
+	token.ExpiresAt = fromUnix(expiresAt)
+	token.LastUsedAt = fromUnix(lastUsedAt)
+	token.RevokedAt = fromUnix(revokedAt)
+
+	return token, nil
+}
+
+func (db *database) GetAPITokenByHash(hash string) (model.APIToken, error) {
+	row := db.db.QueryRow("SELECT "+apiTokenColumns+" FROM `gt_api_token` WHERE token_hash=?", hash)
+
+	return scanAPIToken(row)
+}
+
+// GetAPITokensForUser returns the tokens of the user that have not been revoked, newest first.
+func (db *database) GetAPITokensForUser(user model.User) ([]model.APIToken, error) {
+	rows, err := db.db.Query("SELECT "+apiTokenColumns+" FROM `gt_api_token` WHERE "+ownedBy+" AND revoked_at=0 ORDER BY created_at DESC", user.Id, user.Login)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make([]model.APIToken, 0)
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+func (db *database) TouchAPIToken(id int, lastUsedAt time.Time) error {
+	_, err := db.db.Exec("UPDATE `gt_api_token` SET last_used_at=? WHERE id=?", toUnix(lastUsedAt), id)
+
+	return err
+}
+
+// RevokeAPIToken only revokes the token when it belongs to the user.
+func (db *database) RevokeAPIToken(id int, user model.User, revokedAt time.Time) error {
+	res, err := db.db.Exec("UPDATE `gt_api_token` SET revoked_at=? WHERE id=? AND "+ownedBy+" AND revoked_at=0", toUnix(revokedAt), id, user.Id, user.Login)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New(fmt.Sprintf("No API token with id %d", id))
+	}
+
+	return nil
+}
+
+func (db *database) RevokeAPITokensForUser(user model.User, revokedAt time.Time) error {
+	_, err := db.db.Exec("UPDATE `gt_api_token` SET revoked_at=? WHERE "+ownedBy+" AND revoked_at=0", toUnix(revokedAt), user.Id, user.Login)
+
+	return err
+}
+
+// GetUserToken returns an empty token when none is stored for the user.
+func (db *database) GetUserToken(user model.User) (model.UserToken, error) {
+	row := db.db.QueryRow("SELECT COALESCE(user_id, 0), username, access_token, expires_at, refresh_token, refresh_expires_at FROM `gt_user_token` WHERE user_id=?", user.Id)
+
+	token := model.UserToken{UserID: user.Id, Username: user.Login}
+	var expiresAt, refreshExpiresAt int64
+	if err := row.Scan(&token.UserID, &token.Username, &token.AccessToken, &expiresAt, &token.RefreshToken, &refreshExpiresAt); err != nil {
+		if err == sql.ErrNoRows {
+			return token, nil
+		}
+		return model.UserToken{}, err
+	}
+
+	token.ExpiresAt = fromUnix(expiresAt)
+	token.RefreshExpiresAt = fromUnix(refreshExpiresAt)
+
+	return token, nil
+}
+
+// SaveUserToken replaces the token of the user. Tokens are stored per user,
+// so a renamed user keeps their token.
+func (db *database) SaveUserToken(token model.UserToken) error {
+	if token.UserID == 0 {
+		return errors.New(fmt.Sprintf("Could not save the token of '%s', the user is not stored", token.Username))
+	}
+
+	_, err := db.db.Exec("INSERT INTO `gt_user_token` (user_id, username, access_token, expires_at, refresh_token, refresh_expires_at) VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT(user_id) DO UPDATE SET username=excluded.username, access_token=excluded.access_token, expires_at=excluded.expires_at, refresh_token=excluded.refresh_token, refresh_expires_at=excluded.refresh_expires_at", token.UserID, token.Username, token.AccessToken, toUnix(token.ExpiresAt), token.RefreshToken, toUnix(token.RefreshExpiresAt))
+
+	return err
+}
+
+func (db *database) DeleteUserToken(user model.User) error {
+	_, err := db.db.Exec("DELETE FROM `gt_user_token` WHERE user_id=?", user.Id)
+
+	return err
+}
+
+// toUnix and fromUnix store a zero time as 0.
+func toUnix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func fromUnix(seconds int64) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
+
 func (db *database) GetColumn(id int) (model.Column, error) {
 	row := db.db.QueryRow("SELECT id, name, project_id FROM `gt_project_column` WHERE id=?", id)
 
@@ -107,6 +538,82 @@ func (db *database) GetColumnsForProject(projectID int) ([]model.Column, error)
 	return cols, err
 }
 
+// GetColumnsPage returns limit columns of the project from offset, and the
+// number of columns of the project.
+func (db *database) GetColumnsPage(projectID, limit, offset int) ([]model.Column, int, error) {
+	var total int
+	if err := db.db.QueryRow("SELECT COUNT(*) FROM `gt_project_column` WHERE project_id=?", projectID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.db.Query("SELECT id, name, project_id FROM `gt_project_column` WHERE project_id=? ORDER BY id LIMIT ? OFFSET ?", projectID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	cols := make([]model.Column, 0, limit)
+	for rows.Next() {
+		var col model.Column
+		if err := rows.Scan(&col.Id, &col.Name, &col.ProjectID); err != nil {
+			return nil, 0, err
+		}
+		cols = append(cols, col)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	for i := range cols {
+		cols[i].Items, err = db.GetItemsForColumn(cols[i].Id)
+		if err != nil {
+			return nil, 0, errors.Join(errors.New("Could not fetch items for column"), err)
+		}
+	}
+
+	return cols, total, nil
+}
+
+// GetItemsPage returns limit items of the project from offset, ordered by
+// column and position, and the number of items. Only the items of the column
+// are returned when columnID is not -1.
+func (db *database) GetItemsPage(projectID, columnID, limit, offset int) ([]model.Item, int, error) {
+	const inProject = " FROM `gt_project_column_item` AS i JOIN `gt_project_column` AS c ON c.id=i.column_id WHERE c.project_id=? AND (?=-1 OR c.id=?)"
+
+	var total int
+	if err := db.db.QueryRow("SELECT COUNT(*)"+inProject, projectID, columnID, columnID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.db.Query("SELECT i.*"+inProject+" ORDER BY c.id, i.column_order LIMIT ? OFFSET ?", projectID, columnID, columnID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	items := make([]model.Item, 0, limit)
+	for rows.Next() {
+		var item model.Item
+		if err := rows.Scan(
+			&item.Id,
+			&item.Name,
+			&item.ColumnID,
+			&item.ColumnOrder,
+			&item.IssueID,
+			&item.IssueNumber,
+			&item.IssueUrl,
+			&item.BranchName,
+			&item.PullRequestID,
+			&item.PullRequestNumber,
+		); err != nil {
+			return nil, 0, err
+		}
+		items = append(items, item)
+	}
+
+	return items, total, rows.Err()
+}
+
 func (db *database) GetItemsForColumn(columnID int) ([]model.Item, error) {
 	rows, err := db.db.Query("SELECT * FROM `gt_project_column_item` WHERE column_id=? ORDER BY column_order", columnID)
 	if err != nil {
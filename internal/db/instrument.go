@@ -0,0 +1,221 @@
+package db
+
+import (
+	"go-track/internal/metrics"
+	"go-track/internal/model"
+	"time"
+)
+
+// instrumentedDB times every method of the facade it wraps.
+type instrumentedDB struct {
+	next DatabaseFacade
+}
+
+// Instrument records the duration and result of every call to the facade.
+func Instrument(facade DatabaseFacade) DatabaseFacade {
+	return &instrumentedDB{next: facade}
+}
+
+func observe(method string, start time.Time, err *error) {
+	metrics.DBQueryDuration.WithLabelValues(method, metrics.Result(*err)).Observe(time.Since(start).Seconds())
+}
+
+func (db *instrumentedDB) Ping() (err error) {
+	defer observe("Ping", time.Now(), &err)
+	return db.next.Ping()
+}
+
+func (db *instrumentedDB) GetProject(id int) (_ model.Project, err error) {
+	defer observe("GetProject", time.Now(), &err)
+	return db.next.GetProject(id)
+}
+
+func (db *instrumentedDB) GetProjects() (_ []model.Project, err error) {
+	defer observe("GetProjects", time.Now(), &err)
+	return db.next.GetProjects()
+}
+
+func (db *instrumentedDB) GetProjectSettings(projectID int) (_ model.ProjectSettings, err error) {
+	defer observe("GetProjectSettings", time.Now(), &err)
+	return db.next.GetProjectSettings(projectID)
+}
+
+func (db *instrumentedDB) UpdateProjectSettings(settings model.ProjectSettings) (_ model.ProjectSettings, err error) {
+	defer observe("UpdateProjectSettings", time.Now(), &err)
+	return db.next.UpdateProjectSettings(settings)
+}
+
+func (db *instrumentedDB) GetColumnsForProject(projectID int) (_ []model.Column, err error) {
+	defer observe("GetColumnsForProject", time.Now(), &err)
+	return db.next.GetColumnsForProject(projectID)
+}
+
+func (db *instrumentedDB) GetColumnsPage(projectID, limit, offset int) (_ []model.Column, _ int, err error) {
+	defer observe("GetColumnsPage", time.Now(), &err)
+	return db.next.GetColumnsPage(projectID, limit, offset)
+}
+
+func (db *instrumentedDB) GetColumn(id int) (_ model.Column, err error) {
+	defer observe("GetColumn", time.Now(), &err)
+	return db.next.GetColumn(id)
+}
+
+func (db *instrumentedDB) AddItemToColumn(name string, columnID int) (_ model.Item, err error) {
+	defer observe("AddItemToColumn", time.Now(), &err)
+	return db.next.AddItemToColumn(name, columnID)
+}
+
+func (db *instrumentedDB) GetNextItemColumnOrder(columnID int) (_ int, err error) {
+	defer observe("GetNextItemColumnOrder", time.Now(), &err)
+	return db.next.GetNextItemColumnOrder(columnID)
+}
+
+func (db *instrumentedDB) GetItem(id int) (_ model.Item, err error) {
+	defer observe("GetItem", time.Now(), &err)
+	return db.next.GetItem(id)
+}
+
+func (db *instrumentedDB) GetItemsPage(projectID, columnID, limit, offset int) (_ []model.Item, _ int, err error) {
+	defer observe("GetItemsPage", time.Now(), &err)
+	return db.next.GetItemsPage(projectID, columnID, limit, offset)
+}
+
+func (db *instrumentedDB) UpdateItem(id int, item model.Item) (_ model.Item, err error) {
+	defer observe("UpdateItem", time.Now(), &err)
+	return db.next.UpdateItem(id, item)
+}
+
+func (db *instrumentedDB) DeleteItem(itemID int) (err error) {
+	defer observe("DeleteItem", time.Now(), &err)
+	return db.next.DeleteItem(itemID)
+}
+
+func (db *instrumentedDB) GetProjectMembers(projectID int) (_ []model.Member, err error) {
+	defer observe("GetProjectMembers", time.Now(), &err)
+	return db.next.GetProjectMembers(projectID)
+}
+
+func (db *instrumentedDB) GetProjectMember(projectID int, user model.User) (_ model.Member, err error) {
+	defer observe("GetProjectMember", time.Now(), &err)
+	return db.next.GetProjectMember(projectID, user)
+}
+
+func (db *instrumentedDB) SaveProjectMember(member model.Member) (_ model.Member, err error) {
+	defer observe("SaveProjectMember", time.Now(), &err)
+	return db.next.SaveProjectMember(member)
+}
+
+func (db *instrumentedDB) DeleteProjectMember(projectID int, username string) (err error) {
+	defer observe("DeleteProjectMember", time.Now(), &err)
+	return db.next.DeleteProjectMember(projectID, username)
+}
+
+func (db *instrumentedDB) UpsertUser(user model.User) (_ model.User, err error) {
+	defer observe("UpsertUser", time.Now(), &err)
+	return db.next.UpsertUser(user)
+}
+
+func (db *instrumentedDB) GetUser(id int) (_ model.User, err error) {
+	defer observe("GetUser", time.Now(), &err)
+	return db.next.GetUser(id)
+}
+
+func (db *instrumentedDB) GetUserByLogin(login string) (_ model.User, err error) {
+	defer observe("GetUserByLogin", time.Now(), &err)
+	return db.next.GetUserByLogin(login)
+}
+
+func (db *instrumentedDB) TouchUser(id int, lastSeenAt time.Time) (err error) {
+	defer observe("TouchUser", time.Now(), &err)
+	return db.next.TouchUser(id, lastSeenAt)
+}
+
+func (db *instrumentedDB) LinkProjectMembers(user model.User) (err error) {
+	defer observe("LinkProjectMembers", time.Now(), &err)
+	return db.next.LinkProjectMembers(user)
+}
+
+func (db *instrumentedDB) CreateSession(session model.Session) (err error) {
+	defer observe("CreateSession", time.Now(), &err)
+	return db.next.CreateSession(session)
+}
+
+func (db *instrumentedDB) GetSession(id string) (_ model.Session, err error) {
+	defer observe("GetSession", time.Now(), &err)
+	return db.next.GetSession(id)
+}
+
+func (db *instrumentedDB) GetSessionsForUser(user model.User) (_ []model.Session, err error) {
+	defer observe("GetSessionsForUser", time.Now(), &err)
+	return db.next.GetSessionsForUser(user)
+}
+
+func (db *instrumentedDB) TouchSession(id string, lastSeenAt time.Time) (err error) {
+	defer observe("TouchSession", time.Now(), &err)
+	return db.next.TouchSession(id, lastSeenAt)
+}
+
+func (db *instrumentedDB) ExtendSession(id string, expiresAt time.Time) (err error) {
+	defer observe("ExtendSession", time.Now(), &err)
+	return db.next.ExtendSession(id, expiresAt)
+}
+
+func (db *instrumentedDB) RevokeSession(id string, revokedAt time.Time) (err error) {
+	defer observe("RevokeSession", time.Now(), &err)
+	return db.next.RevokeSession(id, revokedAt)
+}
+
+func (db *instrumentedDB) RevokeSessionsForUser(user model.User, revokedAt time.Time) (err error) {
+	defer observe("RevokeSessionsForUser", time.Now(), &err)
+	return db.next.RevokeSessionsForUser(user, revokedAt)
+}
+
+func (db *instrumentedDB) DeleteSessionsExpiredBefore(before time.Time) (err error) {
+	defer observe("DeleteSessionsExpiredBefore", time.Now(), &err)
+	return db.next.DeleteSessionsExpiredBefore(before)
+}
+
+func (db *instrumentedDB) CreateAPIToken(token model.APIToken) (_ model.APIToken, err error) {
+	defer observe("CreateAPIToken", time.Now(), &err)
+	return db.next.CreateAPIToken(token)
+}
+
+func (db *instrumentedDB) GetAPITokenByHash(hash string) (_ model.APIToken, err error) {
+	defer observe("GetAPITokenByHash", time.Now(), &err)
+	return db.next.GetAPITokenByHash(hash)
+}
+
+func (db *instrumentedDB) GetAPITokensForUser(user model.User) (_ []model.APIToken, err error) {
+	defer observe("GetAPITokensForUser", time.Now(), &err)
+	return db.next.GetAPITokensForUser(user)
+}
+
+func (db *instrumentedDB) TouchAPIToken(id int, lastUsedAt time.Time) (err error) {
+	defer observe("TouchAPIToken", time.Now(), &err)
+	return db.next.TouchAPIToken(id, lastUsedAt)
+}
+
+func (db *instrumentedDB) RevokeAPIToken(id int, user model.User, revokedAt time.Time) (err error) {
+	defer observe("RevokeAPIToken", time.Now(), &err)
+	return db.next.RevokeAPIToken(id, user, revokedAt)
+}
+
+func (db *instrumentedDB) RevokeAPITokensForUser(user model.User, revokedAt time.Time) (err error) {
+	defer observe("RevokeAPITokensForUser", time.Now(), &err)
+	return db.next.RevokeAPITokensForUser(user, revokedAt)
+}
+
+func (db *instrumentedDB) GetUserToken(user model.User) (_ model.UserToken, err error) {
+	defer observe("GetUserToken", time.Now(), &err)
+	return db.next.GetUserToken(user)
+}
+
+func (db *instrumentedDB) SaveUserToken(token model.UserToken) (err error) {
+	defer observe("SaveUserToken", time.Now(), &err)
+	return db.next.SaveUserToken(token)
+}
+
+func (db *instrumentedDB) DeleteUserToken(user model.User) (err error) {
+	defer observe("DeleteUserToken", time.Now(), &err)
+	return db.next.DeleteUserToken(user)
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// migrations are applied in order and exactly once. New migrations must be
+// appended to the end of the list, existing entries must never be changed.
+var migrations = []string{
+	"CREATE TABLE IF NOT EXISTS `gt_project_settings` (project_id INTEGER PRIMARY KEY REFERENCES `gt_project`(id) ON DELETE CASCADE, merge_method TEXT NOT NULL DEFAULT 'merge')",
+	"ALTER TABLE `gt_project_settings` ADD COLUMN draft_pull_requests INTEGER NOT NULL DEFAULT 0",
+	"ALTER TABLE `gt_project_settings` ADD COLUMN branch_template TEXT NOT NULL DEFAULT '{type}/{issue_number}-{slug}'",
+	"ALTER TABLE `gt_project_settings` ADD COLUMN default_branch TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE `gt_project_settings` ADD COLUMN gh_owner TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE `gt_project_settings` ADD COLUMN gh_repo TEXT NOT NULL DEFAULT ''",
+	"CREATE TABLE IF NOT EXISTS `gt_user_token` (username TEXT PRIMARY KEY, access_token TEXT NOT NULL, expires_at INTEGER NOT NULL DEFAULT 0, refresh_token TEXT NOT NULL DEFAULT '', refresh_expires_at INTEGER NOT NULL DEFAULT 0)",
+	"CREATE TABLE IF NOT EXISTS `gt_project_member` (project_id INTEGER NOT NULL REFERENCES `gt_project`(id) ON DELETE CASCADE, username TEXT NOT NULL COLLATE NOCASE, role TEXT NOT NULL, PRIMARY KEY (project_id, username))",
+	"ALTER TABLE `gt_project_settings` ADD COLUMN roles_from_repo INTEGER NOT NULL DEFAULT 0",
+	"CREATE TABLE IF NOT EXISTS `gt_session` (id TEXT PRIMARY KEY, username TEXT NOT NULL COLLATE NOCASE, user_agent TEXT NOT NULL DEFAULT '', created_at INTEGER NOT NULL, last_seen_at INTEGER NOT NULL, expires_at INTEGER NOT NULL, revoked_at INTEGER NOT NULL DEFAULT 0)",
+	"CREATE INDEX IF NOT EXISTS `gt_session_username` ON `gt_session` (username)",
+	"CREATE TABLE IF NOT EXISTS `gt_api_token` (id INTEGER PRIMARY KEY AUTOINCREMENT, username TEXT NOT NULL COLLATE NOCASE, name TEXT NOT NULL, project_id INTEGER NOT NULL REFERENCES `gt_project`(id) ON DELETE CASCADE, scope TEXT NOT NULL, token_hash TEXT NOT NULL UNIQUE, created_at INTEGER NOT NULL, expires_at INTEGER NOT NULL, last_used_at INTEGER NOT NULL DEFAULT 0, revoked_at INTEGER NOT NULL DEFAULT 0)",
+	"CREATE TABLE IF NOT EXISTS `gt_user` (id INTEGER PRIMARY KEY AUTOINCREMENT, github_id INTEGER NOT NULL UNIQUE, login TEXT NOT NULL COLLATE NOCASE, name TEXT NOT NULL DEFAULT '', avatar_url TEXT NOT NULL DEFAULT '', created_at INTEGER NOT NULL, last_seen_at INTEGER NOT NULL)",
+	"CREATE INDEX IF NOT EXISTS `gt_user_login` ON `gt_user` (login)",
+	"ALTER TABLE `gt_project_member` ADD COLUMN user_id INTEGER REFERENCES `gt_user`(id) ON DELETE SET NULL",
+	"ALTER TABLE `gt_session` ADD COLUMN user_id INTEGER REFERENCES `gt_user`(id) ON DELETE CASCADE",
+	"UPDATE `gt_session` SET user_id=(SELECT id FROM `gt_user` WHERE login=`gt_session`.username ORDER BY last_seen_at DESC LIMIT 1)",
+	"CREATE INDEX IF NOT EXISTS `gt_session_user_id` ON `gt_session` (user_id)",
+	"ALTER TABLE `gt_api_token` ADD COLUMN user_id INTEGER REFERENCES `gt_user`(id) ON DELETE CASCADE",
+	"UPDATE `gt_api_token` SET user_id=(SELECT id FROM `gt_user` WHERE login=`gt_api_token`.username ORDER BY last_seen_at DESC LIMIT 1)",
+	"ALTER TABLE `gt_user_token` ADD COLUMN user_id INTEGER REFERENCES `gt_user`(id) ON DELETE CASCADE",
+	"UPDATE `gt_user_token` SET user_id=(SELECT id FROM `gt_user` WHERE login=`gt_user_token`.username ORDER BY last_seen_at DESC LIMIT 1)",
+	// tokens are keyed by the user instead of the login, tokens of logins
+	// without a user are dropped and those users sign in again
+	"CREATE TABLE `gt_user_token_by_id` (user_id INTEGER PRIMARY KEY REFERENCES `gt_user`(id) ON DELETE CASCADE, username TEXT NOT NULL, access_token TEXT NOT NULL, expires_at INTEGER NOT NULL DEFAULT 0, refresh_token TEXT NOT NULL DEFAULT '', refresh_expires_at INTEGER NOT NULL DEFAULT 0)",
+	"INSERT OR IGNORE INTO `gt_user_token_by_id` (user_id, username, access_token, expires_at, refresh_token, refresh_expires_at) SELECT user_id, username, access_token, expires_at, refresh_token, refresh_expires_at FROM `gt_user_token` WHERE user_id IS NOT NULL",
+	"DROP TABLE `gt_user_token`",
+	"ALTER TABLE `gt_user_token_by_id` RENAME TO `gt_user_token`",
+}
+
+func (db *database) migrate() error {
+	_, err := db.db.Exec("CREATE TABLE IF NOT EXISTS `gt_migration` (version INTEGER PRIMARY KEY)")
+	if err != nil {
+		return errors.Join(errors.New("Could not create migration table"), err)
+	}
+
+	var version sql.NullInt64
+	if err := db.db.QueryRow("SELECT MAX(version) FROM `gt_migration`").Scan(&version); err != nil {
+		return errors.Join(errors.New("Could not read schema version"), err)
+	}
+
+	for i := int(version.Int64); i < len(migrations); i++ {
+		if err := db.applyMigration(i+1, migrations[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyMigration records the version in the same transaction as the migration,
+// so a migration that is not idempotent is never applied twice.
+func (db *database) applyMigration(version int, migration string) error {
+	tx, err := db.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return errors.Join(errors.New(fmt.Sprintf("Starting migration %d failed", version)), err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(migration); err != nil {
+		return errors.Join(errors.New(fmt.Sprintf("Applying migration %d failed", version)), err)
+	}
+	if _, err := tx.Exec("INSERT INTO `gt_migration` (version) VALUES (?)", version); err != nil {
+		return errors.Join(errors.New(fmt.Sprintf("Recording migration %d failed", version)), err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Join(errors.New(fmt.Sprintf("Committing migration %d failed", version)), err)
+	}
+
+	return nil
+}
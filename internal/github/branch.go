@@ -14,72 +14,76 @@ type BranchDTO struct {
 	Commit struct {
 		Sha string `json:"sha"`
 	} `json:"commit"`
+	Protected  bool `json:"protected"`
+	Protection struct {
+		RequiredStatusChecks struct {
+			Contexts []string `json:"contexts"`
+		} `json:"required_status_checks"`
+	} `json:"protection"`
 }
 
+// GetBranches returns every branch of the repository, following the pages of
+// the listing.
 func (gh *githubService) GetBranches(owner string, repo string) ([]BranchDTO, error) {
-	installation, err := gh.GetUserInstallation(owner)
+	token, err := gh.repoToken(owner, repo)
 	if err != nil {
 		return nil, err
 	}
 
-	access, err := gh.GetInstallationAccessToken(installation)
-	if err != nil {
-		return nil, err
-	}
+	branches := make([]BranchDTO, 0)
+	reqUrl := fmt.Sprintf("%s/repos/%s/%s/branches?per_page=100", gh.apiUrl, owner, repo)
+	for reqUrl != "" {
+		req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
-	reqUrl := fmt.Sprintf("https://api.github.com/repos/%s/%s/branches", owner, repo)
-	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", access.Token))
-	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+		res, err := gh.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
 
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
+		resBody, err := io.ReadAll(res.Body)
+		if err != nil {
+			return nil, err
+		}
 
-	resBody, err := io.ReadAll(res.Body)
-	if err != nil {
-		return nil, err
-	}
+		if res.StatusCode != 200 {
+			return nil, errors.New(fmt.Sprintf("Getting branches for repo: '%s/%s', failed with body: %s", owner, repo, resBody))
+		}
 
-	if res.StatusCode != 200 {
-		return nil, errors.New(fmt.Sprintf("Creating issue for repo: '%s/%s', failed with body: %s", owner, repo, resBody))
-	}
+		page := make([]BranchDTO, 0)
+		err = json.Unmarshal(resBody, &page)
+		if err != nil {
+			return nil, err
+		}
+		branches = append(branches, page...)
 
-	branches := make([]BranchDTO, 0, 0)
-	err = json.Unmarshal(resBody, &branches)
-	if err != nil {
-		return nil, err
+		reqUrl = nextPageUrl(res.Header)
 	}
 
 	return branches, nil
 }
 
 func (gh *githubService) GetBranch(owner, repo, name string) (BranchDTO, error) {
-	installation, err := gh.GetUserInstallation(owner)
-	if err != nil {
-		return BranchDTO{}, err
-	}
-
-	access, err := gh.GetInstallationAccessToken(installation)
+	token, err := gh.repoToken(owner, repo)
 	if err != nil {
 		return BranchDTO{}, err
 	}
 
-	reqUrl := fmt.Sprintf("https://api.github.com/repos/%s/%s/branches/%s", owner, repo, name)
+	reqUrl := fmt.Sprintf("%s/repos/%s/%s/branches/%s", gh.apiUrl, owner, repo, name)
 	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
 	if err != nil {
 		return BranchDTO{}, err
 	}
 	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", access.Token))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := gh.client.Do(req)
 	if err != nil {
 		return BranchDTO{}, err
 	}
@@ -108,12 +112,7 @@ type githubReference struct {
 }
 
 func (gh *githubService) CreateBranch(owner, repo, name, fromSha string) (BranchDTO, error) {
-	installation, err := gh.GetUserInstallation(owner)
-	if err != nil {
-		return BranchDTO{}, err
-	}
-
-	access, err := gh.GetInstallationAccessToken(installation)
+	token, err := gh.repoToken(owner, repo)
 	if err != nil {
 		return BranchDTO{}, err
 	}
@@ -129,16 +128,16 @@ func (gh *githubService) CreateBranch(owner, repo, name, fromSha string) (Branch
 	}
 
 	bodyReader := bytes.NewReader(reqBody)
-	reqUrl := fmt.Sprintf("https://api.github.com/repos/%s/%s/git/refs", owner, repo)
+	reqUrl := fmt.Sprintf("%s/repos/%s/%s/git/refs", gh.apiUrl, owner, repo)
 	req, err := http.NewRequest(http.MethodPost, reqUrl, bodyReader)
 	if err != nil {
 		return BranchDTO{}, err
 	}
 	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", access.Token))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := gh.client.Do(req)
 	if err != nil {
 		return BranchDTO{}, err
 	}
@@ -152,38 +151,29 @@ func (gh *githubService) CreateBranch(owner, repo, name, fromSha string) (Branch
 		return BranchDTO{}, errors.New(fmt.Sprintf("Creating branch for repo: '%s/%s', failed with body: %s", owner, repo, resBody))
 	}
 
-	return BranchDTO{
-		Name: name,
-		Commit: struct {
-			Sha string `json:"sha"`
-		}{
-			Sha: fromSha,
-		},
-	}, nil
+	branch := BranchDTO{Name: name}
+	branch.Commit.Sha = fromSha
+
+	return branch, nil
 }
 
 func (gh *githubService) DeleteBranch(owner string, repo string, name string) error {
-	installation, err := gh.GetUserInstallation(owner)
-	if err != nil {
-		return err
-	}
-
-	access, err := gh.GetInstallationAccessToken(installation)
+	token, err := gh.repoToken(owner, repo)
 	if err != nil {
 		return err
 	}
 
 	ref := fmt.Sprintf("heads/%s", name)
-	reqUrl := fmt.Sprintf("https://api.github.com/repos/%s/%s/git/refs/%s", owner, repo, ref)
+	reqUrl := fmt.Sprintf("%s/repos/%s/%s/git/refs/%s", gh.apiUrl, owner, repo, ref)
 	req, err := http.NewRequest(http.MethodDelete, reqUrl, nil)
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", access.Token))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := gh.client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -198,3 +188,134 @@ func (gh *githubService) DeleteBranch(owner string, repo string, name string) er
 
 	return nil
 }
+
+type gitCommitDTO struct {
+	Sha  string `json:"sha"`
+	Tree struct {
+		Sha string `json:"sha"`
+	} `json:"tree"`
+}
+
+type createCommitDTO struct {
+	Message string   `json:"message"`
+	Tree    string   `json:"tree"`
+	Parents []string `json:"parents"`
+}
+
+type updateReferenceDTO struct {
+	Sha string `json:"sha"`
+}
+
+// CreateEmptyCommit adds a commit without changes on top of the branch. GitHub
+// refuses to open pull requests for branches without commits, so this allows a
+// draft pull request to be opened as soon as the branch is created.
+func (gh *githubService) CreateEmptyCommit(owner, repo, branch, message string) (BranchDTO, error) {
+	token, err := gh.repoToken(owner, repo)
+	if err != nil {
+		return BranchDTO{}, err
+	}
+
+	head, err := gh.GetBranch(owner, repo, branch)
+	if err != nil {
+		return BranchDTO{}, err
+	}
+
+	reqUrl := fmt.Sprintf("%s/repos/%s/%s/git/commits/%s", gh.apiUrl, owner, repo, head.Commit.Sha)
+	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return BranchDTO{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	res, err := gh.client.Do(req)
+	if err != nil {
+		return BranchDTO{}, err
+	}
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return BranchDTO{}, err
+	}
+
+	if res.StatusCode != 200 {
+		return BranchDTO{}, errors.New(fmt.Sprintf("Getting commit '%s' for repo: '%s/%s', failed with body: %s", head.Commit.Sha, owner, repo, resBody))
+	}
+
+	var parent gitCommitDTO
+	err = json.Unmarshal(resBody, &parent)
+	if err != nil {
+		return BranchDTO{}, err
+	}
+
+	reqBody, err := json.Marshal(createCommitDTO{
+		Message: message,
+		Tree:    parent.Tree.Sha,
+		Parents: []string{parent.Sha},
+	})
+	if err != nil {
+		return BranchDTO{}, err
+	}
+
+	reqUrl = fmt.Sprintf("%s/repos/%s/%s/git/commits", gh.apiUrl, owner, repo)
+	req, err = http.NewRequest(http.MethodPost, reqUrl, bytes.NewReader(reqBody))
+	if err != nil {
+		return BranchDTO{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	res, err = gh.client.Do(req)
+	if err != nil {
+		return BranchDTO{}, err
+	}
+
+	resBody, err = io.ReadAll(res.Body)
+	if err != nil {
+		return BranchDTO{}, err
+	}
+
+	if res.StatusCode != 201 {
+		return BranchDTO{}, errors.New(fmt.Sprintf("Creating commit for repo: '%s/%s', failed with body: %s", owner, repo, resBody))
+	}
+
+	var commit gitCommitDTO
+	err = json.Unmarshal(resBody, &commit)
+	if err != nil {
+		return BranchDTO{}, err
+	}
+
+	reqBody, err = json.Marshal(updateReferenceDTO{Sha: commit.Sha})
+	if err != nil {
+		return BranchDTO{}, err
+	}
+
+	reqUrl = fmt.Sprintf("%s/repos/%s/%s/git/refs/heads/%s", gh.apiUrl, owner, repo, branch)
+	req, err = http.NewRequest(http.MethodPatch, reqUrl, bytes.NewReader(reqBody))
+	if err != nil {
+		return BranchDTO{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	res, err = gh.client.Do(req)
+	if err != nil {
+		return BranchDTO{}, err
+	}
+
+	if res.StatusCode != 200 {
+		resBody, err := io.ReadAll(res.Body)
+		if err != nil {
+			return BranchDTO{}, err
+		}
+		return BranchDTO{}, errors.New(fmt.Sprintf("Updating branch '%s' for repo: '%s/%s', failed with body: %s", branch, owner, repo, resBody))
+	}
+
+	updated := BranchDTO{Name: branch}
+	updated.Commit.Sha = commit.Sha
+
+	return updated, nil
+}
@@ -1,24 +1,74 @@
 package github
 
 import (
-	"fmt"
+	"go-track/internal/github/githubtest"
 	"testing"
-
-	"github.com/joho/godotenv"
 )
 
+func newTestService(t *testing.T) (GithubService, *githubtest.Server) {
+	t.Helper()
+
+	srv := githubtest.NewServer()
+	t.Cleanup(srv.Close)
+
+	gh := NewWithConfig(Config{
+		AppId:        "1",
+		ClientId:     githubtest.ClientId,
+		ClientSecret: githubtest.ClientSecret,
+		PrivateKey:   srv.PrivateKey,
+		ApiUrl:       srv.URL,
+		WebUrl:       srv.URL,
+		Client:       srv.Client(),
+	})
+
+	return gh, srv
+}
+
 func TestGetBranches(t *testing.T) {
-	godotenv.Load("../../.env")
+	gh, srv := newTestService(t)
+	srv.AddInstallation("TobiasTheDanish", githubtest.AccountUser)
+	repo := srv.AddRepo("TobiasTheDanish", "go-track")
+	repo.AddBranch("feature/1-board", "main")
 
-	gh, err := New()
+	branches, err := gh.GetBranches("TobiasTheDanish", "go-track")
 	if err != nil {
-		t.Fatalf("Could not create new GithubService: %e\n", err)
+		t.Fatalf("Could not get branches: %s\n", err)
 	}
 
-	branches, err := gh.GetBranches("TobiasTheDanish", "go-track")
+	if len(branches) != 2 || branches[0].Name != "feature/1-board" || branches[1].Name != "main" {
+		t.Fatalf("Expected branches 'feature/1-board' and 'main', got %v\n", branches)
+	}
+	if branches[1].Commit.Sha != repo.Branch("main") {
+		t.Fatalf("Expected main at '%s', got '%s'\n", repo.Branch("main"), branches[1].Commit.Sha)
+	}
+}
+
+func TestCreateAndDeleteBranch(t *testing.T) {
+	gh, srv := newTestService(t)
+	srv.AddInstallation("TobiasTheDanish", githubtest.AccountUser)
+	repo := srv.AddRepo("TobiasTheDanish", "go-track")
+
+	_, err := gh.CreateBranch("TobiasTheDanish", "go-track", "feature/2-login", repo.Branch("main"))
+	if err != nil {
+		t.Fatalf("Could not create branch: %s\n", err)
+	}
+
+	branch, err := gh.CreateEmptyCommit("TobiasTheDanish", "go-track", "feature/2-login", "Start work")
 	if err != nil {
-		t.Fatalf("Could not create new GithubService: %e\n", err)
+		t.Fatalf("Could not create empty commit: %s\n", err)
+	}
+	if branch.Commit.Sha == repo.Branch("main") || branch.Commit.Sha != repo.Branch("feature/2-login") {
+		t.Fatalf("Expected the branch to move to the new commit, got '%s'\n", branch.Commit.Sha)
+	}
+
+	if _, err := gh.CreateBranch("TobiasTheDanish", "go-track", "feature/2-login", repo.Branch("main")); err == nil {
+		t.Fatalf("Expected creating an existing branch to fail\n")
 	}
 
-	fmt.Printf("branches %v\n", branches)
+	if err := gh.DeleteBranch("TobiasTheDanish", "go-track", "feature/2-login"); err != nil {
+		t.Fatalf("Could not delete branch: %s\n", err)
+	}
+	if repo.Branch("feature/2-login") != "" {
+		t.Fatalf("Expected branch to be deleted\n")
+	}
 }
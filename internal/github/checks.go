@@ -0,0 +1,110 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type CommitStatusDTO struct {
+	State       string `json:"state"`
+	Context     string `json:"context"`
+	Description string `json:"description"`
+	TargetUrl   string `json:"target_url"`
+}
+
+type CombinedStatusDTO struct {
+	State    string            `json:"state"`
+	Sha      string            `json:"sha"`
+	Statuses []CommitStatusDTO `json:"statuses"`
+}
+
+func (gh *githubService) GetCombinedStatus(owner, repo, ref string) (CombinedStatusDTO, error) {
+	token, err := gh.repoToken(owner, repo)
+	if err != nil {
+		return CombinedStatusDTO{}, err
+	}
+
+	reqUrl := fmt.Sprintf("%s/repos/%s/%s/commits/%s/status", gh.apiUrl, owner, repo, ref)
+	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return CombinedStatusDTO{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	res, err := gh.client.Do(req)
+	if err != nil {
+		return CombinedStatusDTO{}, err
+	}
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return CombinedStatusDTO{}, err
+	}
+
+	if res.StatusCode != 200 {
+		return CombinedStatusDTO{}, errors.New(fmt.Sprintf("Getting combined status for ref '%s' in repo: '%s/%s', failed with body: %s", ref, owner, repo, resBody))
+	}
+
+	var status CombinedStatusDTO
+	err = json.Unmarshal(resBody, &status)
+	if err != nil {
+		return CombinedStatusDTO{}, err
+	}
+
+	return status, nil
+}
+
+type CheckRunDTO struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+	HtmlUrl    string `json:"html_url"`
+}
+
+type CheckRunsDTO struct {
+	TotalCount int           `json:"total_count"`
+	CheckRuns  []CheckRunDTO `json:"check_runs"`
+}
+
+func (gh *githubService) GetCheckRuns(owner, repo, ref string) (CheckRunsDTO, error) {
+	token, err := gh.repoToken(owner, repo)
+	if err != nil {
+		return CheckRunsDTO{}, err
+	}
+
+	reqUrl := fmt.Sprintf("%s/repos/%s/%s/commits/%s/check-runs", gh.apiUrl, owner, repo, ref)
+	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return CheckRunsDTO{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	res, err := gh.client.Do(req)
+	if err != nil {
+		return CheckRunsDTO{}, err
+	}
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return CheckRunsDTO{}, err
+	}
+
+	if res.StatusCode != 200 {
+		return CheckRunsDTO{}, errors.New(fmt.Sprintf("Getting check runs for ref '%s' in repo: '%s/%s', failed with body: %s", ref, owner, repo, resBody))
+	}
+
+	var runs CheckRunsDTO
+	err = json.Unmarshal(resBody, &runs)
+	if err != nil {
+		return CheckRunsDTO{}, err
+	}
+
+	return runs, nil
+}
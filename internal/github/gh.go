@@ -11,31 +11,87 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type GithubService interface {
-	GetAuthUrl() string
-	AuthUserByCode(code string) (model.AuthUserRes, error)
+	GetAuthUrl(state string, codeChallenge string) string
+	AuthUserByCode(code string, codeVerifier string) (model.AuthUserRes, error)
 	GetAuthorizedUser(auth model.AuthUserRes) (model.AuthorizedUser, error)
 	CreateIssue(owner string, repo string, title string) (CreateIssueRes, error)
 
+	GetRepository(owner string, repo string) (RepositoryDTO, error)
+	GetCollaboratorPermission(owner string, repo string, username string) (CollaboratorPermissionDTO, error)
+
+	GetOrgMembership(org string) (MembershipDTO, error)
+	GetTeamMembership(org string, team string, username string) (MembershipDTO, error)
+
+	GetApp() (AppDTO, error)
+	ListAppInstallations() ([]InstallationDTO, error)
+	GetUserInstallations(userToken string) ([]InstallationDTO, error)
+	ListUserInstallationRepositories(userToken string, installationId int) ([]RepositoryDTO, error)
+	CheckPrivateKey() error
+	CheckInstallationToken(installation Installation) error
+
 	GetBranches(owner string, repo string) ([]BranchDTO, error)
 	GetBranch(owner string, repo string, name string) (BranchDTO, error)
 	CreateBranch(owner string, repo string, name string, sha string) (BranchDTO, error)
 	DeleteBranch(owner string, repo string, name string) error
+	CreateEmptyCommit(owner string, repo string, branch string, message string) (BranchDTO, error)
+
+	CreatePullRequest(owner string, repo string, head string, base string, issueNumber int, draft bool) (PullRequestDTO, error)
+	MergePullRequest(owner string, repo string, title string, message string, method string, sha string, pullNumber int) (PullRequestDTO, error)
+	GetPullRequest(owner string, repo string, pullNumber int) (PullRequestDTO, error)
+	ListPullRequests(owner string, repo string, state string) ([]PullRequestDTO, error)
+	GetPullRequestReviews(owner string, repo string, pullNumber int) ([]ReviewDTO, error)
+	MarkPullRequestReadyForReview(owner string, repo string, nodeId string) error
 
-	CreatePullRequest(owner string, repo string, head string, base string, issueNumber int) (PullRequestDTO, error)
-	MergePullRequest(owner string, repo string, title string, message string, pullNumber int) (PullRequestDTO, error)
+	GetCombinedStatus(owner string, repo string, ref string) (CombinedStatusDTO, error)
+	GetCheckRuns(owner string, repo string, ref string) (CheckRunsDTO, error)
+
+	RefreshUserToken(refreshToken string) (model.AuthUserRes, error)
+	WithUserToken(userToken string) GithubService
 }
 
 type githubService struct {
 	appId        string
 	clientId     string
 	clientSecret string
+	redirectUrl  string
 	privateKey   *rsa.PrivateKey
+	apiUrl       string
+	webUrl       string
+	client       *http.Client
+	userToken    string
+
+	installations *installationCache
+}
+
+const (
+	defaultApiUrl = "https://api.github.com"
+	defaultWebUrl = "https://github.com"
+)
+
+// ErrBadRefreshToken is returned when GitHub rejects a refresh token because
+// it has been used, revoked or has expired.
+var ErrBadRefreshToken = errors.New("The refresh token is incorrect or expired")
+
+// Config configures a GithubService. ApiUrl, WebUrl and Client default to the
+// public GitHub endpoints and http.DefaultClient, and are meant to be changed
+// for tests running against a fake GitHub server. RedirectUrl is the OAuth
+// callback, and must match a callback URL of the GitHub App when set.
+type Config struct {
+	AppId        string
+	ClientId     string
+	ClientSecret string
+	RedirectUrl  string
+	PrivateKey   *rsa.PrivateKey
+	ApiUrl       string
+	WebUrl       string
+	Client       *http.Client
 }
 
 func New() (GithubService, error) {
@@ -46,24 +102,91 @@ func New() (GithubService, error) {
 		return nil, err
 	}
 
+	return NewWithConfig(Config{
+		AppId:        os.Getenv("GITHUB_APP_ID"),
+		ClientId:     os.Getenv("GITHUB_CLIENT_ID"),
+		ClientSecret: os.Getenv("GITHUB_CLIENT_SECRET"),
+		RedirectUrl:  os.Getenv("GITHUB_REDIRECT_URL"),
+		PrivateKey:   private,
+		Client:       &http.Client{Transport: NewMetricsTransport(nil)},
+	}), nil
+}
+
+func NewWithConfig(cfg Config) GithubService {
+	if cfg.ApiUrl == "" {
+		cfg.ApiUrl = defaultApiUrl
+	}
+	if cfg.WebUrl == "" {
+		cfg.WebUrl = defaultWebUrl
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+
 	return &githubService{
-		appId:        os.Getenv("GITHUB_APP_ID"),
-		clientId:     os.Getenv("GITHUB_CLIENT_ID"),
-		clientSecret: os.Getenv("GITHUB_CLIENT_SECRET"),
-		privateKey:   private,
-	}, nil
+		appId:        cfg.AppId,
+		clientId:     cfg.ClientId,
+		clientSecret: cfg.ClientSecret,
+		redirectUrl:  cfg.RedirectUrl,
+		privateKey:   cfg.PrivateKey,
+		apiUrl:       strings.TrimSuffix(cfg.ApiUrl, "/"),
+		webUrl:       strings.TrimSuffix(cfg.WebUrl, "/"),
+		client:       cfg.Client,
+
+		installations: &installationCache{byRepo: make(map[string]Installation)},
+	}
 }
 
-func (s *githubService) GetAuthUrl() string {
-	return fmt.Sprintf("https://github.com/login/oauth/authorize?client_id=%s", s.clientId)
+// GetAuthUrl returns the url of the GitHub sign in page. state is returned
+// unchanged to the callback, and codeChallenge is the S256 PKCE challenge.
+func (s *githubService) GetAuthUrl(state string, codeChallenge string) string {
+	query := url.Values{}
+	query.Set("client_id", s.clientId)
+	query.Set("state", state)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+	if s.redirectUrl != "" {
+		query.Set("redirect_uri", s.redirectUrl)
+	}
+
+	return fmt.Sprintf("%s/login/oauth/authorize?%s", s.webUrl, query.Encode())
 }
 
-func (s *githubService) AuthUserByCode(code string) (model.AuthUserRes, error) {
-	reqUrl := "https://github.com/login/oauth/access_token?"
-	reqVals, err := url.ParseQuery(fmt.Sprintf("code=%s&client_id=%s&client_secret=%s", code, s.clientId, s.clientSecret))
+func (s *githubService) AuthUserByCode(code string, codeVerifier string) (model.AuthUserRes, error) {
+	reqVals := url.Values{}
+	reqVals.Set("code", code)
+	reqVals.Set("code_verifier", codeVerifier)
+	if s.redirectUrl != "" {
+		reqVals.Set("redirect_uri", s.redirectUrl)
+	}
+
+	authRes, err := s.requestUserToken(reqVals)
+	if err != nil {
+		return model.AuthUserRes{}, errors.Join(errors.New(fmt.Sprintf("Authentication with code '%s' failed", code)), err)
+	}
+
+	return authRes, nil
+}
+
+// RefreshUserToken exchanges a refresh token for a new user token. The refresh
+// token can only be used once, a new one is returned with the new user token.
+func (s *githubService) RefreshUserToken(refreshToken string) (model.AuthUserRes, error) {
+	reqVals := url.Values{}
+	reqVals.Set("grant_type", "refresh_token")
+	reqVals.Set("refresh_token", refreshToken)
+
+	authRes, err := s.requestUserToken(reqVals)
 	if err != nil {
-		return model.AuthUserRes{}, errors.Join(errors.New("Parsing request query parameters failed"), err)
+		return model.AuthUserRes{}, errors.Join(errors.New("Refreshing user token failed"), err)
 	}
+
+	return authRes, nil
+}
+
+func (s *githubService) requestUserToken(reqVals url.Values) (model.AuthUserRes, error) {
+	reqUrl := s.webUrl + "/login/oauth/access_token"
+	reqVals.Set("client_id", s.clientId)
+	reqVals.Set("client_secret", s.clientSecret)
 	reqBody := bytes.NewReader([]byte(reqVals.Encode()))
 
 	req, err := http.NewRequest(http.MethodPost, reqUrl, reqBody)
@@ -73,7 +196,7 @@ func (s *githubService) AuthUserByCode(code string) (model.AuthUserRes, error) {
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("Accept", "application/json")
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := s.client.Do(req)
 	if err != nil {
 		return model.AuthUserRes{}, errors.Join(errors.New("Getting from oauth url failed."), err)
 	}
@@ -84,7 +207,7 @@ func (s *githubService) AuthUserByCode(code string) (model.AuthUserRes, error) {
 	}
 
 	if res.StatusCode != 200 {
-		return model.AuthUserRes{}, errors.New(fmt.Sprintf("Authentication with code '%s', failed with body: %s", code, resBody))
+		return model.AuthUserRes{}, errors.New(fmt.Sprintf("Request failed with body: %s", resBody))
 	}
 
 	var authRes model.AuthUserRes
@@ -93,11 +216,19 @@ func (s *githubService) AuthUserByCode(code string) (model.AuthUserRes, error) {
 		return model.AuthUserRes{}, errors.Join(errors.New("Unmarshalling response body failed."), err)
 	}
 
+	// GitHub reports a bad code or refresh token with status 200 and an error in the body.
+	if authRes.Error == "bad_refresh_token" {
+		return model.AuthUserRes{}, ErrBadRefreshToken
+	}
+	if authRes.Error != "" {
+		return model.AuthUserRes{}, errors.New(fmt.Sprintf("Request failed with error: %s", authRes.Error))
+	}
+
 	return authRes, nil
 }
 
 func (s *githubService) GetAuthorizedUser(auth model.AuthUserRes) (model.AuthorizedUser, error) {
-	reqUrl := "https://api.github.com/user"
+	reqUrl := s.apiUrl + "/user"
 	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
 	if err != nil {
 		return model.AuthorizedUser{}, err
@@ -106,7 +237,7 @@ func (s *githubService) GetAuthorizedUser(auth model.AuthUserRes) (model.Authori
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", auth.AccessToken))
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := s.client.Do(req)
 	if err != nil {
 		return model.AuthorizedUser{}, err
 	}
@@ -116,6 +247,10 @@ func (s *githubService) GetAuthorizedUser(auth model.AuthUserRes) (model.Authori
 		return model.AuthorizedUser{}, err
 	}
 
+	if res.StatusCode != 200 {
+		return model.AuthorizedUser{}, errors.New(fmt.Sprintf("Getting authorized user failed with body: %s", resBody))
+	}
+
 	var user model.AuthorizedUser
 	if err := json.Unmarshal(resBody, &user); err != nil {
 		return model.AuthorizedUser{}, err
@@ -138,3 +273,56 @@ func (s *githubService) getJWT() (string, error) {
 
 	return signer.SignedString(s.privateKey)
 }
+
+// WithUserToken returns a service making repository requests with the token of
+// a signed in user, so issues and pull requests are authored by the user and
+// the permissions of the user apply. App and installation requests are unchanged.
+func (s *githubService) WithUserToken(userToken string) GithubService {
+	return &githubService{
+		appId:         s.appId,
+		clientId:      s.clientId,
+		clientSecret:  s.clientSecret,
+		redirectUrl:   s.redirectUrl,
+		privateKey:    s.privateKey,
+		apiUrl:        s.apiUrl,
+		webUrl:        s.webUrl,
+		client:        s.client,
+		userToken:     userToken,
+		installations: s.installations,
+	}
+}
+
+// repoToken returns the token used for requests to the repository. That is the
+// user token when acting as a user, and otherwise an installation token.
+func (s *githubService) repoToken(owner, repo string) (string, error) {
+	if s.userToken != "" {
+		return s.userToken, nil
+	}
+
+	installation, err := s.GetInstallation(owner, repo)
+	if err != nil {
+		return "", err
+	}
+
+	access, err := s.GetInstallationAccessToken(installation)
+	if err != nil {
+		return "", err
+	}
+
+	return access.Token, nil
+}
+
+// nextPageUrl returns the url of the next page from the Link header of a list
+// response, or an empty string on the last page.
+func nextPageUrl(header http.Header) string {
+	for _, link := range strings.Split(header.Get("Link"), ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+		if !ok || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+
+		return strings.Trim(strings.TrimSpace(target), "<>")
+	}
+
+	return ""
+}
@@ -0,0 +1,57 @@
+package github
+
+import (
+	"go-track/internal/auth"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGetAuthUrl(t *testing.T) {
+	gh := NewWithConfig(Config{
+		ClientId:    "client",
+		RedirectUrl: "https://go-track.example/auth/callback",
+	})
+
+	authUrl, err := url.Parse(gh.GetAuthUrl("nonce", "challenge"))
+	if err != nil {
+		t.Fatalf("Could not parse auth url: %s\n", err)
+	}
+
+	query := authUrl.Query()
+	if query.Get("state") != "nonce" || query.Get("code_challenge") != "challenge" || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("Expected state and code challenge in auth url, got %s\n", authUrl)
+	}
+	if query.Get("redirect_uri") != "https://go-track.example/auth/callback" {
+		t.Fatalf("Expected redirect_uri in auth url, got %s\n", authUrl)
+	}
+}
+
+func TestAuthUserByCodeWithVerifier(t *testing.T) {
+	gh, srv := newTestService(t)
+	srv.AddUser("octocat")
+
+	state, err := auth.NewOAuthState("/")
+	if err != nil {
+		t.Fatalf("Could not create state: %s\n", err)
+	}
+
+	code := srv.NewCode("octocat", state.CodeChallenge())
+	if _, err := gh.AuthUserByCode(code, "wrong-verifier"); err == nil || !strings.Contains(err.Error(), "bad_verification_code") {
+		t.Fatalf("Expected a wrong verifier to be rejected, got: %v\n", err)
+	}
+
+	code = srv.NewCode("octocat", state.CodeChallenge())
+	authRes, err := gh.AuthUserByCode(code, state.Verifier)
+	if err != nil {
+		t.Fatalf("Could not authenticate: %s\n", err)
+	}
+
+	user, err := gh.GetAuthorizedUser(authRes)
+	if err != nil {
+		t.Fatalf("Could not get authorized user: %s\n", err)
+	}
+	if user.Username != "octocat" {
+		t.Fatalf("Expected user 'octocat', got '%s'\n", user.Username)
+	}
+}
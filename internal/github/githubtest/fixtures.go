@@ -0,0 +1,454 @@
+package githubtest
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	AccountUser         = "User"
+	AccountOrganization = "Organization"
+)
+
+// Installation is an installation of the app on a user or organization. When
+// Repos is empty the installation has access to every repository of the account.
+type Installation struct {
+	Id          int
+	Account     string
+	AccountType string
+	Repos       []string
+}
+
+// User is a GitHub user that can sign in through the OAuth flow.
+type User struct {
+	Id    int
+	Login string
+	Name  string
+	Orgs  []string
+	// Teams are org/team-slug pairs.
+	Teams []string
+}
+
+type oauthCode struct {
+	login    string
+	verifier string
+}
+
+type commit struct {
+	sha     string
+	tree    string
+	message string
+	parents []string
+}
+
+// Repo is a repository on the fake server. Fixture methods on a repo are safe
+// to call while the server is serving requests.
+type Repo struct {
+	srv *Server
+
+	Id            int
+	Owner         string
+	Name          string
+	DefaultBranch string
+	Private       bool
+
+	nextNumber    int
+	collaborators map[string]string
+	branches      map[string]string
+	required      map[string][]string
+	commits       map[string]commit
+	issues        map[int]*Issue
+	pulls         map[int]*Pull
+	statuses      map[string][]Status
+	checkRuns     map[string][]CheckRun
+}
+
+// Author is the login of the user who created the issue or pull request, or
+// the bot of the app when it was created with an installation token.
+type Issue struct {
+	Id     int
+	Number int
+	Title  string
+	Author string
+}
+
+type Pull struct {
+	Id        int
+	NodeId    string
+	Number    int
+	Title     string
+	Author    string
+	Head      string
+	HeadSha   string
+	Base      string
+	State     string
+	Draft     bool
+	Merged    bool
+	Mergeable *bool
+	Reviews   []Review
+}
+
+type Review struct {
+	Id    int
+	Login string
+	State string
+}
+
+type Status struct {
+	Context     string
+	State       string
+	Description string
+	TargetUrl   string
+}
+
+type CheckRun struct {
+	Name       string
+	Status     string
+	Conclusion string
+	HtmlUrl    string
+}
+
+func repoKey(owner, name string) string {
+	return strings.ToLower(owner + "/" + name)
+}
+
+func fakeSha(parts ...any) string {
+	sum := sha1.Sum([]byte(fmt.Sprint(parts...)))
+	return hex.EncodeToString(sum[:])
+}
+
+// AddInstallation installs the app on an account. accountType is either
+// AccountUser or AccountOrganization.
+func (s *Server) AddInstallation(account string, accountType string, repos ...string) *Installation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	installation := &Installation{
+		Id:          s.id(),
+		Account:     account,
+		AccountType: accountType,
+		Repos:       repos,
+	}
+	s.installations = append(s.installations, installation)
+
+	return installation
+}
+
+// AddRepo creates a repository with a single commit on its default branch.
+func (s *Server) AddRepo(owner, name string) *Repo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo := &Repo{
+		srv:           s,
+		Id:            s.id(),
+		Owner:         owner,
+		Name:          name,
+		DefaultBranch: "main",
+		nextNumber:    1,
+		collaborators: make(map[string]string),
+		branches:      make(map[string]string),
+		required:      make(map[string][]string),
+		commits:       make(map[string]commit),
+		issues:        make(map[int]*Issue),
+		pulls:         make(map[int]*Pull),
+		statuses:      make(map[string][]Status),
+		checkRuns:     make(map[string][]CheckRun),
+	}
+	root := repo.commit("Initial commit")
+	repo.branches[repo.DefaultBranch] = root.sha
+	s.repos[repoKey(owner, name)] = repo
+
+	return repo
+}
+
+// AddUser creates a user that is a member of orgs. The returned code can be
+// exchanged for a user token at /login/oauth/access_token.
+func (s *Server) AddUser(login string, orgs ...string) (*User, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := &User{
+		Id:    s.id(),
+		Login: login,
+		Name:  login,
+		Orgs:  orgs,
+	}
+	s.users[strings.ToLower(login)] = user
+
+	code := fakeSha("code", user.Id)[:20]
+	s.codes[code] = oauthCode{login: user.Login}
+
+	return user, code
+}
+
+// NewCode returns a new OAuth code for an existing user. If verifier is not
+// empty, the code must be exchanged with a matching PKCE code_verifier.
+func (s *Server) NewCode(login string, verifier string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	code := fakeSha("code", login, s.id())[:20]
+	s.codes[code] = oauthCode{login: login, verifier: verifier}
+
+	return code
+}
+
+// RenameUser changes the login of the user, like renaming a GitHub account.
+// Tokens issued before the rename belong to the new login.
+func (s *Server) RenameUser(login, newLogin string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.users[strings.ToLower(login)]
+	delete(s.users, strings.ToLower(login))
+	user.Login = newLogin
+	s.users[strings.ToLower(newLogin)] = user
+
+	for _, tokens := range []map[string]string{s.userTokens, s.refreshTokens} {
+		for token, l := range tokens {
+			if strings.EqualFold(l, login) {
+				tokens[token] = newLogin
+			}
+		}
+	}
+}
+
+// AddTeamMember adds the user to a team of the organization, and to the
+// organization itself.
+func (s *Server) AddTeamMember(org, team, login string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.users[strings.ToLower(login)]
+	user.Teams = append(user.Teams, org+"/"+team)
+	if !slices.ContainsFunc(user.Orgs, func(o string) bool { return strings.EqualFold(o, org) }) {
+		user.Orgs = append(user.Orgs, org)
+	}
+}
+
+// Repo returns the repository owner/name, or nil if it does not exist.
+func (s *Server) Repo(owner, name string) *Repo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.repos[repoKey(owner, name)]
+}
+
+// permission must be called with s.mu held. Owners are admins, collaborators
+// have the permission they were given and members of the owning organization
+// can read.
+func (s *Server) permission(login string, repo *Repo) string {
+	user := s.users[strings.ToLower(login)]
+	if user == nil {
+		return "none"
+	}
+
+	if strings.EqualFold(user.Login, repo.Owner) {
+		return "admin"
+	}
+	if permission, ok := repo.collaborators[strings.ToLower(user.Login)]; ok {
+		return permission
+	}
+	if slices.ContainsFunc(user.Orgs, func(org string) bool { return strings.EqualFold(org, repo.Owner) }) {
+		return "read"
+	}
+
+	return "none"
+}
+
+// installationFor must be called with s.mu held.
+func (s *Server) installationFor(repo *Repo) *Installation {
+	for _, installation := range s.installations {
+		if !strings.EqualFold(installation.Account, repo.Owner) {
+			continue
+		}
+		if len(installation.Repos) == 0 {
+			return installation
+		}
+		for _, name := range installation.Repos {
+			if strings.EqualFold(name, repo.Name) {
+				return installation
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *Repo) FullName() string {
+	return r.Owner + "/" + r.Name
+}
+
+// commit must be called with the server lock held.
+func (r *Repo) commit(message string, parents ...string) commit {
+	c := commit{
+		sha:     fakeSha(r.FullName(), len(r.commits), message, parents),
+		tree:    fakeSha("tree", r.FullName(), len(r.commits)),
+		message: message,
+		parents: parents,
+	}
+	r.commits[c.sha] = c
+
+	return c
+}
+
+// AddBranch creates a branch pointing at the head of from.
+func (r *Repo) AddBranch(name string, from string) string {
+	r.srv.mu.Lock()
+	defer r.srv.mu.Unlock()
+
+	sha := r.branches[from]
+	r.branches[name] = sha
+
+	return sha
+}
+
+// Push adds a commit to the branch and returns the new head.
+func (r *Repo) Push(branch string, message string) string {
+	r.srv.mu.Lock()
+	defer r.srv.mu.Unlock()
+
+	c := r.commit(message, r.branches[branch])
+	r.branches[branch] = c.sha
+
+	return c.sha
+}
+
+// Branch returns the head of the branch, or an empty string if it does not exist.
+func (r *Repo) Branch(name string) string {
+	r.srv.mu.Lock()
+	defer r.srv.mu.Unlock()
+
+	return r.branches[name]
+}
+
+// AddCollaborator gives the user a permission on the repository, which is one
+// of admin, write or read.
+func (r *Repo) AddCollaborator(login string, permission string) {
+	r.srv.mu.Lock()
+	defer r.srv.mu.Unlock()
+
+	r.collaborators[strings.ToLower(login)] = permission
+}
+
+// Protect requires the status check contexts to pass before merging into branch.
+func (r *Repo) Protect(branch string, contexts ...string) {
+	r.srv.mu.Lock()
+	defer r.srv.mu.Unlock()
+
+	r.required[branch] = contexts
+}
+
+func (r *Repo) AddStatus(sha string, status Status) {
+	r.srv.mu.Lock()
+	defer r.srv.mu.Unlock()
+
+	r.statuses[sha] = append(r.statuses[sha], status)
+}
+
+func (r *Repo) AddCheckRun(sha string, run CheckRun) {
+	r.srv.mu.Lock()
+	defer r.srv.mu.Unlock()
+
+	r.checkRuns[sha] = append(r.checkRuns[sha], run)
+}
+
+// AddPull opens a pull request from head into base.
+func (r *Repo) AddPull(title, head, base string) *Pull {
+	r.srv.mu.Lock()
+	defer r.srv.mu.Unlock()
+
+	return r.openPull(r.nextNumberLocked(), title, head, base, false, "")
+}
+
+// Pull returns a copy of the pull request, or nil if it does not exist.
+func (r *Repo) Pull(number int) *Pull {
+	r.srv.mu.Lock()
+	defer r.srv.mu.Unlock()
+
+	pull, ok := r.pulls[number]
+	if !ok {
+		return nil
+	}
+	cp := *pull
+	cp.Reviews = append([]Review(nil), pull.Reviews...)
+
+	return &cp
+}
+
+// Issue returns a copy of the issue, or nil if it does not exist.
+func (r *Repo) Issue(number int) *Issue {
+	r.srv.mu.Lock()
+	defer r.srv.mu.Unlock()
+
+	issue, ok := r.issues[number]
+	if !ok {
+		return nil
+	}
+	cp := *issue
+
+	return &cp
+}
+
+// AddReview submits a review on the pull request. state is one of APPROVED,
+// CHANGES_REQUESTED, COMMENTED or DISMISSED.
+func (r *Repo) AddReview(number int, login string, state string) {
+	r.srv.mu.Lock()
+	defer r.srv.mu.Unlock()
+
+	pull, ok := r.pulls[number]
+	if !ok {
+		panic(fmt.Sprintf("githubtest: pr #%d does not exist in %s", number, r.FullName()))
+	}
+	pull.Reviews = append(pull.Reviews, Review{
+		Id:    r.srv.id(),
+		Login: login,
+		State: state,
+	})
+}
+
+// SetMergeable sets whether GitHub considers the pull request free of conflicts.
+func (r *Repo) SetMergeable(number int, mergeable bool) {
+	r.srv.mu.Lock()
+	defer r.srv.mu.Unlock()
+
+	pull, ok := r.pulls[number]
+	if !ok {
+		panic(fmt.Sprintf("githubtest: pr #%d does not exist in %s", number, r.FullName()))
+	}
+	pull.Mergeable = &mergeable
+}
+
+// nextNumberLocked must be called with the server lock held. Issues and pull
+// requests share numbers, like on GitHub.
+func (r *Repo) nextNumberLocked() int {
+	number := r.nextNumber
+	r.nextNumber++
+
+	return number
+}
+
+// openPull must be called with the server lock held.
+func (r *Repo) openPull(number int, title, head, base string, draft bool, author string) *Pull {
+	mergeable := true
+	pull := &Pull{
+		Id:        r.srv.id(),
+		Number:    number,
+		Title:     title,
+		Author:    author,
+		Head:      head,
+		HeadSha:   r.branches[head],
+		Base:      base,
+		State:     "open",
+		Draft:     draft,
+		Mergeable: &mergeable,
+	}
+	pull.NodeId = fmt.Sprintf("PR_%d", pull.Id)
+	r.pulls[number] = pull
+
+	return pull
+}
@@ -0,0 +1,921 @@
+package githubtest
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (s *Server) handleOAuthAccessToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if r.Form.Get("client_id") != ClientId || r.Form.Get("client_secret") != ClientSecret {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"error":             "incorrect_client_credentials",
+			"error_description": "The client_id and/or client_secret passed are incorrect.",
+		})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var login string
+	if r.Form.Get("grant_type") == "refresh_token" {
+		l, ok := s.refreshTokens[r.Form.Get("refresh_token")]
+		if !ok {
+			writeJSON(w, http.StatusOK, map[string]string{
+				"error":             "bad_refresh_token",
+				"error_description": "The refresh token passed is incorrect or expired.",
+			})
+			return
+		}
+		delete(s.refreshTokens, r.Form.Get("refresh_token"))
+		login = l
+	} else {
+		code, ok := s.codes[r.Form.Get("code")]
+		if !ok {
+			writeJSON(w, http.StatusOK, map[string]string{
+				"error":             "bad_verification_code",
+				"error_description": "The code passed is incorrect or expired.",
+			})
+			return
+		}
+		if code.verifier != "" && !verifierMatches(r.Form.Get("code_verifier"), code.verifier) {
+			writeJSON(w, http.StatusOK, map[string]string{
+				"error":             "bad_verification_code",
+				"error_description": "The code_verifier does not match the code_challenge.",
+			})
+			return
+		}
+		delete(s.codes, r.Form.Get("code"))
+		login = code.login
+	}
+
+	token := "ghu_" + fakeSha("user-token", login, s.id())[:36]
+	refresh := "ghr_" + fakeSha("refresh-token", login, s.id())[:36]
+	s.userTokens[token] = login
+	s.refreshTokens[refresh] = login
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token":             token,
+		"expires_in":               28800,
+		"refresh_token":            refresh,
+		"refresh_token_expires_in": 15811200,
+		"scope":                    "",
+		"token_type":               "bearer",
+	})
+}
+
+// verifierMatches reports whether verifier is the code_verifier of the S256
+// code_challenge challenge. A plain challenge is accepted as well.
+func verifierMatches(verifier, challenge string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:]) == challenge || verifier == challenge
+}
+
+func (s *Server) userFor(r *http.Request) *User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.users[strings.ToLower(s.userTokens[bearerToken(r)])]
+}
+
+func (s *Server) handleGetUser(w http.ResponseWriter, r *http.Request) {
+	user := s.userFor(r)
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":                user.Id,
+		"login":             user.Login,
+		"name":              user.Name,
+		"avatar_url":        fmt.Sprintf("%s/avatars/%s", s.URL, user.Login),
+		"organizations_url": fmt.Sprintf("%s/users/%s/orgs", s.URL, user.Login),
+	})
+}
+
+func (s *Server) handleGetOrgMembership(w http.ResponseWriter, r *http.Request) {
+	user := s.userFor(r)
+	org := r.PathValue("org")
+
+	if !slices.ContainsFunc(user.Orgs, func(o string) bool { return strings.EqualFold(o, org) }) {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"state": "active",
+		"role":  "member",
+		"user":  map[string]any{"login": user.Login},
+	})
+}
+
+// handleGetTeamMembership only lets members of the organization see the
+// members of its teams, like GitHub does for secret teams.
+func (s *Server) handleGetTeamMembership(w http.ResponseWriter, r *http.Request) {
+	user := s.userFor(r)
+	org := r.PathValue("org")
+	if !slices.ContainsFunc(user.Orgs, func(o string) bool { return strings.EqualFold(o, org) }) {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	s.mu.Lock()
+	member := s.users[strings.ToLower(r.PathValue("username"))]
+	s.mu.Unlock()
+
+	team := org + "/" + r.PathValue("team")
+	if member == nil || !slices.ContainsFunc(member.Teams, func(t string) bool { return strings.EqualFold(t, team) }) {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"state": "active",
+		"role":  "member",
+	})
+}
+
+func (s *Server) handleGetUserInstallations(w http.ResponseWriter, r *http.Request) {
+	user := s.userFor(r)
+
+	s.mu.Lock()
+	installations := make([]any, 0)
+	for _, installation := range s.installations {
+		if strings.EqualFold(installation.Account, user.Login) || slices.ContainsFunc(user.Orgs, func(org string) bool {
+			return strings.EqualFold(org, installation.Account)
+		}) {
+			installations = append(installations, installationJSON(installation))
+		}
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"total_count":   len(installations),
+		"installations": paginate(w, r, installations),
+	})
+}
+
+func installationJSON(i *Installation) map[string]any {
+	return map[string]any{
+		"id": i.Id,
+		"account": map[string]any{
+			"login": i.Account,
+			"type":  i.AccountType,
+		},
+	}
+}
+
+func (s *Server) handleGetApp(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"slug":     AppSlug,
+		"name":     AppSlug,
+		"html_url": fmt.Sprintf("%s/apps/%s", s.URL, AppSlug),
+	})
+}
+
+func (s *Server) handleListInstallations(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	installations := make([]any, 0, len(s.installations))
+	for _, installation := range s.installations {
+		installations = append(installations, installationJSON(installation))
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, paginate(w, r, installations))
+}
+
+func (s *Server) handleCreateAccessToken(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := slices.ContainsFunc(s.installations, func(i *Installation) bool { return i.Id == id })
+	if !found {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	token := "ghs_" + fakeSha("installation-token", id, s.id())[:36]
+	s.tokens[token] = id
+
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"token":      token,
+		"expires_at": time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+	})
+}
+
+func (s *Server) handleGetAccountInstallation(accountType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		account := r.PathValue("account")
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		for _, installation := range s.installations {
+			if installation.AccountType == accountType && strings.EqualFold(installation.Account, account) {
+				writeJSON(w, http.StatusOK, installationJSON(installation))
+				return
+			}
+		}
+
+		writeMessage(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+func (s *Server) handleGetRepoInstallation(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo := s.repos[repoKey(r.PathValue("owner"), r.PathValue("repo"))]
+	if repo != nil {
+		if installation := s.installationFor(repo); installation != nil {
+			writeJSON(w, http.StatusOK, installationJSON(installation))
+			return
+		}
+	}
+
+	writeMessage(w, http.StatusNotFound, "Not Found")
+}
+
+// handleListUserInstallationRepositories lists the repositories of the
+// installation the user can access.
+func (s *Server) handleListUserInstallationRepositories(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	login := s.userTokens[bearerToken(r)]
+	repos := make([]*Repo, 0)
+	for _, repo := range s.repos {
+		if installation := s.installationFor(repo); installation != nil && installation.Id == id && s.permission(login, repo) != "none" {
+			repos = append(repos, repo)
+		}
+	}
+	slices.SortFunc(repos, func(a, b *Repo) int { return strings.Compare(a.FullName(), b.FullName()) })
+
+	res := make([]any, 0, len(repos))
+	for _, repo := range paginate(w, r, repos) {
+		res = append(res, s.repoJSON(repo))
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"total_count":  len(repos),
+		"repositories": res,
+	})
+}
+
+// repo must be called with the server lock held. repoAuth has already checked
+// that the repository exists.
+func (s *Server) repo(r *http.Request) *Repo {
+	return s.repos[repoKey(r.PathValue("owner"), r.PathValue("repo"))]
+}
+
+func (s *Server) repoJSON(repo *Repo) map[string]any {
+	return map[string]any{
+		"id":             repo.Id,
+		"name":           repo.Name,
+		"full_name":      repo.FullName(),
+		"owner":          map[string]any{"login": repo.Owner},
+		"default_branch": repo.DefaultBranch,
+		"html_url":       fmt.Sprintf("%s/%s", s.URL, repo.FullName()),
+		"private":        repo.Private,
+	}
+}
+
+func (s *Server) handleGetRepo(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, s.repoJSON(s.repo(r)))
+}
+
+func (s *Server) handleCollaboratorPermission(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	username := r.PathValue("username")
+	if s.users[strings.ToLower(username)] == nil {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	permission := s.permission(username, s.repo(r))
+	writeJSON(w, http.StatusOK, map[string]any{
+		"permission": permission,
+		"role_name":  permission,
+		"user":       map[string]any{"login": username},
+	})
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeMessage(w, http.StatusBadRequest, "Problems parsing JSON")
+		return false
+	}
+
+	return true
+}
+
+func (s *Server) handleCreateIssue(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Title string `json:"title"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+	if body.Title == "" {
+		writeMessage(w, http.StatusUnprocessableEntity, "Validation Failed")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo := s.repo(r)
+	issue := &Issue{
+		Id:     s.id(),
+		Number: repo.nextNumberLocked(),
+		Title:  body.Title,
+		Author: s.author(r),
+	}
+	repo.issues[issue.Number] = issue
+
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"id":       issue.Id,
+		"number":   issue.Number,
+		"title":    issue.Title,
+		"user":     map[string]any{"login": issue.Author},
+		"html_url": fmt.Sprintf("%s/%s/issues/%d", s.URL, repo.FullName(), issue.Number),
+	})
+}
+
+func branchJSON(repo *Repo, name string) map[string]any {
+	contexts, protected := repo.required[name]
+	if contexts == nil {
+		contexts = []string{}
+	}
+
+	return map[string]any{
+		"name":      name,
+		"commit":    map[string]any{"sha": repo.branches[name]},
+		"protected": protected,
+		"protection": map[string]any{
+			"required_status_checks": map[string]any{"contexts": contexts},
+		},
+	}
+}
+
+func (s *Server) handleListBranches(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo := s.repo(r)
+	names := make([]string, 0, len(repo.branches))
+	for name := range repo.branches {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	branches := make([]any, 0, len(names))
+	for _, name := range paginate(w, r, names) {
+		branches = append(branches, branchJSON(repo, name))
+	}
+
+	writeJSON(w, http.StatusOK, branches)
+}
+
+func (s *Server) handleGetBranch(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo := s.repo(r)
+	name := r.PathValue("branch")
+	if _, ok := repo.branches[name]; !ok {
+		writeMessage(w, http.StatusNotFound, "Branch not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, branchJSON(repo, name))
+}
+
+func (s *Server) refJSON(repo *Repo, name string) map[string]any {
+	return map[string]any{
+		"ref": "refs/heads/" + name,
+		"object": map[string]any{
+			"type": "commit",
+			"sha":  repo.branches[name],
+		},
+	}
+}
+
+func (s *Server) handleCreateRef(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Ref string `json:"ref"`
+		Sha string `json:"sha"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+
+	name, ok := strings.CutPrefix(body.Ref, "refs/heads/")
+	if !ok || name == "" {
+		writeMessage(w, http.StatusUnprocessableEntity, "Reference name is invalid")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo := s.repo(r)
+	if _, exists := repo.branches[name]; exists {
+		writeMessage(w, http.StatusUnprocessableEntity, "Reference already exists")
+		return
+	}
+	if _, exists := repo.commits[body.Sha]; !exists {
+		writeMessage(w, http.StatusUnprocessableEntity, "Object does not exist")
+		return
+	}
+	repo.branches[name] = body.Sha
+
+	writeJSON(w, http.StatusCreated, s.refJSON(repo, name))
+}
+
+func (s *Server) handleUpdateRef(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Sha string `json:"sha"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo := s.repo(r)
+	name := r.PathValue("branch")
+	if _, exists := repo.branches[name]; !exists {
+		writeMessage(w, http.StatusUnprocessableEntity, "Reference does not exist")
+		return
+	}
+	if _, exists := repo.commits[body.Sha]; !exists {
+		writeMessage(w, http.StatusUnprocessableEntity, "Object does not exist")
+		return
+	}
+	repo.branches[name] = body.Sha
+
+	writeJSON(w, http.StatusOK, s.refJSON(repo, name))
+}
+
+func (s *Server) handleDeleteRef(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo := s.repo(r)
+	name := r.PathValue("branch")
+	if _, exists := repo.branches[name]; !exists {
+		writeMessage(w, http.StatusUnprocessableEntity, "Reference does not exist")
+		return
+	}
+	delete(repo.branches, name)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func commitJSON(c commit) map[string]any {
+	parents := make([]any, 0, len(c.parents))
+	for _, parent := range c.parents {
+		parents = append(parents, map[string]any{"sha": parent})
+	}
+
+	return map[string]any{
+		"sha":     c.sha,
+		"message": c.message,
+		"tree":    map[string]any{"sha": c.tree},
+		"parents": parents,
+	}
+}
+
+func (s *Server) handleGetCommit(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.repo(r).commits[r.PathValue("sha")]
+	if !ok {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, commitJSON(c))
+}
+
+func (s *Server) handleCreateCommit(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Message string   `json:"message"`
+		Tree    string   `json:"tree"`
+		Parents []string `json:"parents"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo := s.repo(r)
+	for _, parent := range body.Parents {
+		if _, ok := repo.commits[parent]; !ok {
+			writeMessage(w, http.StatusUnprocessableEntity, "Object does not exist")
+			return
+		}
+	}
+
+	c := repo.commit(body.Message, body.Parents...)
+	if body.Tree != "" {
+		c.tree = body.Tree
+		repo.commits[c.sha] = c
+	}
+
+	writeJSON(w, http.StatusCreated, commitJSON(c))
+}
+
+// resolveRef must be called with the server lock held.
+func resolveRef(repo *Repo, ref string) string {
+	if sha, ok := repo.branches[ref]; ok {
+		return sha
+	}
+
+	return ref
+}
+
+func (s *Server) handleCombinedStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo := s.repo(r)
+	sha := resolveRef(repo, r.PathValue("ref"))
+
+	state := "success"
+	statuses := make([]any, 0)
+	for _, status := range repo.statuses[sha] {
+		switch {
+		case status.State == "failure" || status.State == "error":
+			state = "failure"
+		case status.State == "pending" && state != "failure":
+			state = "pending"
+		}
+		statuses = append(statuses, map[string]any{
+			"state":       status.State,
+			"context":     status.Context,
+			"description": status.Description,
+			"target_url":  status.TargetUrl,
+		})
+	}
+	if len(statuses) == 0 {
+		state = "pending"
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"state":       state,
+		"sha":         sha,
+		"total_count": len(statuses),
+		"statuses":    statuses,
+	})
+}
+
+func (s *Server) handleCheckRuns(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo := s.repo(r)
+	sha := resolveRef(repo, r.PathValue("ref"))
+
+	runs := make([]any, 0)
+	for _, run := range repo.checkRuns[sha] {
+		var conclusion any
+		if run.Conclusion != "" {
+			conclusion = run.Conclusion
+		}
+		runs = append(runs, map[string]any{
+			"name":       run.Name,
+			"status":     run.Status,
+			"conclusion": conclusion,
+			"html_url":   run.HtmlUrl,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"total_count": len(runs),
+		"check_runs":  runs,
+	})
+}
+
+// pullJSON must be called with the server lock held. The head of an open pull
+// request follows its branch, like on GitHub.
+func (s *Server) pullJSON(repo *Repo, pull *Pull) map[string]any {
+	if sha, ok := repo.branches[pull.Head]; ok && pull.State == "open" {
+		pull.HeadSha = sha
+	}
+
+	mergeableState := "clean"
+	if pull.Mergeable != nil && !*pull.Mergeable {
+		mergeableState = "dirty"
+	} else if pull.Draft {
+		mergeableState = "draft"
+	}
+
+	return map[string]any{
+		"id":              pull.Id,
+		"node_id":         pull.NodeId,
+		"number":          pull.Number,
+		"title":           pull.Title,
+		"user":            map[string]any{"login": pull.Author},
+		"html_url":        fmt.Sprintf("%s/%s/pull/%d", s.URL, repo.FullName(), pull.Number),
+		"state":           pull.State,
+		"draft":           pull.Draft,
+		"merged":          pull.Merged,
+		"mergeable":       pull.Mergeable,
+		"mergeable_state": mergeableState,
+		"head":            map[string]any{"ref": pull.Head, "sha": pull.HeadSha},
+		"base":            map[string]any{"ref": pull.Base},
+	}
+}
+
+// pull must be called with the server lock held.
+func (s *Server) pull(w http.ResponseWriter, r *http.Request) (*Repo, *Pull, bool) {
+	repo := s.repo(r)
+	number, err := strconv.Atoi(r.PathValue("number"))
+	if err != nil {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return nil, nil, false
+	}
+
+	pull, ok := repo.pulls[number]
+	if !ok {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return nil, nil, false
+	}
+
+	return repo, pull, true
+}
+
+func (s *Server) handleListPulls(w http.ResponseWriter, r *http.Request) {
+	state := r.URL.Query().Get("state")
+	if state == "" {
+		state = "open"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo := s.repo(r)
+	numbers := make([]int, 0, len(repo.pulls))
+	for number, pull := range repo.pulls {
+		if state == "all" || pull.State == state {
+			numbers = append(numbers, number)
+		}
+	}
+	slices.Sort(numbers)
+	slices.Reverse(numbers)
+
+	pulls := make([]any, 0, len(numbers))
+	for _, number := range numbers {
+		pulls = append(pulls, s.pullJSON(repo, repo.pulls[number]))
+	}
+
+	writeJSON(w, http.StatusOK, pulls)
+}
+
+func (s *Server) handleCreatePull(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Head  string `json:"head"`
+		Base  string `json:"base"`
+		Title string `json:"title"`
+		Issue int    `json:"issue"`
+		Draft bool   `json:"draft"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo := s.repo(r)
+	if _, ok := repo.branches[body.Head]; !ok {
+		writeMessage(w, http.StatusUnprocessableEntity, "Validation Failed: head does not exist")
+		return
+	}
+	if _, ok := repo.branches[body.Base]; !ok {
+		writeMessage(w, http.StatusUnprocessableEntity, "Validation Failed: base does not exist")
+		return
+	}
+	if repo.branches[body.Head] == repo.branches[body.Base] {
+		writeMessage(w, http.StatusUnprocessableEntity, fmt.Sprintf("Validation Failed: No commits between %s and %s", body.Base, body.Head))
+		return
+	}
+	for _, pull := range repo.pulls {
+		if pull.State == "open" && pull.Head == body.Head && pull.Base == body.Base {
+			writeMessage(w, http.StatusUnprocessableEntity, fmt.Sprintf("Validation Failed: A pull request already exists for %s:%s.", repo.Owner, body.Head))
+			return
+		}
+	}
+
+	var pull *Pull
+	if body.Issue != 0 {
+		issue, ok := repo.issues[body.Issue]
+		if !ok {
+			writeMessage(w, http.StatusUnprocessableEntity, "Validation Failed: issue does not exist")
+			return
+		}
+		delete(repo.issues, issue.Number)
+		pull = repo.openPull(issue.Number, issue.Title, body.Head, body.Base, body.Draft, s.author(r))
+	} else {
+		if body.Title == "" {
+			writeMessage(w, http.StatusUnprocessableEntity, "Validation Failed: title is missing")
+			return
+		}
+		pull = repo.openPull(repo.nextNumberLocked(), body.Title, body.Head, body.Base, body.Draft, s.author(r))
+	}
+
+	writeJSON(w, http.StatusCreated, s.pullJSON(repo, pull))
+}
+
+func (s *Server) handleGetPull(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, pull, ok := s.pull(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.pullJSON(repo, pull))
+}
+
+func (s *Server) handleListReviews(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, pull, ok := s.pull(w, r)
+	if !ok {
+		return
+	}
+
+	reviews := make([]any, 0, len(pull.Reviews))
+	for _, review := range pull.Reviews {
+		reviews = append(reviews, map[string]any{
+			"id":    review.Id,
+			"state": review.State,
+			"user":  map[string]any{"login": review.Login},
+		})
+	}
+
+	writeJSON(w, http.StatusOK, reviews)
+}
+
+func (s *Server) handleMergePull(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Title       string `json:"commit_title"`
+		Message     string `json:"commit_message"`
+		MergeMethod string `json:"merge_method"`
+		Sha         string `json:"sha"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, pull, ok := s.pull(w, r)
+	if !ok {
+		return
+	}
+	s.pullJSON(repo, pull)
+
+	if body.MergeMethod != "" && body.MergeMethod != "merge" && body.MergeMethod != "squash" && body.MergeMethod != "rebase" {
+		writeMessage(w, http.StatusUnprocessableEntity, "Validation Failed: merge_method is invalid")
+		return
+	}
+	if pull.State != "open" || pull.Draft || (pull.Mergeable != nil && !*pull.Mergeable) {
+		writeMessage(w, http.StatusMethodNotAllowed, "Pull Request is not mergeable")
+		return
+	}
+	if body.Sha != "" && body.Sha != pull.HeadSha {
+		writeMessage(w, http.StatusConflict, "Head branch was modified. Review and try the merge again.")
+		return
+	}
+
+	title := body.Title
+	if title == "" {
+		title = fmt.Sprintf("Merge pull request #%d", pull.Number)
+	}
+	merged := repo.commit(title, repo.branches[pull.Base], pull.HeadSha)
+	repo.branches[pull.Base] = merged.sha
+	pull.State = "closed"
+	pull.Merged = true
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"sha":     merged.sha,
+		"merged":  true,
+		"message": "Pull Request successfully merged",
+	})
+}
+
+func (s *Server) handleGraphql(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Query     string         `json:"query"`
+		Variables map[string]any `json:"variables"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, isUser := s.userTokens[bearerToken(r)]
+	_, isInstallation := s.tokens[bearerToken(r)]
+	if !isUser && !isInstallation {
+		writeMessage(w, http.StatusUnauthorized, "Bad credentials")
+		return
+	}
+
+	if !strings.Contains(body.Query, "markPullRequestReadyForReview") {
+		writeGraphqlError(w, "githubtest: only markPullRequestReadyForReview is supported")
+		return
+	}
+
+	id, _ := body.Variables["id"].(string)
+	for _, repo := range s.repos {
+		for _, pull := range repo.pulls {
+			if pull.NodeId != id {
+				continue
+			}
+			pull.Draft = false
+			writeJSON(w, http.StatusOK, map[string]any{
+				"data": map[string]any{
+					"markPullRequestReadyForReview": map[string]any{
+						"pullRequest": map[string]any{"isDraft": false},
+					},
+				},
+			})
+			return
+		}
+	}
+
+	writeGraphqlError(w, fmt.Sprintf("Could not resolve to a node with the global id of '%s'", id))
+}
+
+func writeGraphqlError(w http.ResponseWriter, message string) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"data":   nil,
+		"errors": []any{map[string]any{"message": message}},
+	})
+}
+
+// paginate returns the page of items asked for with the page and per_page
+// query parameters, and links to the next page like GitHub does. GitHub lists
+// 30 items per page by default.
+func paginate[T any](w http.ResponseWriter, r *http.Request, items []T) []T {
+	perPage, err := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if err != nil || perPage < 1 {
+		perPage = 30
+	}
+	perPage = min(perPage, 100)
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	start := min((page-1)*perPage, len(items))
+	end := min(start+perPage, len(items))
+	if end < len(items) {
+		next := *r.URL
+		query := next.Query()
+		query.Set("page", strconv.Itoa(page+1))
+		next.RawQuery = query.Encode()
+		w.Header().Set("Link", fmt.Sprintf("<http://%s%s>; rel=\"next\"", r.Host, next.RequestURI()))
+	}
+
+	return items[start:end]
+}
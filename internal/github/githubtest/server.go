@@ -0,0 +1,305 @@
+// Package githubtest provides an in-process fake of the parts of the GitHub
+// API go-track uses. The fake keeps state between requests, so a whole board
+// workflow of issues, branches and pull requests can be tested without network.
+package githubtest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	ClientId     = "githubtest-client-id"
+	ClientSecret = "githubtest-client-secret"
+	AppSlug      = "go-track"
+)
+
+// Request is a request received by the fake server.
+type Request struct {
+	Method string
+	Path   string
+	Query  string
+	Body   []byte
+}
+
+type failure struct {
+	method  string
+	path    string
+	status  int
+	message string
+	times   int
+}
+
+// Server is a fake GitHub API. It serves both the REST API and the OAuth
+// endpoints of github.com, so the same URL is used for both.
+type Server struct {
+	*httptest.Server
+	PrivateKey *rsa.PrivateKey
+
+	mu            sync.Mutex
+	nextId        int
+	installations []*Installation
+	repos         map[string]*Repo
+	users         map[string]*User
+	tokens        map[string]int
+	userTokens    map[string]string
+	refreshTokens map[string]string
+	codes         map[string]oauthCode
+	failures      []*failure
+	requests      []Request
+}
+
+// NewServer starts a fake GitHub server. It must be closed by the caller.
+func NewServer() *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("githubtest: generating private key failed: %s", err))
+	}
+
+	s := &Server{
+		PrivateKey:    key,
+		nextId:        1000,
+		repos:         make(map[string]*Repo),
+		users:         make(map[string]*User),
+		tokens:        make(map[string]int),
+		userTokens:    make(map[string]string),
+		refreshTokens: make(map[string]string),
+		codes:         make(map[string]oauthCode),
+	}
+	s.Server = httptest.NewServer(s.routes())
+
+	return s
+}
+
+// Fail makes the next times requests matching method and path fail with the
+// given status. A times of zero or less fails every matching request until
+// ClearFailures is called.
+func (s *Server) Fail(method, path string, status int, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = append(s.failures, &failure{
+		method:  method,
+		path:    path,
+		status:  status,
+		message: fmt.Sprintf("githubtest: scripted failure for %s %s", method, path),
+		times:   times,
+	})
+}
+
+func (s *Server) ClearFailures() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = nil
+}
+
+// Requests returns every request received by the server in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// RequestCount returns the number of requests received for method and path.
+func (s *Server) RequestCount(method, path string) int {
+	count := 0
+	for _, r := range s.Requests() {
+		if r.Method == method && r.Path == path {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (s *Server) id() int {
+	s.nextId++
+	return s.nextId
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /login/oauth/access_token", s.handleOAuthAccessToken)
+	mux.HandleFunc("GET /user", s.userAuth(s.handleGetUser))
+	mux.HandleFunc("GET /user/installations", s.userAuth(s.handleGetUserInstallations))
+	mux.HandleFunc("GET /user/installations/{id}/repositories", s.userAuth(s.handleListUserInstallationRepositories))
+	mux.HandleFunc("GET /user/memberships/orgs/{org}", s.userAuth(s.handleGetOrgMembership))
+	mux.HandleFunc("GET /orgs/{org}/teams/{team}/memberships/{username}", s.userAuth(s.handleGetTeamMembership))
+
+	mux.HandleFunc("GET /app", s.appAuth(s.handleGetApp))
+	mux.HandleFunc("GET /app/installations", s.appAuth(s.handleListInstallations))
+	mux.HandleFunc("POST /app/installations/{id}/access_tokens", s.appAuth(s.handleCreateAccessToken))
+	mux.HandleFunc("GET /users/{account}/installation", s.appAuth(s.handleGetAccountInstallation("User")))
+	mux.HandleFunc("GET /orgs/{account}/installation", s.appAuth(s.handleGetAccountInstallation("Organization")))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/installation", s.appAuth(s.handleGetRepoInstallation))
+
+	mux.HandleFunc("GET /repos/{owner}/{repo}", s.repoAuth(s.handleGetRepo))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/collaborators/{username}/permission", s.repoAuth(s.handleCollaboratorPermission))
+	mux.HandleFunc("POST /repos/{owner}/{repo}/issues", s.repoAuth(s.handleCreateIssue))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/branches", s.repoAuth(s.handleListBranches))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/branches/{branch...}", s.repoAuth(s.handleGetBranch))
+	mux.HandleFunc("POST /repos/{owner}/{repo}/git/refs", s.repoAuth(s.handleCreateRef))
+	mux.HandleFunc("PATCH /repos/{owner}/{repo}/git/refs/heads/{branch...}", s.repoAuth(s.handleUpdateRef))
+	mux.HandleFunc("DELETE /repos/{owner}/{repo}/git/refs/heads/{branch...}", s.repoAuth(s.handleDeleteRef))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/git/commits/{sha}", s.repoAuth(s.handleGetCommit))
+	mux.HandleFunc("POST /repos/{owner}/{repo}/git/commits", s.repoAuth(s.handleCreateCommit))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/commits/{ref}/status", s.repoAuth(s.handleCombinedStatus))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/commits/{ref}/check-runs", s.repoAuth(s.handleCheckRuns))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/pulls", s.repoAuth(s.handleListPulls))
+	mux.HandleFunc("POST /repos/{owner}/{repo}/pulls", s.repoAuth(s.handleCreatePull))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/pulls/{number}", s.repoAuth(s.handleGetPull))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/pulls/{number}/reviews", s.repoAuth(s.handleListReviews))
+	mux.HandleFunc("PUT /repos/{owner}/{repo}/pulls/{number}/merge", s.repoAuth(s.handleMergePull))
+	mux.HandleFunc("POST /graphql", s.handleGraphql)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.RawQuery,
+			Body:   body,
+		})
+		f := s.takeFailure(r.Method, r.URL.Path)
+		s.mu.Unlock()
+
+		if f != nil {
+			writeMessage(w, f.status, f.message)
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) takeFailure(method, path string) *failure {
+	for i, f := range s.failures {
+		if f.method != method || f.path != path {
+			continue
+		}
+		if f.times > 0 {
+			f.times--
+			if f.times == 0 {
+				s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			}
+		}
+		return f
+	}
+
+	return nil
+}
+
+func bearerToken(r *http.Request) string {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+
+	return token
+}
+
+// appAuth requires a JWT signed with the private key of the app.
+func (s *Server) appAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, err := jwt.Parse(bearerToken(r), func(t *jwt.Token) (interface{}, error) {
+			if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+				return nil, errors.New("unexpected signing method")
+			}
+			return &s.PrivateKey.PublicKey, nil
+		})
+		if err != nil {
+			writeMessage(w, http.StatusUnauthorized, "A JSON web token could not be decoded")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// userAuth requires a user-to-server token issued through the OAuth flow.
+func (s *Server) userAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		_, ok := s.userTokens[bearerToken(r)]
+		s.mu.Unlock()
+
+		if !ok {
+			writeMessage(w, http.StatusUnauthorized, "Bad credentials")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// repoAuth requires either a token of a user with access to the repository in
+// the path, or a token of an installation with access to it.
+func (s *Server) repoAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+
+		s.mu.Lock()
+		login, isUser := s.userTokens[token]
+		installationId, isInstallation := s.tokens[token]
+		repo := s.repos[repoKey(r.PathValue("owner"), r.PathValue("repo"))]
+		allowed := false
+		if isUser && repo != nil {
+			allowed = s.permission(login, repo) != "none"
+		}
+		if isInstallation && repo != nil {
+			installation := s.installationFor(repo)
+			allowed = installation != nil && installation.Id == installationId
+		}
+		s.mu.Unlock()
+
+		if !isUser && !isInstallation {
+			writeMessage(w, http.StatusUnauthorized, "Bad credentials")
+			return
+		}
+		if repo == nil || !allowed {
+			writeMessage(w, http.StatusNotFound, "Not Found")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// author must be called with s.mu held.
+func (s *Server) author(r *http.Request) string {
+	if login, ok := s.userTokens[bearerToken(r)]; ok {
+		return login
+	}
+
+	return AppSlug + "[bot]"
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeMessage(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{
+		"message":           message,
+		"documentation_url": "https://docs.github.com/rest",
+	})
+}
@@ -0,0 +1,354 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+var errInstallationNotFound = errors.New("The GitHub App is not installed")
+
+// installationCache is shared by a service and the services acting as a user
+// created from it with WithUserToken.
+type installationCache struct {
+	mu     sync.Mutex
+	byRepo map[string]Installation
+}
+
+// GetInstallation finds the installation of the GitHub App granting access to
+// the repository. An installation may only be granted some of the repositories
+// of the account, so when repo is given GitHub's answer for the repository is
+// final. Without a repo the installation on the organization is tried first,
+// falling back to the one on the user. Results are cached per owner and repo.
+func (s *githubService) GetInstallation(owner, repo string) (Installation, error) {
+	key := strings.ToLower(owner + "/" + repo)
+
+	s.installations.mu.Lock()
+	cached, ok := s.installations.byRepo[key]
+	s.installations.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	lookups := []func() (Installation, error){
+		func() (Installation, error) { return s.GetOrgInstallation(owner) },
+		func() (Installation, error) { return s.GetUserInstallation(owner) },
+	}
+	if repo != "" {
+		lookups = []func() (Installation, error){
+			func() (Installation, error) { return s.GetRepoInstallation(owner, repo) },
+		}
+	}
+
+	for _, lookup := range lookups {
+		installation, err := lookup()
+		if errors.Is(err, errInstallationNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		s.installations.mu.Lock()
+		s.installations.byRepo[key] = installation
+		s.installations.mu.Unlock()
+
+		return installation, nil
+	}
+
+	if repo == "" {
+		return nil, errors.New(fmt.Sprintf("The GitHub App is not installed on '%s'", owner))
+	}
+	return nil, errors.New(fmt.Sprintf("The GitHub App is not installed on '%s/%s'", owner, repo))
+}
+
+func (s *githubService) forgetInstallation(id int) {
+	s.installations.mu.Lock()
+	defer s.installations.mu.Unlock()
+
+	for key, installation := range s.installations.byRepo {
+		if installation.GetId() == id {
+			delete(s.installations.byRepo, key)
+		}
+	}
+}
+
+func (s *githubService) GetRepoInstallation(owner, repo string) (Installation, error) {
+	reqUrl := fmt.Sprintf("%s/repos/%s/%s/installation", s.apiUrl, owner, repo)
+	installation, err := s.findInstallation(reqUrl)
+	if err != nil {
+		return nil, errors.Join(errors.New(fmt.Sprintf("Getting installation for repo: '%s/%s' failed", owner, repo)), err)
+	}
+
+	return installation, nil
+}
+
+func (s *githubService) GetOrgInstallation(org string) (Installation, error) {
+	reqUrl := fmt.Sprintf("%s/orgs/%s/installation", s.apiUrl, org)
+	installation, err := s.findInstallation(reqUrl)
+	if err != nil {
+		return nil, errors.Join(errors.New(fmt.Sprintf("Getting installation for org: '%s' failed", org)), err)
+	}
+
+	return installation, nil
+}
+
+// findInstallation requests an installation endpoint authenticated as the app.
+// errInstallationNotFound is returned when GitHub responds with 404.
+func (s *githubService) findInstallation(reqUrl string) (Installation, error) {
+	token, err := s.getJWT()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == 404 {
+		return nil, errInstallationNotFound
+	}
+
+	if res.StatusCode != 200 {
+		return nil, errors.New(fmt.Sprintf("Request failed with body: %s", resBody))
+	}
+
+	var installation InstallationDTO
+	err = json.Unmarshal(resBody, &installation)
+	if err != nil {
+		return nil, err
+	}
+
+	return installation, nil
+}
+
+type InstallationDTO struct {
+	Id      int `json:"id"`
+	Account struct {
+		Login string `json:"login"`
+		Type  string `json:"type"`
+	} `json:"account"`
+}
+
+func (i InstallationDTO) GetId() int { return i.Id }
+
+type AppDTO struct {
+	Slug    string `json:"slug"`
+	Name    string `json:"name"`
+	HtmlUrl string `json:"html_url"`
+}
+
+// CheckPrivateKey makes sure the private key of the GitHub App is valid, and
+// that app JWTs can be signed with it.
+func (s *githubService) CheckPrivateKey() error {
+	if s.privateKey == nil {
+		return errors.New("No GitHub App private key is configured")
+	}
+
+	if err := s.privateKey.Validate(); err != nil {
+		return errors.Join(errors.New("The GitHub App private key is invalid"), err)
+	}
+
+	_, err := s.getJWT()
+	return err
+}
+
+// CheckInstallationToken mints an access token for the installation and
+// throws it away, to find out whether installation requests would work.
+func (s *githubService) CheckInstallationToken(i Installation) error {
+	_, err := s.GetInstallationAccessToken(i)
+	return err
+}
+
+// GetApp returns the GitHub App go-track is authenticated as.
+func (s *githubService) GetApp() (AppDTO, error) {
+	token, err := s.getJWT()
+	if err != nil {
+		return AppDTO{}, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, s.apiUrl+"/app", nil)
+	if err != nil {
+		return AppDTO{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return AppDTO{}, err
+	}
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return AppDTO{}, err
+	}
+
+	if res.StatusCode != 200 {
+		return AppDTO{}, errors.New(fmt.Sprintf("Getting app failed with body: %s", resBody))
+	}
+
+	var app AppDTO
+	err = json.Unmarshal(resBody, &app)
+	if err != nil {
+		return AppDTO{}, err
+	}
+
+	return app, nil
+}
+
+// ListAppInstallations returns every installation of the GitHub App.
+func (s *githubService) ListAppInstallations() ([]InstallationDTO, error) {
+	token, err := s.getJWT()
+	if err != nil {
+		return nil, err
+	}
+
+	installations := make([]InstallationDTO, 0)
+	reqUrl := s.apiUrl + "/app/installations?per_page=100"
+	for reqUrl != "" {
+		req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+		res, err := s.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		resBody, err := io.ReadAll(res.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.StatusCode != 200 {
+			return nil, errors.New(fmt.Sprintf("Listing app installations failed with body: %s", resBody))
+		}
+
+		page := make([]InstallationDTO, 0)
+		err = json.Unmarshal(resBody, &page)
+		if err != nil {
+			return nil, err
+		}
+		installations = append(installations, page...)
+
+		reqUrl = nextPageUrl(res.Header)
+	}
+
+	return installations, nil
+}
+
+type userInstallationsRes struct {
+	TotalCount    int               `json:"total_count"`
+	Installations []InstallationDTO `json:"installations"`
+}
+
+// GetUserInstallations returns the installations of the GitHub App the user
+// behind the user-to-server token has access to.
+func (s *githubService) GetUserInstallations(userToken string) ([]InstallationDTO, error) {
+	installations := make([]InstallationDTO, 0)
+	reqUrl := s.apiUrl + "/user/installations?per_page=100"
+	for reqUrl != "" {
+		req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", userToken))
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+		res, err := s.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		resBody, err := io.ReadAll(res.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.StatusCode != 200 {
+			return nil, errors.New(fmt.Sprintf("Listing user installations failed with body: %s", resBody))
+		}
+
+		var installationsRes userInstallationsRes
+		err = json.Unmarshal(resBody, &installationsRes)
+		if err != nil {
+			return nil, err
+		}
+		installations = append(installations, installationsRes.Installations...)
+
+		reqUrl = nextPageUrl(res.Header)
+	}
+
+	return installations, nil
+}
+
+type installationRepositoriesRes struct {
+	TotalCount   int             `json:"total_count"`
+	Repositories []RepositoryDTO `json:"repositories"`
+}
+
+// ListUserInstallationRepositories returns the repositories of the
+// installation the user behind the user-to-server token has access to.
+func (s *githubService) ListUserInstallationRepositories(userToken string, installationId int) ([]RepositoryDTO, error) {
+	repositories := make([]RepositoryDTO, 0)
+	reqUrl := fmt.Sprintf("%s/user/installations/%d/repositories?per_page=100", s.apiUrl, installationId)
+	for reqUrl != "" {
+		req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", userToken))
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+		res, err := s.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		resBody, err := io.ReadAll(res.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.StatusCode != 200 {
+			return nil, errors.New(fmt.Sprintf("Listing repositories for installation: %d, failed with body: %s", installationId, resBody))
+		}
+
+		var repositoriesRes installationRepositoriesRes
+		err = json.Unmarshal(resBody, &repositoriesRes)
+		if err != nil {
+			return nil, err
+		}
+		repositories = append(repositories, repositoriesRes.Repositories...)
+
+		reqUrl = nextPageUrl(res.Header)
+	}
+
+	return repositories, nil
+}
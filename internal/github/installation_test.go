@@ -0,0 +1,120 @@
+package github
+
+import (
+	"fmt"
+	"go-track/internal/github/githubtest"
+	"net/http"
+	"testing"
+)
+
+func TestGetInstallationFallsBackToOrg(t *testing.T) {
+	gh, srv := newTestService(t)
+	installation := srv.AddInstallation("go-track-org", githubtest.AccountOrganization, "board")
+	srv.AddRepo("go-track-org", "board")
+
+	got, err := gh.(*githubService).GetInstallation("go-track-org", "")
+	if err != nil {
+		t.Fatalf("Could not get installation: %s\n", err)
+	}
+	if got.GetId() != installation.Id {
+		t.Fatalf("Expected installation %d, got %d\n", installation.Id, got.GetId())
+	}
+	if srv.RequestCount(http.MethodGet, "/orgs/go-track-org/installation") != 1 {
+		t.Fatalf("Expected the org installation to be looked up\n")
+	}
+
+	if _, err := gh.(*githubService).GetInstallation("Go-Track-Org", ""); err != nil {
+		t.Fatalf("Could not get cached installation: %s\n", err)
+	}
+	if srv.RequestCount(http.MethodGet, "/orgs/Go-Track-Org/installation") != 0 {
+		t.Fatalf("Expected the installation to be cached per owner\n")
+	}
+}
+
+func TestGetInstallationUnselectedRepo(t *testing.T) {
+	gh, srv := newTestService(t)
+	installation := srv.AddInstallation("go-track-org", githubtest.AccountOrganization, "board")
+	srv.AddRepo("go-track-org", "board")
+	srv.AddRepo("go-track-org", "other")
+
+	got, err := gh.(*githubService).GetInstallation("go-track-org", "board")
+	if err != nil {
+		t.Fatalf("Could not get installation: %s\n", err)
+	}
+	if got.GetId() != installation.Id {
+		t.Fatalf("Expected installation %d, got %d\n", installation.Id, got.GetId())
+	}
+
+	if _, err := gh.(*githubService).GetInstallation("go-track-org", "other"); err == nil {
+		t.Fatalf("Expected a repository not selected for the installation to not be installed\n")
+	}
+	if srv.RequestCount(http.MethodGet, "/orgs/go-track-org/installation") != 0 {
+		t.Fatalf("Expected no fallback to the org installation\n")
+	}
+	if _, err := gh.GetRepository("go-track-org", "other"); err == nil {
+		t.Fatalf("Expected an error getting a repository not selected for the installation\n")
+	}
+}
+
+func TestGetInstallationNotInstalled(t *testing.T) {
+	gh, srv := newTestService(t)
+	srv.AddRepo("someone", "elsewhere")
+
+	if _, err := gh.GetRepository("someone", "elsewhere"); err == nil {
+		t.Fatalf("Expected an error when the app is not installed\n")
+	}
+}
+
+func TestInstallationForgottenWhenTokenMintingFails(t *testing.T) {
+	gh, srv := newTestService(t)
+	installation := srv.AddInstallation("TobiasTheDanish", githubtest.AccountUser)
+	srv.AddRepo("TobiasTheDanish", "go-track")
+
+	if _, err := gh.GetRepository("TobiasTheDanish", "go-track"); err != nil {
+		t.Fatalf("Could not get repository: %s\n", err)
+	}
+
+	srv.Fail(http.MethodPost, fmt.Sprintf("/app/installations/%d/access_tokens", installation.Id), http.StatusNotFound, 1)
+	if _, err := gh.GetRepository("TobiasTheDanish", "go-track"); err == nil {
+		t.Fatalf("Expected an error when minting a token fails\n")
+	}
+
+	if _, err := gh.GetRepository("TobiasTheDanish", "go-track"); err != nil {
+		t.Fatalf("Could not get repository after the failure: %s\n", err)
+	}
+	if srv.RequestCount(http.MethodGet, "/repos/TobiasTheDanish/go-track/installation") != 2 {
+		t.Fatalf("Expected the installation to be looked up again\n")
+	}
+}
+
+func TestListInstallationsFollowsPages(t *testing.T) {
+	gh, srv := newTestService(t)
+
+	orgs := make([]string, 0, 150)
+	for i := 0; i < 150; i++ {
+		org := fmt.Sprintf("org-%d", i)
+		srv.AddInstallation(org, githubtest.AccountOrganization)
+		orgs = append(orgs, org)
+	}
+	_, code := srv.AddUser("octocat", orgs...)
+
+	installations, err := gh.ListAppInstallations()
+	if err != nil {
+		t.Fatalf("Could not list app installations: %s\n", err)
+	}
+	if len(installations) != 150 {
+		t.Fatalf("Expected all 150 app installations, got %d\n", len(installations))
+	}
+
+	authUser, err := gh.AuthUserByCode(code, "")
+	if err != nil {
+		t.Fatalf("Could not authorize user: %s\n", err)
+	}
+	installations, err = gh.GetUserInstallations(authUser.AccessToken)
+	if err != nil {
+		t.Fatalf("Could not list user installations: %s\n", err)
+	}
+	if len(installations) != 150 {
+		t.Fatalf("Expected all 150 installations of the user, got %d\n", len(installations))
+	}
+}
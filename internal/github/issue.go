@@ -36,12 +36,7 @@ type CreateIssueRes struct {
 func (gh *githubService) CreateIssue(owner string, repo string, title string) (CreateIssueRes, error) {
 	issue := emptyIssue(title)
 
-	installation, err := gh.GetUserInstallation(owner)
-	if err != nil {
-		return CreateIssueRes{}, err
-	}
-
-	access, err := gh.GetInstallationAccessToken(installation)
+	token, err := gh.repoToken(owner, repo)
 	if err != nil {
 		return CreateIssueRes{}, err
 	}
@@ -52,16 +47,16 @@ func (gh *githubService) CreateIssue(owner string, repo string, title string) (C
 	}
 
 	bodyReader := bytes.NewReader(reqBody)
-	reqUrl := fmt.Sprintf("https://api.github.com/repos/%s/%s/issues", owner, repo)
+	reqUrl := fmt.Sprintf("%s/repos/%s/%s/issues", gh.apiUrl, owner, repo)
 	req, err := http.NewRequest(http.MethodPost, reqUrl, bodyReader)
 	if err != nil {
 		return CreateIssueRes{}, err
 	}
 	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", access.Token))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := gh.client.Do(req)
 	if err != nil {
 		return CreateIssueRes{}, err
 	}
@@ -88,48 +83,14 @@ type Installation interface {
 	GetId() int
 }
 
-type userInstallationRes struct {
-	Id int `json:"id"`
-}
-
-func (i userInstallationRes) GetId() int { return i.Id }
-
 func (s *githubService) GetUserInstallation(username string) (Installation, error) {
-	token, err := s.getJWT()
-	if err != nil {
-		return nil, err
-	}
-
-	reqUrl := fmt.Sprintf("https://api.github.com/users/%s/installation", username)
-	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
-	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
-
-	res, err := http.DefaultClient.Do(req)
+	reqUrl := fmt.Sprintf("%s/users/%s/installation", s.apiUrl, username)
+	installation, err := s.findInstallation(reqUrl)
 	if err != nil {
-		return nil, err
-	}
-
-	resBody, err := io.ReadAll(res.Body)
-	if err != nil {
-		return nil, err
+		return nil, errors.Join(errors.New(fmt.Sprintf("Getting installation for user: '%s' failed", username)), err)
 	}
 
-	if res.StatusCode != 200 {
-		return nil, errors.New(fmt.Sprintf("Getting installation for user: '%s', failed with body: %s", username, resBody))
-	}
-
-	var installationRes userInstallationRes
-	err = json.Unmarshal(resBody, &installationRes)
-	if err != nil {
-		return nil, err
-	}
-
-	return installationRes, nil
+	return installation, nil
 }
 
 type installationAccess struct {
@@ -143,7 +104,7 @@ func (s *githubService) GetInstallationAccessToken(i Installation) (*installatio
 		return nil, err
 	}
 
-	reqUrl := fmt.Sprintf("https://api.github.com/app/installations/%d/access_tokens", i.GetId())
+	reqUrl := fmt.Sprintf("%s/app/installations/%d/access_tokens", s.apiUrl, i.GetId())
 	req, err := http.NewRequest(http.MethodPost, reqUrl, nil)
 	if err != nil {
 		return nil, err
@@ -152,7 +113,7 @@ func (s *githubService) GetInstallationAccessToken(i Installation) (*installatio
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := s.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -162,6 +123,11 @@ func (s *githubService) GetInstallationAccessToken(i Installation) (*installatio
 		return nil, err
 	}
 
+	if res.StatusCode == 404 {
+		// the app has been uninstalled, so the cached installation is stale
+		s.forgetInstallation(i.GetId())
+	}
+
 	if res.StatusCode != 201 {
 		return nil, errors.New(fmt.Sprintf("Creating installation access token for id: %d, failed with body: %s", i.GetId(), resBody))
 	}
@@ -0,0 +1,117 @@
+package github
+
+import (
+	"go-track/internal/metrics"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metricsTransport counts and times the requests to GitHub, and keeps track of
+// the rate limit left.
+type metricsTransport struct {
+	next http.RoundTripper
+}
+
+// NewMetricsTransport records metrics of the requests made with next, or with
+// http.DefaultTransport when next is nil.
+func NewMetricsTransport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &metricsTransport{next: next}
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := endpointOf(req.URL.Path)
+	start := time.Now()
+
+	res, err := t.next.RoundTrip(req)
+
+	metrics.GithubRequestDuration.WithLabelValues(req.Method, endpoint).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.GithubRequests.WithLabelValues(req.Method, endpoint, "error").Inc()
+		return nil, err
+	}
+	metrics.GithubRequests.WithLabelValues(req.Method, endpoint, strconv.Itoa(res.StatusCode)).Inc()
+
+	if remaining, err := strconv.Atoi(res.Header.Get("X-RateLimit-Remaining")); err == nil {
+		resource := res.Header.Get("X-RateLimit-Resource")
+		if resource == "" {
+			resource = "core"
+		}
+		metrics.GithubRateLimitRemaining.WithLabelValues(resource).Set(float64(remaining))
+	}
+
+	return res, nil
+}
+
+// endpointOf replaces the owners, names and numbers in a GitHub API path with
+// placeholders, so every endpoint is one metric series.
+func endpointOf(path string) string {
+	path = strings.TrimPrefix(path, "/api/v3")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) == 0 || segments[0] == "" {
+		return "/"
+	}
+
+	for i, segment := range segments {
+		if _, err := strconv.Atoi(segment); err == nil {
+			segments[i] = "{number}"
+		}
+	}
+
+	switch segments[0] {
+	case "repos":
+		segments = repoEndpoint(segments)
+	case "users":
+		placeholder(segments, 1, "{account}")
+	case "orgs":
+		placeholder(segments, 1, "{account}")
+		if len(segments) > 3 && segments[2] == "teams" {
+			segments[3] = "{team}"
+			placeholder(segments, 5, "{username}")
+		}
+	case "user":
+		if len(segments) > 3 && segments[1] == "memberships" {
+			segments[3] = "{org}"
+		}
+	}
+
+	return "/" + strings.Join(segments, "/")
+}
+
+func repoEndpoint(segments []string) []string {
+	placeholder(segments, 1, "{owner}")
+	placeholder(segments, 2, "{repo}")
+	if len(segments) < 5 {
+		return segments
+	}
+
+	switch segments[3] {
+	case "branches":
+		// branch names may contain slashes
+		return append(segments[:4], "{branch}")
+	case "git":
+		if segments[4] == "refs" && len(segments) > 6 {
+			return append(segments[:6], "{branch}")
+		}
+		if segments[4] == "commits" {
+			placeholder(segments, 5, "{sha}")
+		}
+	case "commits":
+		segments[4] = "{ref}"
+	case "collaborators":
+		segments[4] = "{username}"
+	}
+
+	return segments
+}
+
+func placeholder(segments []string, i int, name string) {
+	if i < len(segments) {
+		segments[i] = name
+	}
+}
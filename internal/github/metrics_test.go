@@ -0,0 +1,27 @@
+package github
+
+import "testing"
+
+func TestEndpointOf(t *testing.T) {
+	tests := map[string]string{
+		"/app/installations/42/access_tokens":                      "/app/installations/{number}/access_tokens",
+		"/repos/TobiasTheDanish/go-track/pulls/7/merge":            "/repos/{owner}/{repo}/pulls/{number}/merge",
+		"/repos/TobiasTheDanish/go-track/branches/feature/1-login": "/repos/{owner}/{repo}/branches/{branch}",
+		"/repos/o/r/git/refs/heads/feature/1-login":                "/repos/{owner}/{repo}/git/refs/heads/{branch}",
+		"/repos/o/r/git/refs":                                      "/repos/{owner}/{repo}/git/refs",
+		"/repos/o/r/git/commits/abc123":                            "/repos/{owner}/{repo}/git/commits/{sha}",
+		"/repos/o/r/commits/main/check-runs":                       "/repos/{owner}/{repo}/commits/{ref}/check-runs",
+		"/repos/o/r/collaborators/someone/permission":              "/repos/{owner}/{repo}/collaborators/{username}/permission",
+		"/orgs/acme/teams/core/memberships/someone":                "/orgs/{account}/teams/{team}/memberships/{username}",
+		"/user/memberships/orgs/acme":                              "/user/memberships/orgs/{org}",
+		"/users/someone/installation":                              "/users/{account}/installation",
+		"/api/v3/repos/o/r":                                        "/repos/{owner}/{repo}",
+		"/graphql":                                                 "/graphql",
+	}
+
+	for path, expected := range tests {
+		if endpoint := endpointOf(path); endpoint != expected {
+			t.Errorf("Expected %s to become %s, got %s\n", path, expected, endpoint)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type MembershipDTO struct {
+	State string `json:"state"`
+	Role  string `json:"role"`
+}
+
+// Active reports whether the user is a member, and not just invited.
+func (m MembershipDTO) Active() bool {
+	return m.State == "active"
+}
+
+var errNoUserToken = errors.New("A user token is needed to check memberships")
+
+// GetOrgMembership returns the membership of the user behind the user token in
+// the organization. Users who are not members get an empty membership.
+func (gh *githubService) GetOrgMembership(org string) (MembershipDTO, error) {
+	reqUrl := fmt.Sprintf("%s/user/memberships/orgs/%s", gh.apiUrl, org)
+
+	return gh.getMembership(reqUrl, fmt.Sprintf("organization '%s'", org))
+}
+
+// GetTeamMembership returns the membership of the user in a team, as seen by the
+// user behind the user token. Users who are not members get an empty membership.
+func (gh *githubService) GetTeamMembership(org string, team string, username string) (MembershipDTO, error) {
+	reqUrl := fmt.Sprintf("%s/orgs/%s/teams/%s/memberships/%s", gh.apiUrl, org, team, username)
+
+	return gh.getMembership(reqUrl, fmt.Sprintf("team '%s/%s'", org, team))
+}
+
+// getMembership treats only a 404 as the user not being a member. A 403 is a
+// rate limit or an organization restricting OAuth apps, which says nothing
+// about the membership.
+func (gh *githubService) getMembership(reqUrl string, of string) (MembershipDTO, error) {
+	if gh.userToken == "" {
+		return MembershipDTO{}, errNoUserToken
+	}
+
+	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return MembershipDTO{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", gh.userToken))
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	res, err := gh.client.Do(req)
+	if err != nil {
+		return MembershipDTO{}, err
+	}
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return MembershipDTO{}, err
+	}
+
+	if res.StatusCode == 404 {
+		return MembershipDTO{}, nil
+	}
+
+	if res.StatusCode != 200 {
+		return MembershipDTO{}, errors.New(fmt.Sprintf("Getting membership of %s failed with body: %s", of, resBody))
+	}
+
+	var membership MembershipDTO
+	err = json.Unmarshal(resBody, &membership)
+	if err != nil {
+		return MembershipDTO{}, err
+	}
+
+	return membership, nil
+}
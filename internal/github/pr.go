@@ -8,32 +8,43 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 )
 
 type PullRequestDTO struct {
-	Id     int    `json:"id"`
-	Number int    `json:"number"`
-	Url    string `json:"html_url"`
+	Id             int    `json:"id"`
+	NodeId         string `json:"node_id"`
+	Number         int    `json:"number"`
+	Title          string `json:"title"`
+	Url            string `json:"html_url"`
+	State          string `json:"state"`
+	Draft          bool   `json:"draft"`
+	Mergeable      *bool  `json:"mergeable"`
+	MergeableState string `json:"mergeable_state"`
+	Head           struct {
+		Ref string `json:"ref"`
+		Sha string `json:"sha"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
 }
 
 type createPullRequestFromIssueDTO struct {
 	Head  string `json:"head"`
 	Base  string `json:"base"`
 	Issue int    `json:"issue"`
+	Draft bool   `json:"draft"`
 }
 type createPullRequestDTO struct {
 	Head  string `json:"head"`
 	Base  string `json:"base"`
 	Title string `json:"title"`
+	Draft bool   `json:"draft"`
 }
 
-func (gh *githubService) CreatePullRequest(owner string, repo string, head string, base string, issueNumber int) (PullRequestDTO, error) {
-	installation, err := gh.GetUserInstallation(owner)
-	if err != nil {
-		return PullRequestDTO{}, err
-	}
-
-	access, err := gh.GetInstallationAccessToken(installation)
+func (gh *githubService) CreatePullRequest(owner string, repo string, head string, base string, issueNumber int, draft bool) (PullRequestDTO, error) {
+	token, err := gh.repoToken(owner, repo)
 	if err != nil {
 		return PullRequestDTO{}, err
 	}
@@ -44,6 +55,7 @@ func (gh *githubService) CreatePullRequest(owner string, repo string, head strin
 			Head:  head,
 			Base:  base,
 			Title: head,
+			Draft: draft,
 		}
 		reqBody, err = json.Marshal(pr)
 	} else {
@@ -51,6 +63,7 @@ func (gh *githubService) CreatePullRequest(owner string, repo string, head strin
 			Head:  head,
 			Base:  base,
 			Issue: issueNumber,
+			Draft: draft,
 		}
 		reqBody, err = json.Marshal(pr)
 	}
@@ -60,16 +73,16 @@ func (gh *githubService) CreatePullRequest(owner string, repo string, head strin
 
 	bodyReader := bytes.NewReader(reqBody)
 
-	reqUrl := fmt.Sprintf("https://api.github.com/repos/%s/%s/pulls", owner, repo)
+	reqUrl := fmt.Sprintf("%s/repos/%s/%s/pulls", gh.apiUrl, owner, repo)
 	req, err := http.NewRequest(http.MethodPost, reqUrl, bodyReader)
 	if err != nil {
 		return PullRequestDTO{}, err
 	}
 	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", access.Token))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := gh.client.Do(req)
 	if err != nil {
 		return PullRequestDTO{}, err
 	}
@@ -96,16 +109,15 @@ type mergePullRequestDTO struct {
 	Title       string `json:"commit_title"`
 	Message     string `json:"commit_message"`
 	MergeMethod string `json:"merge_method"`
+	Sha         string `json:"sha,omitempty"`
 }
 
-func (gh *githubService) MergePullRequest(owner string, repo string, title string, message string, pullNumber int) (PullRequestDTO, error) {
+// MergePullRequest merges the pull request using the given merge method. When
+// sha is not empty, GitHub refuses the merge if the head of the pull request
+// no longer matches it.
+func (gh *githubService) MergePullRequest(owner string, repo string, title string, message string, method string, sha string, pullNumber int) (PullRequestDTO, error) {
 	log.Printf("Merging pull request #%d for repo: %s/%s\n", pullNumber, owner, repo)
-	installation, err := gh.GetUserInstallation(owner)
-	if err != nil {
-		return PullRequestDTO{}, err
-	}
-
-	access, err := gh.GetInstallationAccessToken(installation)
+	token, err := gh.repoToken(owner, repo)
 	if err != nil {
 		return PullRequestDTO{}, err
 	}
@@ -113,7 +125,8 @@ func (gh *githubService) MergePullRequest(owner string, repo string, title strin
 	pr := mergePullRequestDTO{
 		Title:       title,
 		Message:     message,
-		MergeMethod: "merge",
+		MergeMethod: method,
+		Sha:         sha,
 	}
 
 	reqBody, err := json.Marshal(pr)
@@ -123,16 +136,16 @@ func (gh *githubService) MergePullRequest(owner string, repo string, title strin
 
 	bodyReader := bytes.NewReader(reqBody)
 
-	reqUrl := fmt.Sprintf("https://api.github.com/repos/%s/%s/pulls/%d/merge", owner, repo, pullNumber)
+	reqUrl := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/merge", gh.apiUrl, owner, repo, pullNumber)
 	req, err := http.NewRequest(http.MethodPut, reqUrl, bodyReader)
 	if err != nil {
 		return PullRequestDTO{}, err
 	}
 	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", access.Token))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := gh.client.Do(req)
 	if err != nil {
 		return PullRequestDTO{}, err
 	}
@@ -142,9 +155,203 @@ func (gh *githubService) MergePullRequest(owner string, repo string, title strin
 		return PullRequestDTO{}, err
 	}
 
+	if res.StatusCode == 409 {
+		return PullRequestDTO{}, errors.New(fmt.Sprintf("The head of pr #%d in repo: '%s/%s' has changed since it was reviewed. Review and try the merge again.", pullNumber, owner, repo))
+	}
+
 	if res.StatusCode != 200 {
 		return PullRequestDTO{}, errors.New(fmt.Sprintf("Merging pr for repo: '%s/%s', failed with body: %s", owner, repo, resBody))
 	}
 
 	return PullRequestDTO{}, nil
 }
+
+func (gh *githubService) GetPullRequest(owner string, repo string, pullNumber int) (PullRequestDTO, error) {
+	token, err := gh.repoToken(owner, repo)
+	if err != nil {
+		return PullRequestDTO{}, err
+	}
+
+	reqUrl := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", gh.apiUrl, owner, repo, pullNumber)
+	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return PullRequestDTO{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	res, err := gh.client.Do(req)
+	if err != nil {
+		return PullRequestDTO{}, err
+	}
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return PullRequestDTO{}, err
+	}
+
+	if res.StatusCode != 200 {
+		return PullRequestDTO{}, errors.New(fmt.Sprintf("Getting pr #%d for repo: '%s/%s', failed with body: %s", pullNumber, owner, repo, resBody))
+	}
+
+	var dto PullRequestDTO
+	err = json.Unmarshal(resBody, &dto)
+	if err != nil {
+		return PullRequestDTO{}, err
+	}
+
+	return dto, nil
+}
+
+type ReviewDTO struct {
+	Id    int    `json:"id"`
+	State string `json:"state"`
+	User  struct {
+		Login string `json:"login"`
+	} `json:"user"`
+}
+
+func (gh *githubService) GetPullRequestReviews(owner string, repo string, pullNumber int) ([]ReviewDTO, error) {
+	token, err := gh.repoToken(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	reqUrl := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/reviews", gh.apiUrl, owner, repo, pullNumber)
+	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	res, err := gh.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != 200 {
+		return nil, errors.New(fmt.Sprintf("Getting reviews for pr #%d in repo: '%s/%s', failed with body: %s", pullNumber, owner, repo, resBody))
+	}
+
+	reviews := make([]ReviewDTO, 0)
+	err = json.Unmarshal(resBody, &reviews)
+	if err != nil {
+		return nil, err
+	}
+
+	return reviews, nil
+}
+
+type graphqlRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+type graphqlResponse struct {
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+const markReadyForReviewMutation = `mutation($id: ID!) {
+	markPullRequestReadyForReview(input: {pullRequestId: $id}) {
+		pullRequest { isDraft }
+	}
+}`
+
+// MarkPullRequestReadyForReview takes a pull request out of draft. The REST
+// API can not do this, so the GraphQL mutation is used with the node id of the pr.
+func (gh *githubService) MarkPullRequestReadyForReview(owner string, repo string, nodeId string) error {
+	token, err := gh.repoToken(owner, repo)
+	if err != nil {
+		return err
+	}
+
+	reqBody, err := json.Marshal(graphqlRequest{
+		Query:     markReadyForReviewMutation,
+		Variables: map[string]any{"id": nodeId},
+	})
+	if err != nil {
+		return err
+	}
+
+	bodyReader := bytes.NewReader(reqBody)
+	req, err := http.NewRequest(http.MethodPost, gh.apiUrl+"/graphql", bodyReader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	res, err := gh.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode != 200 {
+		return errors.New(fmt.Sprintf("Marking pr ready for review in repo: '%s/%s', failed with body: %s", owner, repo, resBody))
+	}
+
+	var gqlRes graphqlResponse
+	err = json.Unmarshal(resBody, &gqlRes)
+	if err != nil {
+		return err
+	}
+
+	if len(gqlRes.Errors) > 0 {
+		return errors.New(fmt.Sprintf("Marking pr ready for review in repo: '%s/%s', failed with error: %s", owner, repo, gqlRes.Errors[0].Message))
+	}
+
+	return nil
+}
+
+func (gh *githubService) ListPullRequests(owner string, repo string, state string) ([]PullRequestDTO, error) {
+	token, err := gh.repoToken(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	reqUrl := fmt.Sprintf("%s/repos/%s/%s/pulls?state=%s&per_page=100", gh.apiUrl, owner, repo, url.QueryEscape(state))
+	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	res, err := gh.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != 200 {
+		return nil, errors.New(fmt.Sprintf("Listing prs for repo: '%s/%s', failed with body: %s", owner, repo, resBody))
+	}
+
+	prs := make([]PullRequestDTO, 0)
+	err = json.Unmarshal(resBody, &prs)
+	if err != nil {
+		return nil, err
+	}
+
+	return prs, nil
+}
@@ -0,0 +1,76 @@
+package github
+
+import (
+	"go-track/internal/github/githubtest"
+	"strings"
+	"testing"
+)
+
+func TestMergePullRequestWithExpectedHead(t *testing.T) {
+	gh, srv := newTestService(t)
+	srv.AddInstallation("TobiasTheDanish", githubtest.AccountUser)
+	repo := srv.AddRepo("TobiasTheDanish", "go-track")
+	repo.AddBranch("feature/3-merge", "main")
+	repo.Push("feature/3-merge", "Add merge")
+	pull := repo.AddPull("Add merge", "feature/3-merge", "main")
+
+	seen, err := gh.GetPullRequest("TobiasTheDanish", "go-track", pull.Number)
+	if err != nil {
+		t.Fatalf("Could not get pr: %s\n", err)
+	}
+
+	repo.Push("feature/3-merge", "Sneaky change")
+
+	_, err = gh.MergePullRequest("TobiasTheDanish", "go-track", "Add merge", "", "squash", seen.Head.Sha, pull.Number)
+	if err == nil || !strings.Contains(err.Error(), "has changed since it was reviewed") {
+		t.Fatalf("Expected the merge to be refused, got: %v\n", err)
+	}
+
+	current, err := gh.GetPullRequest("TobiasTheDanish", "go-track", pull.Number)
+	if err != nil {
+		t.Fatalf("Could not get pr: %s\n", err)
+	}
+
+	_, err = gh.MergePullRequest("TobiasTheDanish", "go-track", "Add merge", "", "squash", current.Head.Sha, pull.Number)
+	if err != nil {
+		t.Fatalf("Could not merge pr: %s\n", err)
+	}
+	if merged := repo.Pull(pull.Number); !merged.Merged || merged.State != "closed" {
+		t.Fatalf("Expected the pr to be merged, got %+v\n", merged)
+	}
+}
+
+func TestDraftPullRequestReadyForReview(t *testing.T) {
+	gh, srv := newTestService(t)
+	srv.AddInstallation("TobiasTheDanish", githubtest.AccountUser)
+	repo := srv.AddRepo("TobiasTheDanish", "go-track")
+
+	issue, err := gh.CreateIssue("TobiasTheDanish", "go-track", "Drafts")
+	if err != nil {
+		t.Fatalf("Could not create issue: %s\n", err)
+	}
+
+	repo.AddBranch("feature/4-drafts", "main")
+	if _, err := gh.CreateEmptyCommit("TobiasTheDanish", "go-track", "feature/4-drafts", "Start work"); err != nil {
+		t.Fatalf("Could not create empty commit: %s\n", err)
+	}
+
+	pr, err := gh.CreatePullRequest("TobiasTheDanish", "go-track", "feature/4-drafts", "main", issue.Number, true)
+	if err != nil {
+		t.Fatalf("Could not create pr: %s\n", err)
+	}
+	if !pr.Draft || pr.Number != issue.Number || pr.Title != "Drafts" {
+		t.Fatalf("Expected a draft pr for issue #%d, got %+v\n", issue.Number, pr)
+	}
+
+	if err := gh.MarkPullRequestReadyForReview("TobiasTheDanish", "go-track", pr.NodeId); err != nil {
+		t.Fatalf("Could not mark pr ready for review: %s\n", err)
+	}
+	if repo.Pull(pr.Number).Draft {
+		t.Fatalf("Expected the pr to no longer be a draft\n")
+	}
+
+	if err := gh.MarkPullRequestReadyForReview("TobiasTheDanish", "go-track", "PR_unknown"); err == nil {
+		t.Fatalf("Expected an error for an unknown node id\n")
+	}
+}
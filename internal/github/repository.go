@@ -0,0 +1,109 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type RepositoryDTO struct {
+	Id       int    `json:"id"`
+	Name     string `json:"name"`
+	FullName string `json:"full_name"`
+	Owner    struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	DefaultBranch string `json:"default_branch"`
+	HtmlUrl       string `json:"html_url"`
+	Private       bool   `json:"private"`
+}
+
+func (gh *githubService) GetRepository(owner string, repo string) (RepositoryDTO, error) {
+	token, err := gh.repoToken(owner, repo)
+	if err != nil {
+		return RepositoryDTO{}, err
+	}
+
+	reqUrl := fmt.Sprintf("%s/repos/%s/%s", gh.apiUrl, owner, repo)
+	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return RepositoryDTO{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	res, err := gh.client.Do(req)
+	if err != nil {
+		return RepositoryDTO{}, err
+	}
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return RepositoryDTO{}, err
+	}
+
+	if res.StatusCode != 200 {
+		return RepositoryDTO{}, errors.New(fmt.Sprintf("Getting repo: '%s/%s', failed with body: %s", owner, repo, resBody))
+	}
+
+	var repository RepositoryDTO
+	err = json.Unmarshal(resBody, &repository)
+	if err != nil {
+		return RepositoryDTO{}, err
+	}
+
+	return repository, nil
+}
+
+type CollaboratorPermissionDTO struct {
+	Permission string `json:"permission"`
+	RoleName   string `json:"role_name"`
+}
+
+// GetCollaboratorPermission returns the permission of the user on the
+// repository, which is one of admin, write, read or none. Users who are not
+// collaborators get the permission "none".
+func (gh *githubService) GetCollaboratorPermission(owner string, repo string, username string) (CollaboratorPermissionDTO, error) {
+	token, err := gh.repoToken(owner, repo)
+	if err != nil {
+		return CollaboratorPermissionDTO{}, err
+	}
+
+	reqUrl := fmt.Sprintf("%s/repos/%s/%s/collaborators/%s/permission", gh.apiUrl, owner, repo, username)
+	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return CollaboratorPermissionDTO{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	res, err := gh.client.Do(req)
+	if err != nil {
+		return CollaboratorPermissionDTO{}, err
+	}
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return CollaboratorPermissionDTO{}, err
+	}
+
+	if res.StatusCode == 404 {
+		return CollaboratorPermissionDTO{Permission: "none"}, nil
+	}
+
+	if res.StatusCode != 200 {
+		return CollaboratorPermissionDTO{}, errors.New(fmt.Sprintf("Getting permission of '%s' on repo: '%s/%s', failed with body: %s", username, owner, repo, resBody))
+	}
+
+	var permission CollaboratorPermissionDTO
+	err = json.Unmarshal(resBody, &permission)
+	if err != nil {
+		return CollaboratorPermissionDTO{}, err
+	}
+
+	return permission, nil
+}
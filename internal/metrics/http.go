@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// unmatchedRoute labels requests that matched no route, so scanning for paths
+// does not create a series per path.
+const unmatchedRoute = "unmatched"
+
+// Middleware counts and times the requests by their route pattern.
+func Middleware(skip ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			route := c.Path()
+			for _, s := range skip {
+				if route == s {
+					return next(c)
+				}
+			}
+
+			start := time.Now()
+			err := next(c)
+
+			status := c.Response().Status
+			if err != nil {
+				// the error handler writes the response after the middlewares
+				status = http.StatusInternalServerError
+				var httpErr *echo.HTTPError
+				if errors.As(err, &httpErr) {
+					status = httpErr.Code
+				}
+			}
+			// requests rejected before routing have no route pattern
+			if route == "" || status == http.StatusNotFound && !isRoute(c, route) {
+				route = unmatchedRoute
+			}
+
+			method := c.Request().Method
+			HTTPRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+			if !strings.HasPrefix(c.Response().Header().Get(echo.HeaderContentType), "text/event-stream") {
+				HTTPRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+			}
+
+			return err
+		}
+	}
+}
+
+// isRoute reports whether the path is a registered route, and not a catch all
+// echo adds for not found requests.
+func isRoute(c echo.Context, path string) bool {
+	for _, route := range c.Echo().Routes() {
+		if route.Path == path && route.Method != echo.RouteNotFound {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type AuthUserRes struct {
 	AccessToken           string `json:"access_token"`
 	ExpiresIn             int    `json:"expires_in"`
@@ -7,14 +9,96 @@ type AuthUserRes struct {
 	RefreshTokenExpiresIn int    `json:"refresh_token_expires_in"`
 	Scope                 string `json:"scope"`
 	TokenType             string `json:"token_type"`
+	Error                 string `json:"error"`
+}
+
+// UserToken is the GitHub token of a signed in user. A zero expiry means the
+// token does not expire. The tokens are encrypted before they are saved.
+type UserToken struct {
+	UserID           int
+	Username         string
+	AccessToken      string
+	ExpiresAt        time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+func (t UserToken) Expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && !now.Before(t.ExpiresAt)
+}
+
+func (t UserToken) Refreshable(now time.Time) bool {
+	return t.RefreshToken != "" && (t.RefreshExpiresAt.IsZero() || now.Before(t.RefreshExpiresAt))
 }
 
 type AuthorizedUser struct {
-	Username string `json:"login"`
-	OrgUrl   string `json:"organizations_url"`
+	Id        int64  `json:"id"`
+	Username  string `json:"login"`
+	Name      string `json:"name"`
+	AvatarUrl string `json:"avatar_url"`
+	OrgUrl    string `json:"organizations_url"`
 }
 
 type Branch struct {
 	Name string
 	Sha  string
 }
+
+type Repository struct {
+	Owner         string
+	Name          string
+	DefaultBranch string
+	Private       bool
+	Url           string
+}
+
+func (r Repository) FullName() string {
+	return r.Owner + "/" + r.Name
+}
+
+type PullRequest struct {
+	Id         int
+	Number     int
+	Title      string
+	HeadBranch string
+	Url        string
+}
+
+const (
+	CheckStatePending = "pending"
+	CheckStateSuccess = "success"
+	CheckStateFailure = "failure"
+)
+
+type Check struct {
+	Name  string
+	State string
+	Url   string
+}
+
+type CheckStatus struct {
+	Sha    string
+	State  string
+	Checks []Check
+}
+
+func (s CheckStatus) Failing() []Check {
+	failing := make([]Check, 0)
+	for _, c := range s.Checks {
+		if c.State == CheckStateFailure {
+			failing = append(failing, c)
+		}
+	}
+
+	return failing
+}
+
+type MergeReadiness struct {
+	HeadSha        string
+	Checks         CheckStatus
+	BlockedReasons []string
+}
+
+func (r MergeReadiness) Blocked() bool {
+	return len(r.BlockedReasons) > 0
+}
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+const (
+	HealthOK      = "ok"
+	HealthFailing = "failing"
+)
+
+// DependencyHealth is the result of checking one dependency of the app.
+type DependencyHealth struct {
+	Status     string `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// Readiness tells whether the app can serve requests, with the health of every
+// dependency by name.
+type Readiness struct {
+	Status       string                      `json:"status"`
+	CheckedAt    time.Time                   `json:"checkedAt"`
+	Dependencies map[string]DependencyHealth `json:"dependencies"`
+}
+
+func (r Readiness) Ready() bool {
+	return r.Status == HealthOK
+}
@@ -1,11 +1,135 @@
 package model
 
+import "strings"
+
 type Project struct {
 	Id      int
 	Name    string
 	Columns []Column
 }
 
+const (
+	MergeMethodMerge  = "merge"
+	MergeMethodSquash = "squash"
+	MergeMethodRebase = "rebase"
+)
+
+var MergeMethods = []string{MergeMethodMerge, MergeMethodSquash, MergeMethodRebase}
+
+func IsMergeMethod(method string) bool {
+	for _, m := range MergeMethods {
+		if m == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+const DefaultBranchTemplate = "{type}/{issue_number}-{slug}"
+
+type ProjectSettings struct {
+	ProjectID         int
+	Owner             string
+	Repo              string
+	MergeMethod       string
+	DraftPullRequests bool
+	BranchTemplate    string
+	// DefaultBranch overrides the default branch of the repository when set.
+	DefaultBranch string
+	// RolesFromRepository gives users a role from their permission on the
+	// GitHub repository, in addition to the members of the project.
+	RolesFromRepository bool
+}
+
+// HasRepository reports whether a GitHub repository has been picked for the project.
+func (s ProjectSettings) HasRepository() bool {
+	return s.Owner != "" && s.Repo != ""
+}
+
+func DefaultProjectSettings(projectID int) ProjectSettings {
+	return ProjectSettings{
+		ProjectID:      projectID,
+		MergeMethod:    MergeMethodMerge,
+		BranchTemplate: DefaultBranchTemplate,
+	}
+}
+
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// Roles are ordered from least to most permissions. Every role has the
+// permissions of the roles before it.
+var Roles = []string{RoleViewer, RoleEditor, RoleAdmin}
+
+func roleRank(role string) int {
+	for i, r := range Roles {
+		if r == role {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+func IsRole(role string) bool {
+	return roleRank(role) > 0
+}
+
+// RoleAtLeast reports whether role has the permissions of min.
+func RoleAtLeast(role, min string) bool {
+	return IsRole(role) && roleRank(role) >= roleRank(min)
+}
+
+// HighestRole returns the role with the most permissions, or an empty string
+// when none of the roles are valid.
+func HighestRole(roles ...string) string {
+	highest := ""
+	for _, role := range roles {
+		if roleRank(role) > roleRank(highest) {
+			highest = role
+		}
+	}
+
+	return highest
+}
+
+type Member struct {
+	ProjectID int
+	Username  string
+	Role      string
+	// UserID is 0 until the member signs in for the first time.
+	UserID int
+}
+
+// Steps of the workflow that run when an item is moved into a column.
+const (
+	StepNone   = ""
+	StepIssue  = "issue"
+	StepBranch = "branch"
+	StepReview = "review"
+	StepMerge  = "merge"
+)
+
+// columnSteps maps the lowercase names of the columns to their step.
+var columnSteps = map[string]string{
+	"todo":                   StepIssue,
+	"in progress":            StepBranch,
+	"ready for pull request": StepReview,
+	"in review":              StepReview,
+	"review":                 StepReview,
+	"done":                   StepMerge,
+}
+
+// WorkflowStep returns the step for items moved into the column, or StepNone
+// for columns like the backlog.
+func WorkflowStep(columnName string) string {
+	return columnSteps[strings.ToLower(strings.TrimSpace(columnName))]
+}
+
 type Column struct {
 	Id        int
 	Name      string
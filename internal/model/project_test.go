@@ -0,0 +1,22 @@
+package model
+
+import "testing"
+
+func TestWorkflowStep(t *testing.T) {
+	tests := map[string]string{
+		"Backlog":                StepNone,
+		"Todo":                   StepIssue,
+		"In Progress":            StepBranch,
+		"Ready for pull request": StepReview,
+		"In Review":              StepReview,
+		" review ":               StepReview,
+		"Done":                   StepMerge,
+		"Blocked":                StepNone,
+	}
+
+	for name, expected := range tests {
+		if step := WorkflowStep(name); step != expected {
+			t.Errorf("Expected column '%s' to have step '%s', got '%s'\n", name, expected, step)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Session is a signed in browser. The session cookie only carries the id, so
+// a session can be revoked before its cookie expires.
+type Session struct {
+	Id         string
+	UserID     int
+	Username   string
+	UserAgent  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	RevokedAt  time.Time
+}
+
+func (s Session) Active(now time.Time) bool {
+	return s.RevokedAt.IsZero() && now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+const (
+	ScopeRead      = "read"
+	ScopeReadWrite = "read-write"
+)
+
+var Scopes = []string{ScopeRead, ScopeReadWrite}
+
+func IsScope(scope string) bool {
+	return scope == ScopeRead || scope == ScopeReadWrite
+}
+
+// APITokenLifetimes are the lifetimes a token can be created with, in days.
+var APITokenLifetimes = []int{7, 30, 90, 365}
+
+// APIToken lets scripts act as a user on one project. Only the hash of the
+// token is stored, the token itself is shown once when it is created.
+type APIToken struct {
+	Id         int
+	UserID     int
+	Username   string
+	Name       string
+	ProjectID  int
+	Scope      string
+	TokenHash  string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+	RevokedAt  time.Time
+}
+
+func (t APIToken) Active(now time.Time) bool {
+	return t.RevokedAt.IsZero() && now.Before(t.ExpiresAt)
+}
+
+// MaxRole is the most a request with the token can do, whatever the role of
+// the user is. Managing settings and members needs a browser session.
+func (t APIToken) MaxRole() string {
+	if t.Scope == ScopeReadWrite {
+		return RoleEditor
+	}
+
+	return RoleViewer
+}
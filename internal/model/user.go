@@ -0,0 +1,38 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// User is a GitHub account that has signed in. Id is the id in go-track,
+// GithubID stays the same when the user renames their GitHub account.
+type User struct {
+	Id         int
+	GithubID   int64
+	Login      string
+	Name       string
+	AvatarUrl  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+}
+
+// DisplayName is the name of the user, or the login when no name is set.
+func (u User) DisplayName() string {
+	if u.Name != "" {
+		return u.Name
+	}
+
+	return u.Login
+}
+
+// Owns reports whether something stored for userID and username belongs to the
+// user. GitHub logins can be taken over by another account once renamed, so the
+// login only counts for what was stored before the user id was recorded.
+func (u User) Owns(userID int, username string) bool {
+	if userID != 0 {
+		return userID == u.Id
+	}
+
+	return strings.EqualFold(username, u.Login)
+}
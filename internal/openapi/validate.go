@@ -0,0 +1,264 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Validate checks a value decoded from JSON against the schema. The error
+// names the first property that does not match.
+func (s *Schema) Validate(value any) error {
+	return s.validate("body", value)
+}
+
+func (s *Schema) validate(path string, value any) error {
+	if value == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+		return errors.New(fmt.Sprintf("%s must not be null", path))
+	}
+
+	switch s.Type {
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return errors.New(fmt.Sprintf("%s must be a boolean", path))
+		}
+
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok {
+			return errors.New(fmt.Sprintf("%s must be a %s", path, s.Type))
+		}
+		if s.Type == "integer" && n != math.Trunc(n) {
+			return errors.New(fmt.Sprintf("%s must be an integer", path))
+		}
+		if s.Minimum != nil && n < float64(*s.Minimum) {
+			return errors.New(fmt.Sprintf("%s must be at least %d", path, *s.Minimum))
+		}
+		if s.Maximum != nil && n > float64(*s.Maximum) {
+			return errors.New(fmt.Sprintf("%s must be at most %d", path, *s.Maximum))
+		}
+
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return errors.New(fmt.Sprintf("%s must be a string", path))
+		}
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+			return errors.New(fmt.Sprintf("%s must be one of %s", path, strings.Join(s.Enum, ", ")))
+		}
+
+	case "array":
+		values, ok := value.([]any)
+		if !ok {
+			return errors.New(fmt.Sprintf("%s must be an array", path))
+		}
+		for i, v := range values {
+			if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), v); err != nil {
+				return err
+			}
+		}
+
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return errors.New(fmt.Sprintf("%s must be an object", path))
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				return errors.New(fmt.Sprintf("%s.%s is required", path, name))
+			}
+		}
+		for name, v := range obj {
+			prop, ok := s.Properties[name]
+			if !ok {
+				continue
+			}
+			if err := prop.validate(path+"."+name, v); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validate checks a path or query parameter, which are strings in the
+// request whatever their type.
+func (p Parameter) validate(raw string) error {
+	if raw == "" {
+		if p.Required {
+			return errors.New(fmt.Sprintf("The %s parameter %s is required", p.In, p.Name))
+		}
+		return nil
+	}
+
+	var value any = raw
+	switch p.Schema.Type {
+	case "integer", "number":
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return errors.New(fmt.Sprintf("The %s parameter %s must be a %s", p.In, p.Name, p.Schema.Type))
+		}
+		value = n
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return errors.New(fmt.Sprintf("The %s parameter %s must be a boolean", p.In, p.Name))
+		}
+		value = b
+	}
+
+	return p.Schema.validate(p.Name, value)
+}
+
+type ValidatorConfig struct {
+	// Fail writes the response for a request that does not match the document.
+	Fail func(c echo.Context, status int, message string) error
+	// StrictResponses replaces responses that do not match the document with
+	// an error, instead of only logging them. Meant for tests.
+	StrictResponses bool
+}
+
+// Validator checks the requests to the routes described in the document, and
+// the JSON responses of their handlers. Routes that are not described are
+// passed through.
+func Validator(doc *Document, config ValidatorConfig) echo.MiddlewareFunc {
+	if config.Fail == nil {
+		config.Fail = func(c echo.Context, status int, message string) error {
+			return c.String(status, message)
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			op := doc.Operation(c.Request().Method, c.Path())
+			if op == nil {
+				return next(c)
+			}
+
+			if err := validateRequest(c, op); err != nil {
+				return config.Fail(c, http.StatusBadRequest, err.Error())
+			}
+
+			rec := &responseRecorder{ResponseWriter: c.Response().Writer, status: http.StatusOK}
+			c.Response().Writer = rec
+			err := next(c)
+			c.Response().Writer = rec.ResponseWriter
+
+			if !c.Response().Committed {
+				// the handler returned an error for the error handler to write
+				return err
+			}
+
+			if invalid := validateResponse(op, rec); invalid != nil {
+				log.Printf("Response of %s %s does not match the API document: %s\n", c.Request().Method, c.Path(), invalid)
+				if config.StrictResponses {
+					rec.ResponseWriter.Header().Del(echo.HeaderContentLength)
+					rec.ResponseWriter.Header().Set(echo.HeaderContentType, echo.MIMETextPlainCharsetUTF8)
+					rec.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+					_, werr := rec.ResponseWriter.Write([]byte(invalid.Error()))
+					return werr
+				}
+			}
+
+			rec.ResponseWriter.WriteHeader(rec.status)
+			if _, werr := rec.ResponseWriter.Write(rec.body.Bytes()); werr != nil {
+				return werr
+			}
+
+			return err
+		}
+	}
+}
+
+func validateRequest(c echo.Context, op *Operation) error {
+	for _, p := range op.Parameters {
+		raw := c.QueryParam(p.Name)
+		if p.In == "path" {
+			raw = c.Param(p.Name)
+		}
+		if err := p.validate(raw); err != nil {
+			return err
+		}
+	}
+
+	if op.RequestBody == nil {
+		return nil
+	}
+
+	req := c.Request()
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.RequestBody.Required {
+			return errors.New("The request body is required")
+		}
+		return nil
+	}
+
+	media, ok := op.RequestBody.Content[mediaTypeJSON]
+	if !ok || !strings.HasPrefix(req.Header.Get(echo.HeaderContentType), mediaTypeJSON) {
+		return errors.New("The request body must be " + mediaTypeJSON)
+	}
+
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return errors.New("The request body is not valid JSON")
+	}
+
+	return media.Schema.Validate(value)
+}
+
+func validateResponse(op *Operation, rec *responseRecorder) error {
+	res, ok := op.Responses[strconv.Itoa(rec.status)]
+	if !ok {
+		res, ok = op.Responses["default"]
+	}
+	if !ok {
+		return errors.New(fmt.Sprintf("status %d is not described", rec.status))
+	}
+
+	media, ok := res.Content[mediaTypeJSON]
+	if !ok {
+		return nil
+	}
+
+	var value any
+	if err := json.Unmarshal(rec.body.Bytes(), &value); err != nil {
+		return errors.New("the body is not valid JSON")
+	}
+
+	return media.Schema.Validate(value)
+}
+
+// responseRecorder holds back the response until it has been validated.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	return r.body.Write(b)
+}
@@ -0,0 +1,74 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+type testRequest struct {
+	Name  string `json:"name"`
+	Count int    `json:"count,omitempty"`
+}
+
+type testResponse struct {
+	Id   int     `json:"id"`
+	Note *string `json:"note"`
+}
+
+func TestValidator(t *testing.T) {
+	doc := &Document{OpenAPI: Version}
+	doc.Add(http.MethodPost, "/things/:id", &Operation{
+		OperationID: "createThing",
+		Parameters:  []Parameter{{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer"}}},
+		RequestBody: &RequestBody{Required: true, Content: JSON(SchemaOf(testRequest{}))},
+		Responses:   map[string]Response{"200": {Description: "The thing", Content: JSON(SchemaOf(testResponse{}))}},
+	})
+
+	response := `{"id":1,"note":null}`
+	e := echo.New()
+	e.POST("/things/:id", func(c echo.Context) error {
+		return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, []byte(response))
+	}, Validator(doc, ValidatorConfig{StrictResponses: true}))
+
+	send := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+		e.ServeHTTP(res, req)
+		return res
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		response string
+		status   int
+	}{
+		{"valid", "/things/1", `{"name":"a","count":2}`, response, http.StatusOK},
+		{"missing required property", "/things/1", `{"count":2}`, response, http.StatusBadRequest},
+		{"wrong property type", "/things/1", `{"name":"a","count":"2"}`, response, http.StatusBadRequest},
+		{"fractional integer", "/things/1", `{"name":"a","count":1.5}`, response, http.StatusBadRequest},
+		{"path parameter not an integer", "/things/one", `{"name":"a"}`, response, http.StatusBadRequest},
+		{"missing body", "/things/1", ``, response, http.StatusBadRequest},
+		{"invalid response", "/things/1", `{"name":"a"}`, `{"id":"1","note":null}`, http.StatusInternalServerError},
+		{"response missing property", "/things/1", `{"name":"a"}`, `{"id":1}`, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response = tt.response
+			res := send(tt.path, tt.body)
+			if res.Code != tt.status {
+				t.Errorf("expected %d, got %d: %s", tt.status, res.Code, res.Body.String())
+			}
+			if tt.status == http.StatusOK && res.Body.String() != tt.response {
+				t.Errorf("expected the response to be passed through, got %s", res.Body.String())
+			}
+		})
+	}
+}
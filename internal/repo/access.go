@@ -0,0 +1,177 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"go-track/internal/db"
+	"go-track/internal/github"
+	"go-track/internal/model"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultAccessRecheckInterval = time.Hour
+
+// accessRecheckBackoff is how long a failed recheck is not retried, so an
+// unavailable GitHub is not asked again on every request.
+const accessRecheckBackoff = time.Minute
+
+var ErrAccessDenied = errors.New("Your GitHub account is not allowed to use go-track")
+
+// ErrSignInAgain is returned when the memberships of the user can not be
+// checked, as their GitHub token is gone. It does not mean they are denied.
+var ErrSignInAgain = errors.New("Sign in again so your access to go-track can be checked")
+
+// AccessRepository decides who may sign in. The allowlist is read from
+// GO_TRACK_ALLOWED_USERS, GO_TRACK_ALLOWED_ORGS and GO_TRACK_ALLOWED_TEAMS,
+// where teams are written as org/team-slug. Everyone may sign in when none of
+// them are set. Instance admins are always allowed.
+type AccessRepository interface {
+	Check(user model.User) error
+	Recheck(user model.User) error
+}
+
+type accessRepo struct {
+	gh   github.GithubService
+	auth AuthRepository
+
+	checkedMu sync.Mutex
+	checked   map[string]time.Time
+	failed    map[string]time.Time
+}
+
+func NewAccessRepo(db db.DatabaseFacade, gh github.GithubService) AccessRepository {
+	return &accessRepo{
+		gh:      gh,
+		auth:    NewAuthRepo(db, gh),
+		checked: make(map[string]time.Time),
+		failed:  make(map[string]time.Time),
+	}
+}
+
+func allowlist(env string) []string {
+	list := make([]string, 0)
+	for _, entry := range strings.Split(os.Getenv(env), ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+
+	return list
+}
+
+// accessRecheckInterval is how often the allowlist is checked again for users
+// who are signed in, from GO_TRACK_ACCESS_RECHECK.
+func accessRecheckInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("GO_TRACK_ACCESS_RECHECK"))
+	if err != nil || interval <= 0 {
+		return defaultAccessRecheckInterval
+	}
+
+	return interval
+}
+
+// Check returns ErrAccessDenied when the user is not on the allowlist.
+// Memberships are looked up with the GitHub token of the user, so it must be
+// stored before the check, or ErrSignInAgain is returned.
+func (r *accessRepo) Check(user model.User) error {
+	err := r.check(user)
+	if err != nil {
+		return err
+	}
+
+	r.checkedMu.Lock()
+	r.checked[userKey(user)] = time.Now()
+	delete(r.failed, userKey(user))
+	r.checkedMu.Unlock()
+
+	return nil
+}
+
+// Recheck checks the user again when the last check is older than the
+// recheck interval, so users who leave an organization lose access. When the
+// check fails for another reason than the user being denied, it is not tried
+// again until the backoff has passed.
+func (r *accessRepo) Recheck(user model.User) error {
+	key := userKey(user)
+
+	r.checkedMu.Lock()
+	checkedAt, ok := r.checked[key]
+	failedAt, failed := r.failed[key]
+	r.checkedMu.Unlock()
+
+	if ok && time.Since(checkedAt) < accessRecheckInterval() {
+		return nil
+	}
+	if failed && time.Since(failedAt) < accessRecheckBackoff {
+		return nil
+	}
+
+	err := r.Check(user)
+	if err != nil && !errors.Is(err, ErrAccessDenied) && !errors.Is(err, ErrSignInAgain) {
+		r.checkedMu.Lock()
+		r.failed[key] = time.Now()
+		r.checkedMu.Unlock()
+	}
+
+	return err
+}
+
+func (r *accessRepo) check(user model.User) error {
+	username := user.Login
+	users := allowlist("GO_TRACK_ALLOWED_USERS")
+	orgs := allowlist("GO_TRACK_ALLOWED_ORGS")
+	teams := allowlist("GO_TRACK_ALLOWED_TEAMS")
+
+	if len(users)+len(orgs)+len(teams) == 0 || IsInstanceAdmin(username) {
+		return nil
+	}
+
+	for _, user := range users {
+		if strings.EqualFold(user, username) {
+			return nil
+		}
+	}
+
+	if len(orgs)+len(teams) == 0 {
+		return ErrAccessDenied
+	}
+
+	token, err := r.auth.GetUserToken(user)
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return ErrSignInAgain
+	}
+	gh := r.gh.WithUserToken(token)
+
+	for _, org := range orgs {
+		membership, err := gh.GetOrgMembership(org)
+		if err != nil {
+			return err
+		}
+		if membership.Active() {
+			return nil
+		}
+	}
+
+	for _, team := range teams {
+		org, slug, ok := strings.Cut(team, "/")
+		if !ok {
+			return errors.New(fmt.Sprintf("Invalid team '%s' in GO_TRACK_ALLOWED_TEAMS, expected org/team-slug", team))
+		}
+
+		membership, err := gh.GetTeamMembership(org, slug, username)
+		if err != nil {
+			return err
+		}
+		if membership.Active() {
+			return nil
+		}
+	}
+
+	return ErrAccessDenied
+}
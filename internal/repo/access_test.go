@@ -0,0 +1,108 @@
+package repo
+
+import (
+	"errors"
+	"go-track/internal/auth"
+	"go-track/internal/github/githubtest"
+	"go-track/internal/model"
+	"net/http"
+	"testing"
+)
+
+func TestAccessAllowlist(t *testing.T) {
+	t.Setenv("TOKEN_ENCRYPTION_KEY", "key")
+	t.Setenv("GO_TRACK_ALLOWED_USERS", "friend")
+	t.Setenv("GO_TRACK_ALLOWED_ORGS", "go-track-org")
+	t.Setenv("GO_TRACK_ALLOWED_TEAMS", "other-org/core")
+
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	srv.AddUser("member", "go-track-org")
+	srv.AddUser("core")
+	srv.AddTeamMember("other-org", "core", "core")
+	srv.AddUser("outsider", "other-org")
+	srv.AddUser("friend")
+
+	gh := newTestGithub(srv)
+	db := newMemoryDB()
+	authRepo := NewAuthRepo(db, gh)
+	access := NewAccessRepo(db, gh)
+
+	users := make(map[string]model.User)
+	signIn := func(login string) {
+		t.Helper()
+		state, _ := auth.NewOAuthState("/")
+		user, err := authRepo.AuthorizeUser(srv.NewCode(login, state.CodeChallenge()), state.Verifier)
+		if err != nil {
+			t.Fatalf("Could not authorize %s: %s\n", login, err)
+		}
+		users[login] = user
+	}
+
+	for login, allowed := range map[string]bool{"member": true, "core": true, "friend": true, "outsider": false} {
+		signIn(login)
+		err := access.Check(users[login])
+		if allowed && err != nil {
+			t.Fatalf("Expected %s to be allowed: %s\n", login, err)
+		}
+		if !allowed && !errors.Is(err, ErrAccessDenied) {
+			t.Fatalf("Expected %s to be denied, got %v\n", login, err)
+		}
+	}
+
+	if err := access.Recheck(users["member"]); err != nil {
+		t.Fatalf("Expected a recent check to be reused: %s\n", err)
+	}
+	count := srv.RequestCount("GET", "/user/memberships/orgs/go-track-org")
+
+	t.Setenv("GO_TRACK_ACCESS_RECHECK", "1ns")
+	t.Setenv("GO_TRACK_ALLOWED_ORGS", "")
+	if err := access.Recheck(users["member"]); !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("Expected member to lose access, got %v\n", err)
+	}
+	if srv.RequestCount("GET", "/user/memberships/orgs/go-track-org") != count {
+		t.Fatal("Expected no membership lookup for an org that is no longer allowed")
+	}
+}
+
+func TestAccessRecheckBacksOff(t *testing.T) {
+	t.Setenv("TOKEN_ENCRYPTION_KEY", "key")
+	t.Setenv("GO_TRACK_ALLOWED_ORGS", "go-track-org")
+
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	srv.AddUser("member", "go-track-org")
+
+	gh := newTestGithub(srv)
+	db := newMemoryDB()
+	authRepo := NewAuthRepo(db, gh)
+	access := NewAccessRepo(db, gh)
+
+	state, _ := auth.NewOAuthState("/")
+	member, err := authRepo.AuthorizeUser(srv.NewCode("member", state.CodeChallenge()), state.Verifier)
+	if err != nil {
+		t.Fatalf("Could not authorize member: %s\n", err)
+	}
+
+	t.Setenv("GO_TRACK_ACCESS_RECHECK", "1ns")
+	srv.Fail(http.MethodGet, "/user/memberships/orgs/go-track-org", http.StatusBadGateway, 1)
+	if err := access.Recheck(member); err == nil || errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("Expected the recheck to fail, got %v\n", err)
+	}
+
+	count := srv.RequestCount(http.MethodGet, "/user/memberships/orgs/go-track-org")
+	for range 5 {
+		if err := access.Recheck(member); err != nil {
+			t.Fatalf("Expected the recheck to be skipped after a failure, got %s\n", err)
+		}
+	}
+	if srv.RequestCount(http.MethodGet, "/user/memberships/orgs/go-track-org") != count {
+		t.Fatal("Expected GitHub not to be asked again during the backoff")
+	}
+
+	if err := access.Check(member); err != nil {
+		t.Fatalf("Expected the member to be allowed once GitHub is back: %s\n", err)
+	}
+}
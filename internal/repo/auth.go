@@ -1,34 +1,214 @@
 package repo
 
 import (
+	"errors"
+	"go-track/internal/auth"
+	"go-track/internal/db"
 	"go-track/internal/github"
 	"go-track/internal/model"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+// userTokenLeeway refreshes user tokens a little before they expire, so a
+// token does not expire while a request is in flight.
+const userTokenLeeway = time.Minute
+
 type AuthRepository interface {
-	GetAuthUrl() string
-	AuthorizeUser(code string) (model.AuthorizedUser, error)
+	GetAuthUrl(state, codeChallenge string) string
+	AuthorizeUser(code, codeVerifier string) (model.User, error)
+	GetUserToken(user model.User) (string, error)
+	GetUser(id int) (model.User, error)
+	GetUserByLogin(login string) (model.User, error)
 }
 
+// refreshLocks serializes token refreshes per user. They are shared by every
+// auth repository, as the handlers and the access checks each have their own.
+var refreshLocks = struct {
+	mu    sync.Mutex
+	users map[string]*sync.Mutex
+}{users: make(map[string]*sync.Mutex)}
+
 type authRepo struct {
+	db db.DatabaseFacade
 	gh github.GithubService
 }
 
-func NewAuthRepo(gh github.GithubService) AuthRepository {
+func NewAuthRepo(db db.DatabaseFacade, gh github.GithubService) AuthRepository {
 	return &authRepo{
+		db: db,
 		gh: gh,
 	}
 }
 
-func (r *authRepo) GetAuthUrl() string {
-	return r.gh.GetAuthUrl()
+func (r *authRepo) GetAuthUrl(state, codeChallenge string) string {
+	return r.gh.GetAuthUrl(state, codeChallenge)
+}
+
+// AuthorizeUser signs the user in with the OAuth code, and saves the profile
+// and the GitHub token of the user.
+func (r *authRepo) AuthorizeUser(code, codeVerifier string) (model.User, error) {
+	authUser, err := r.gh.AuthUserByCode(code, codeVerifier)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	ghUser, err := r.gh.GetAuthorizedUser(authUser)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	now := time.Now()
+	user, err := r.db.UpsertUser(model.User{
+		GithubID:   ghUser.Id,
+		Login:      ghUser.Username,
+		Name:       ghUser.Name,
+		AvatarUrl:  ghUser.AvatarUrl,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	})
+	if err != nil {
+		return model.User{}, err
+	}
+
+	err = r.db.LinkProjectMembers(user)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	err = r.saveUserToken(user, authUser, now)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	return user, nil
+}
+
+func (r *authRepo) GetUser(id int) (model.User, error) {
+	return r.db.GetUser(id)
+}
+
+func (r *authRepo) GetUserByLogin(login string) (model.User, error) {
+	return r.db.GetUserByLogin(login)
+}
+
+// userKey identifies the user in caches. Logins can be reused by another
+// GitHub account, so the login is only used for users without an id.
+func userKey(user model.User) string {
+	if user.Id != 0 {
+		return strconv.Itoa(user.Id)
+	}
+
+	return "login:" + strings.ToLower(user.Login)
+}
+
+// GetUserToken returns a valid GitHub token for the user, refreshing it when
+// it has expired. An empty token is returned when the user has no usable
+// token, and the app should be used instead.
+func (r *authRepo) GetUserToken(user model.User) (string, error) {
+	token, err := r.db.GetUserToken(user)
+	if err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", nil
+	}
+
+	if !token.Expired(time.Now().Add(userTokenLeeway)) {
+		return auth.Decrypt(token.AccessToken)
+	}
+
+	return r.refreshUserToken(user)
+}
+
+// refreshUserToken refreshes the token of one user at a time, since GitHub
+// refresh tokens can only be used once. The token is read again once the
+// lock is held, as it may have been refreshed while waiting for it.
+func (r *authRepo) refreshUserToken(user model.User) (string, error) {
+	lock := refreshLock(user)
+	lock.Lock()
+	defer lock.Unlock()
+
+	token, err := r.db.GetUserToken(user)
+	if err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", nil
+	}
+
+	now := time.Now()
+	if !token.Expired(now.Add(userTokenLeeway)) {
+		return auth.Decrypt(token.AccessToken)
+	}
+
+	if !token.Refreshable(now) {
+		return "", r.db.DeleteUserToken(user)
+	}
+
+	refreshToken, err := auth.Decrypt(token.RefreshToken)
+	if err != nil {
+		return "", err
+	}
+
+	authUser, err := r.gh.RefreshUserToken(refreshToken)
+	if errors.Is(err, github.ErrBadRefreshToken) {
+		// the refresh token has been used or revoked, so the user must sign in again
+		return "", r.db.DeleteUserToken(user)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// saved for the user the token is stored for, the login of the session
+	// may be out of date
+	err = r.saveUserToken(model.User{Id: token.UserID, Login: token.Username}, authUser, now)
+	if err != nil {
+		return "", err
+	}
+
+	return authUser.AccessToken, nil
+}
+
+func refreshLock(user model.User) *sync.Mutex {
+	refreshLocks.mu.Lock()
+	defer refreshLocks.mu.Unlock()
+
+	key := userKey(user)
+	lock, ok := refreshLocks.users[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		refreshLocks.users[key] = lock
+	}
+
+	return lock
 }
-func (r *authRepo) AuthorizeUser(code string) (model.AuthorizedUser, error) {
 
-	authUser, err := r.gh.AuthUserByCode(code)
+func (r *authRepo) saveUserToken(user model.User, authUser model.AuthUserRes, now time.Time) error {
+	accessToken, err := auth.Encrypt(authUser.AccessToken)
+	if err != nil {
+		return err
+	}
+
+	refreshToken, err := auth.Encrypt(authUser.RefreshToken)
 	if err != nil {
-		return model.AuthorizedUser{}, err
+		return err
+	}
+
+	token := model.UserToken{
+		UserID:       user.Id,
+		Username:     user.Login,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}
+	if authUser.ExpiresIn > 0 {
+		token.ExpiresAt = now.Add(time.Duration(authUser.ExpiresIn) * time.Second)
+	}
+	if authUser.RefreshTokenExpiresIn > 0 {
+		token.RefreshExpiresAt = now.Add(time.Duration(authUser.RefreshTokenExpiresIn) * time.Second)
 	}
 
-	return r.gh.GetAuthorizedUser(authUser)
+	return r.db.SaveUserToken(token)
 }
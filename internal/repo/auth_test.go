@@ -0,0 +1,318 @@
+package repo
+
+import (
+	"go-track/internal/auth"
+	"go-track/internal/github/githubtest"
+	"go-track/internal/model"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUserTokenIsRefreshedAndUsedForIssues(t *testing.T) {
+	t.Setenv("TOKEN_ENCRYPTION_KEY", "key")
+
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	srv.AddInstallation("go-track-org", githubtest.AccountOrganization)
+	ghRepo := srv.AddRepo("go-track-org", "board")
+	srv.AddUser("octocat", "go-track-org")
+	state, _ := auth.NewOAuthState("/")
+	code := srv.NewCode("octocat", state.CodeChallenge())
+
+	gh := newTestGithub(srv)
+	db := newMemoryDB()
+	authRepo := NewAuthRepo(db, gh)
+
+	user, err := authRepo.AuthorizeUser(code, state.Verifier)
+	if err != nil {
+		t.Fatalf("Could not authorize user: %s\n", err)
+	}
+
+	stored := db.tokens[user.Id]
+	if stored.AccessToken == "" || stored.RefreshToken == "" || stored.ExpiresAt.IsZero() {
+		t.Fatalf("Expected the user token to be stored, got %+v\n", stored)
+	}
+
+	first, err := authRepo.GetUserToken(user)
+	if err != nil || first == "" {
+		t.Fatalf("Could not get user token: %v\n", err)
+	}
+	if first == stored.AccessToken {
+		t.Fatalf("Expected the stored token to be encrypted\n")
+	}
+
+	stored.ExpiresAt = time.Now().Add(-time.Minute)
+	db.tokens[user.Id] = stored
+
+	refreshed, err := authRepo.GetUserToken(user)
+	if err != nil {
+		t.Fatalf("Could not refresh user token: %s\n", err)
+	}
+	if refreshed == "" || refreshed == first {
+		t.Fatalf("Expected a new user token, got '%s'\n", refreshed)
+	}
+
+	items := NewItemRepo(db, gh.WithUserToken(refreshed))
+	item, _ := db.AddItemToColumn("Authored by the user", 1)
+	item, err = items.CreateIssue("go-track-org", "board", item)
+	if err != nil {
+		t.Fatalf("Could not create issue: %s\n", err)
+	}
+	if author := ghRepo.Issue(item.IssueNumber).Author; author != "octocat" {
+		t.Fatalf("Expected the issue to be authored by 'octocat', got '%s'\n", author)
+	}
+
+	stored = db.tokens[user.Id]
+	stored.ExpiresAt = time.Now().Add(-time.Minute)
+	stored.RefreshExpiresAt = time.Now().Add(-time.Minute)
+	db.tokens[user.Id] = stored
+
+	expired, err := authRepo.GetUserToken(user)
+	if err != nil || expired != "" {
+		t.Fatalf("Expected no token when the refresh token has expired, got '%s', %v\n", expired, err)
+	}
+	if _, ok := db.tokens[user.Id]; ok {
+		t.Fatalf("Expected the expired token to be deleted\n")
+	}
+}
+
+func TestConcurrentUserTokenRefresh(t *testing.T) {
+	t.Setenv("TOKEN_ENCRYPTION_KEY", "key")
+
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	srv.AddUser("octocat")
+	state, _ := auth.NewOAuthState("/")
+
+	db := newMemoryDB()
+	authRepo := NewAuthRepo(db, newTestGithub(srv))
+	user, err := authRepo.AuthorizeUser(srv.NewCode("octocat", state.CodeChallenge()), state.Verifier)
+	if err != nil {
+		t.Fatalf("Could not authorize user: %s\n", err)
+	}
+
+	stored := db.tokens[user.Id]
+	stored.ExpiresAt = time.Now().Add(-time.Minute)
+	db.tokens[user.Id] = stored
+
+	srv.Fail(http.MethodPost, "/login/oauth/access_token", http.StatusBadGateway, 1)
+	if _, err := authRepo.GetUserToken(user); err == nil {
+		t.Fatalf("Expected an error when GitHub fails to refresh the token\n")
+	}
+	if _, ok := db.tokens[user.Id]; !ok {
+		t.Fatalf("Expected the token to be kept when the refresh fails\n")
+	}
+
+	count := srv.RequestCount(http.MethodPost, "/login/oauth/access_token")
+	tokens := make([]string, 10)
+	errs := make([]error, 10)
+	// the handlers and the access checks use auth repositories of their own
+	authRepos := []AuthRepository{authRepo, NewAuthRepo(db, newTestGithub(srv))}
+	var wg sync.WaitGroup
+	for i := range tokens {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tokens[i], errs[i] = authRepos[i%2].GetUserToken(user)
+		}()
+	}
+	wg.Wait()
+
+	for i := range tokens {
+		if errs[i] != nil || tokens[i] == "" || tokens[i] != tokens[0] {
+			t.Fatalf("Expected every request to get the refreshed token, got '%s', %v\n", tokens[i], errs[i])
+		}
+	}
+	if refreshes := srv.RequestCount(http.MethodPost, "/login/oauth/access_token") - count; refreshes != 1 {
+		t.Fatalf("Expected the token to be refreshed once, got %d refreshes\n", refreshes)
+	}
+}
+
+func TestAuthorizeUserLinksMemberships(t *testing.T) {
+	t.Setenv("TOKEN_ENCRYPTION_KEY", "key")
+
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	ghUser, _ := srv.AddUser("octocat")
+	db := newMemoryDB()
+	db.SaveProjectMember(model.Member{ProjectID: 1, Username: "OctoCat", Role: model.RoleEditor})
+	authRepo := NewAuthRepo(db, newTestGithub(srv))
+
+	signIn := func() model.User {
+		t.Helper()
+		state, _ := auth.NewOAuthState("/")
+		user, err := authRepo.AuthorizeUser(srv.NewCode(ghUser.Login, state.CodeChallenge()), state.Verifier)
+		if err != nil {
+			t.Fatalf("Could not authorize user: %s\n", err)
+		}
+		return user
+	}
+
+	user := signIn()
+	if user.Id == 0 || user.GithubID != int64(ghUser.Id) {
+		t.Fatalf("Expected the user to be stored, got %+v\n", user)
+	}
+
+	member, _ := db.GetProjectMember(1, user)
+	if member.UserID != user.Id {
+		t.Fatalf("Expected the membership to reference the user, got %+v\n", member)
+	}
+
+	srv.RenameUser("octocat", "octocat-renamed")
+	renamed := signIn()
+	if renamed.Id != user.Id {
+		t.Fatalf("Expected a renamed user to keep its id, got %d and %d\n", user.Id, renamed.Id)
+	}
+
+	member, _ = db.GetProjectMember(1, renamed)
+	if member.Role != model.RoleEditor {
+		t.Fatalf("Expected the membership to follow the rename, got %+v\n", member)
+	}
+}
+
+func TestReusedLoginInheritsNothing(t *testing.T) {
+	t.Setenv("TOKEN_ENCRYPTION_KEY", "key")
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("JWT_ISSUER", "go-track")
+
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	srv.AddUser("octocat")
+	db := newMemoryDB()
+	authRepo := NewAuthRepo(db, newTestGithub(srv))
+	members := NewMemberRepo(db, newTestGithub(srv))
+	sessions := NewSessionRepo(db)
+	apiTokens := NewAPITokenRepo(db)
+
+	signIn := func(login string) model.User {
+		t.Helper()
+		state, _ := auth.NewOAuthState("/")
+		user, err := authRepo.AuthorizeUser(srv.NewCode(login, state.CodeChallenge()), state.Verifier)
+		if err != nil {
+			t.Fatalf("Could not authorize %s: %s\n", login, err)
+		}
+		return user
+	}
+
+	original := signIn("octocat")
+	db.SaveProjectMember(model.Member{ProjectID: 1, Username: "octocat", Role: model.RoleEditor})
+	jwtString, err := sessions.Create(original, "laptop")
+	if err != nil {
+		t.Fatalf("Could not create session: %s\n", err)
+	}
+	if _, _, err := apiTokens.Create(model.APIToken{UserID: original.Id, Username: "octocat", Name: "script", ProjectID: 1, Scope: model.ScopeRead}, 7); err != nil {
+		t.Fatalf("Could not create API token: %s\n", err)
+	}
+
+	// the original account is renamed, and another account takes the login
+	// before the original signs in again
+	srv.RenameUser("octocat", "octocat-renamed")
+	srv.AddUser("octocat")
+	takeover := signIn("octocat")
+	if takeover.Id == original.Id {
+		t.Fatalf("Expected another GitHub account to get another id\n")
+	}
+
+	if role, err := members.GetRole(1, takeover); err != nil || role != "" {
+		t.Fatalf("Expected the new owner of the login to have no role, got '%s', %v\n", role, err)
+	}
+	if role, err := members.GetRole(1, original); err != nil || role != model.RoleEditor {
+		t.Fatalf("Expected the original account to keep its role, got '%s', %v\n", role, err)
+	}
+
+	originalToken, err := authRepo.GetUserToken(original)
+	if err != nil {
+		t.Fatalf("Could not get the token of the original account: %s\n", err)
+	}
+	takeoverToken, err := authRepo.GetUserToken(takeover)
+	if err != nil || takeoverToken == "" {
+		t.Fatalf("Could not get the token of the new owner: %v\n", err)
+	}
+	if originalToken == takeoverToken {
+		t.Fatalf("Expected the accounts to not share a GitHub token\n")
+	}
+
+	if list, _ := sessions.List(takeover); len(list) != 0 {
+		t.Fatalf("Expected the new owner to not see the sessions of the original, got %v\n", list)
+	}
+	session, _, err := sessions.Validate(jwtString)
+	if err != nil {
+		t.Fatalf("Expected the session of the original account to stay valid: %s\n", err)
+	}
+	if err := sessions.Revoke(takeover, session.SessionID); err == nil {
+		t.Fatalf("Expected the new owner to not be able to revoke the session of the original\n")
+	}
+	if list, _ := apiTokens.List(takeover); len(list) != 0 {
+		t.Fatalf("Expected the new owner to not see the API tokens of the original, got %v\n", list)
+	}
+}
+
+func TestRenamedUserKeepsSessionsAndToken(t *testing.T) {
+	t.Setenv("TOKEN_ENCRYPTION_KEY", "key")
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("JWT_ISSUER", "go-track")
+
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	srv.AddUser("octocat")
+	db := newMemoryDB()
+	authRepo := NewAuthRepo(db, newTestGithub(srv))
+	sessions := NewSessionRepo(db)
+
+	signIn := func(login string) model.User {
+		t.Helper()
+		state, _ := auth.NewOAuthState("/")
+		user, err := authRepo.AuthorizeUser(srv.NewCode(login, state.CodeChallenge()), state.Verifier)
+		if err != nil {
+			t.Fatalf("Could not authorize %s: %s\n", login, err)
+		}
+		return user
+	}
+
+	user := signIn("octocat")
+	jwtString, err := sessions.Create(user, "laptop")
+	if err != nil {
+		t.Fatalf("Could not create session: %s\n", err)
+	}
+
+	srv.RenameUser("octocat", "octocat-renamed")
+	renamed := signIn("octocat-renamed")
+	if renamed.Id != user.Id {
+		t.Fatalf("Expected the renamed user to keep its id, got %d and %d\n", user.Id, renamed.Id)
+	}
+
+	if token, err := authRepo.GetUserToken(renamed); err != nil || token == "" {
+		t.Fatalf("Expected the renamed user to have a GitHub token: %v\n", err)
+	}
+	if len(db.tokens) != 1 {
+		t.Fatalf("Expected one GitHub token for the user, got %d\n", len(db.tokens))
+	}
+
+	session, _, err := sessions.Validate(jwtString)
+	if err != nil {
+		t.Fatalf("Expected the session from before the rename to stay valid: %s\n", err)
+	}
+	if list, _ := sessions.List(renamed); len(list) != 1 || list[0].Id != session.SessionID {
+		t.Fatalf("Expected the renamed user to see the session, got %v\n", list)
+	}
+
+	// the user is touched by id, as the login of the session is out of date
+	stored := db.sessions[session.SessionID]
+	stored.LastSeenAt = time.Now().Add(-time.Hour)
+	db.sessions[session.SessionID] = stored
+	before := time.Now().Add(-time.Hour)
+	db.users[user.Id-1].LastSeenAt = before
+	if _, _, err := sessions.Validate(jwtString); err != nil {
+		t.Fatalf("Expected the session to stay valid: %s\n", err)
+	}
+	if got, _ := db.GetUser(user.Id); !got.LastSeenAt.After(before) || got.Login != "octocat-renamed" {
+		t.Fatalf("Expected the renamed user to be touched, got %+v\n", got)
+	}
+}
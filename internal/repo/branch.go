@@ -3,11 +3,15 @@ package repo
 import (
 	"go-track/internal/github"
 	"go-track/internal/model"
+	"strings"
 )
 
 type BranchRepository interface {
 	GetAll(owner, repo string) ([]model.Branch, error)
 	Get(owner, repo, name string) (model.Branch, error)
+	Search(owner, repo, query string) ([]model.Branch, error)
+	GetChecks(owner, repo, name string) (model.CheckStatus, error)
+	GetDefaultName(owner, repo string, settings model.ProjectSettings) (string, error)
 }
 
 type branchRepo struct {
@@ -36,6 +40,24 @@ func (r *branchRepo) GetAll(owner, repo string) ([]model.Branch, error) {
 
 	return branches, nil
 }
+
+func (r *branchRepo) Search(owner, repo, query string) ([]model.Branch, error) {
+	branches, err := r.GetAll(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(strings.TrimSpace(query))
+	matches := make([]model.Branch, 0)
+	for _, b := range branches {
+		if strings.Contains(strings.ToLower(b.Name), query) {
+			matches = append(matches, b)
+		}
+	}
+
+	return matches, nil
+}
+
 func (r *branchRepo) Get(owner, repo, name string) (model.Branch, error) {
 	b, err := r.gh.GetBranch(owner, repo, name)
 	if err != nil {
@@ -47,3 +69,105 @@ func (r *branchRepo) Get(owner, repo, name string) (model.Branch, error) {
 		Sha:  b.Commit.Sha,
 	}, nil
 }
+
+// GetDefaultName returns the default branch of the project settings, falling
+// back to the default branch of the repository.
+func (r *branchRepo) GetDefaultName(owner, repo string, settings model.ProjectSettings) (string, error) {
+	if settings.DefaultBranch != "" {
+		return settings.DefaultBranch, nil
+	}
+
+	repository, err := r.gh.GetRepository(owner, repo)
+	if err != nil {
+		return "", err
+	}
+
+	return repository.DefaultBranch, nil
+}
+
+func (r *branchRepo) GetChecks(owner, repo, name string) (model.CheckStatus, error) {
+	branch, err := r.Get(owner, repo, name)
+	if err != nil {
+		return model.CheckStatus{}, err
+	}
+
+	return getCheckStatus(r.gh, owner, repo, branch.Sha)
+}
+
+func getCheckStatus(gh github.GithubService, owner, repo, sha string) (model.CheckStatus, error) {
+	combined, err := gh.GetCombinedStatus(owner, repo, sha)
+	if err != nil {
+		return model.CheckStatus{}, err
+	}
+
+	runs, err := gh.GetCheckRuns(owner, repo, sha)
+	if err != nil {
+		return model.CheckStatus{}, err
+	}
+
+	checks := make([]model.Check, 0, len(combined.Statuses)+len(runs.CheckRuns))
+	for _, s := range combined.Statuses {
+		checks = append(checks, model.Check{
+			Name:  s.Context,
+			State: commitStatusState(s.State),
+			Url:   s.TargetUrl,
+		})
+	}
+	for _, run := range runs.CheckRuns {
+		checks = append(checks, model.Check{
+			Name:  run.Name,
+			State: checkRunState(run.Status, run.Conclusion),
+			Url:   run.HtmlUrl,
+		})
+	}
+
+	return model.CheckStatus{
+		Sha:    sha,
+		State:  combinedCheckState(checks),
+		Checks: checks,
+	}, nil
+}
+
+// combinedCheckState reduces the checks into a single state, where any failure
+// wins over pending checks. An empty string is returned when there are no checks.
+func combinedCheckState(checks []model.Check) string {
+	if len(checks) == 0 {
+		return ""
+	}
+
+	state := model.CheckStateSuccess
+	for _, c := range checks {
+		if c.State == model.CheckStateFailure {
+			return model.CheckStateFailure
+		}
+		if c.State == model.CheckStatePending {
+			state = model.CheckStatePending
+		}
+	}
+
+	return state
+}
+
+func commitStatusState(state string) string {
+	switch state {
+	case "success":
+		return model.CheckStateSuccess
+	case "error", "failure":
+		return model.CheckStateFailure
+	default:
+		return model.CheckStatePending
+	}
+}
+
+func checkRunState(status, conclusion string) string {
+	if status != "completed" {
+		return model.CheckStatePending
+	}
+
+	switch conclusion {
+	case "success", "neutral", "skipped":
+		return model.CheckStateSuccess
+	default:
+		return model.CheckStateFailure
+	}
+}
@@ -0,0 +1,173 @@
+package repo
+
+import (
+	"fmt"
+	"go-track/internal/github/githubtest"
+	"go-track/internal/model"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestGetChecks(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	srv.AddInstallation("TobiasTheDanish", githubtest.AccountUser)
+	ghRepo := srv.AddRepo("TobiasTheDanish", "go-track")
+	ghRepo.AddBranch("feature/1-checks", "main")
+	sha := ghRepo.Push("feature/1-checks", "Add checks")
+
+	branches := NewBranchRepo(newTestGithub(srv))
+
+	status, err := branches.GetChecks("TobiasTheDanish", "go-track", "feature/1-checks")
+	if err != nil {
+		t.Fatalf("Could not get checks: %s\n", err)
+	}
+	if status.Sha != sha || status.State != "" || len(status.Checks) != 0 {
+		t.Fatalf("Expected no checks for %s, got %+v\n", sha, status)
+	}
+
+	ghRepo.AddStatus(sha, githubtest.Status{Context: "ci/build", State: "success"})
+	ghRepo.AddCheckRun(sha, githubtest.CheckRun{Name: "lint", Status: "in_progress"})
+
+	status, err = branches.GetChecks("TobiasTheDanish", "go-track", "feature/1-checks")
+	if err != nil {
+		t.Fatalf("Could not get checks: %s\n", err)
+	}
+	if status.State != model.CheckStatePending || len(status.Checks) != 2 {
+		t.Fatalf("Expected two checks that are pending, got %+v\n", status)
+	}
+
+	ghRepo.AddCheckRun(sha, githubtest.CheckRun{Name: "test", Status: "completed", Conclusion: "failure"})
+
+	status, err = branches.GetChecks("TobiasTheDanish", "go-track", "feature/1-checks")
+	if err != nil {
+		t.Fatalf("Could not get checks: %s\n", err)
+	}
+	if status.State != model.CheckStateFailure || len(status.Failing()) != 1 || status.Failing()[0].Name != "test" {
+		t.Fatalf("Expected 'test' to fail the checks, got %+v\n", status)
+	}
+}
+
+func TestCheckStates(t *testing.T) {
+	statuses := map[string]string{
+		"success": model.CheckStateSuccess,
+		"failure": model.CheckStateFailure,
+		"error":   model.CheckStateFailure,
+		"pending": model.CheckStatePending,
+	}
+	for state, expected := range statuses {
+		if got := commitStatusState(state); got != expected {
+			t.Errorf("Expected commit status '%s' to be '%s', got '%s'\n", state, expected, got)
+		}
+	}
+
+	runs := []struct {
+		status     string
+		conclusion string
+		expected   string
+	}{
+		{"queued", "", model.CheckStatePending},
+		{"in_progress", "", model.CheckStatePending},
+		{"completed", "success", model.CheckStateSuccess},
+		{"completed", "neutral", model.CheckStateSuccess},
+		{"completed", "skipped", model.CheckStateSuccess},
+		{"completed", "failure", model.CheckStateFailure},
+		{"completed", "cancelled", model.CheckStateFailure},
+		{"completed", "timed_out", model.CheckStateFailure},
+		{"completed", "action_required", model.CheckStateFailure},
+	}
+	for _, run := range runs {
+		if got := checkRunState(run.status, run.conclusion); got != run.expected {
+			t.Errorf("Expected check run '%s/%s' to be '%s', got '%s'\n", run.status, run.conclusion, run.expected, got)
+		}
+	}
+}
+
+func TestCombinedCheckState(t *testing.T) {
+	check := func(state string) model.Check {
+		return model.Check{Name: state, State: state}
+	}
+
+	tests := []struct {
+		checks   []model.Check
+		expected string
+	}{
+		{nil, ""},
+		{[]model.Check{check(model.CheckStateSuccess)}, model.CheckStateSuccess},
+		{[]model.Check{check(model.CheckStateSuccess), check(model.CheckStatePending)}, model.CheckStatePending},
+		{[]model.Check{check(model.CheckStatePending), check(model.CheckStateFailure), check(model.CheckStateSuccess)}, model.CheckStateFailure},
+	}
+	for _, test := range tests {
+		if got := combinedCheckState(test.checks); got != test.expected {
+			t.Errorf("Expected %v to combine into '%s', got '%s'\n", test.checks, test.expected, got)
+		}
+	}
+}
+
+func TestGetDefaultName(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	srv.AddInstallation("TobiasTheDanish", githubtest.AccountUser)
+	ghRepo := srv.AddRepo("TobiasTheDanish", "go-track")
+	ghRepo.AddBranch("develop", "main")
+	ghRepo.DefaultBranch = "develop"
+
+	branches := NewBranchRepo(newTestGithub(srv))
+
+	name, err := branches.GetDefaultName("TobiasTheDanish", "go-track", model.DefaultProjectSettings(1))
+	if err != nil {
+		t.Fatalf("Could not get default branch: %s\n", err)
+	}
+	if name != "develop" {
+		t.Fatalf("Expected the default branch of the repository 'develop', got '%s'\n", name)
+	}
+
+	settings := model.DefaultProjectSettings(1)
+	settings.DefaultBranch = "release"
+	requests := len(srv.Requests())
+
+	name, err = branches.GetDefaultName("TobiasTheDanish", "go-track", settings)
+	if err != nil {
+		t.Fatalf("Could not get default branch: %s\n", err)
+	}
+	if name != "release" {
+		t.Fatalf("Expected the default branch of the project 'release', got '%s'\n", name)
+	}
+	if len(srv.Requests()) != requests {
+		t.Fatalf("Expected the default branch of the project to be used without asking GitHub\n")
+	}
+}
+
+func TestSearchBranches(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	srv.AddInstallation("TobiasTheDanish", githubtest.AccountUser)
+	ghRepo := srv.AddRepo("TobiasTheDanish", "go-track")
+	for i := 1; i <= 150; i++ {
+		ghRepo.AddBranch(fmt.Sprintf("feature/%03d", i), "main")
+	}
+
+	branches := NewBranchRepo(newTestGithub(srv))
+
+	// the branches are listed by name, so these are on the second page
+	matches, err := branches.Search("TobiasTheDanish", "go-track", "Feature/14")
+	if err != nil {
+		t.Fatalf("Could not search branches: %s\n", err)
+	}
+
+	names := make([]string, len(matches))
+	for i, b := range matches {
+		names[i] = b.Name
+	}
+	expected := []string{"feature/140", "feature/141", "feature/142", "feature/143", "feature/144", "feature/145", "feature/146", "feature/147", "feature/148", "feature/149"}
+	if !slices.Equal(names, expected) {
+		t.Fatalf("Expected %v, got %v\n", expected, names)
+	}
+	if count := srv.RequestCount(http.MethodGet, "/repos/TobiasTheDanish/go-track/branches"); count != 2 {
+		t.Fatalf("Expected the branches to be listed in 2 pages, got %d requests\n", count)
+	}
+}
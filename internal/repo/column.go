@@ -7,6 +7,7 @@ import (
 
 type ColumnRepository interface {
 	GetForProject(projectID int) ([]model.Column, error)
+	GetPage(projectID, limit, offset int) ([]model.Column, int, error)
 	Get(id int) (model.Column, error)
 	AddItem(name string, columnID int) (model.Item, error)
 	RemoveItem(itemID, columnID int) (model.Column, error)
@@ -26,6 +27,12 @@ func (r *columnRepo) GetForProject(projectID int) ([]model.Column, error) {
 	return r.db.GetColumnsForProject(projectID)
 }
 
+// GetPage returns limit columns of the project from offset, and the number of
+// columns of the project.
+func (r *columnRepo) GetPage(projectID, limit, offset int) ([]model.Column, int, error) {
+	return r.db.GetColumnsPage(projectID, limit, offset)
+}
+
 func (r *columnRepo) Get(id int) (model.Column, error) {
 	return r.db.GetColumn(id)
 }
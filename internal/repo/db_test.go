@@ -0,0 +1,366 @@
+package repo
+
+import (
+	"errors"
+	"go-track/internal/github"
+	"go-track/internal/github/githubtest"
+	"go-track/internal/model"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryDB keeps what the repos need in memory. Projects are not needed by the
+// tests, so they are not implemented.
+type memoryDB struct {
+	columns   map[int]model.Column
+	items     map[int]model.Item
+	tokenMu   sync.Mutex
+	tokens    map[int]model.UserToken
+	settings  map[int]model.ProjectSettings
+	members   map[int][]model.Member
+	sessions  map[string]model.Session
+	apiTokens []model.APIToken
+	users     []model.User
+	pingErr   error
+}
+
+func newMemoryDB() *memoryDB {
+	return &memoryDB{
+		columns:  make(map[int]model.Column),
+		items:    make(map[int]model.Item),
+		tokens:   make(map[int]model.UserToken),
+		settings: make(map[int]model.ProjectSettings),
+		members:  make(map[int][]model.Member),
+		sessions: make(map[string]model.Session),
+	}
+}
+
+func (m *memoryDB) Ping() error {
+	return m.pingErr
+}
+
+func (m *memoryDB) GetProject(id int) (model.Project, error) {
+	return model.Project{}, errors.New("not implemented")
+}
+
+func (m *memoryDB) GetProjects() ([]model.Project, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *memoryDB) GetProjectSettings(projectID int) (model.ProjectSettings, error) {
+	settings, ok := m.settings[projectID]
+	if !ok {
+		return model.DefaultProjectSettings(projectID), nil
+	}
+
+	return settings, nil
+}
+
+func (m *memoryDB) UpdateProjectSettings(settings model.ProjectSettings) (model.ProjectSettings, error) {
+	m.settings[settings.ProjectID] = settings
+	return settings, nil
+}
+
+func (m *memoryDB) GetProjectMembers(projectID int) ([]model.Member, error) {
+	return append([]model.Member(nil), m.members[projectID]...), nil
+}
+
+func (m *memoryDB) GetProjectMember(projectID int, user model.User) (model.Member, error) {
+	for _, member := range m.members[projectID] {
+		if user.Owns(member.UserID, member.Username) {
+			return member, nil
+		}
+	}
+
+	return model.Member{ProjectID: projectID, Username: user.Login}, nil
+}
+
+func (m *memoryDB) SaveProjectMember(member model.Member) (model.Member, error) {
+	m.DeleteProjectMember(member.ProjectID, member.Username)
+	if user, _ := m.GetUserByLogin(member.Username); user.Id != 0 {
+		member.UserID = user.Id
+	}
+	m.members[member.ProjectID] = append(m.members[member.ProjectID], member)
+	return member, nil
+}
+
+func (m *memoryDB) DeleteProjectMember(projectID int, username string) error {
+	m.members[projectID] = slices.DeleteFunc(m.members[projectID], func(member model.Member) bool {
+		return strings.EqualFold(member.Username, username)
+	})
+	return nil
+}
+
+func (m *memoryDB) GetColumnsForProject(projectID int) ([]model.Column, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *memoryDB) GetColumnsPage(projectID, limit, offset int) ([]model.Column, int, error) {
+	return nil, 0, errors.New("not implemented")
+}
+
+func (m *memoryDB) GetItemsPage(projectID, columnID, limit, offset int) ([]model.Item, int, error) {
+	return nil, 0, errors.New("not implemented")
+}
+
+func (m *memoryDB) GetColumn(id int) (model.Column, error) {
+	col, ok := m.columns[id]
+	if !ok {
+		return model.Column{}, errors.New("column not found")
+	}
+
+	return col, nil
+}
+
+func (m *memoryDB) AddItemToColumn(name string, columnID int) (model.Item, error) {
+	item := model.Item{
+		Id:                len(m.items) + 1,
+		Name:              name,
+		ColumnID:          columnID,
+		IssueID:           -1,
+		IssueNumber:       -1,
+		PullRequestID:     -1,
+		PullRequestNumber: -1,
+	}
+	m.items[item.Id] = item
+
+	return item, nil
+}
+
+func (m *memoryDB) GetNextItemColumnOrder(columnID int) (int, error) {
+	return 0, nil
+}
+
+func (m *memoryDB) GetItem(id int) (model.Item, error) {
+	item, ok := m.items[id]
+	if !ok {
+		return model.Item{}, errors.New("item not found")
+	}
+
+	return item, nil
+}
+
+func (m *memoryDB) UpdateItem(id int, item model.Item) (model.Item, error) {
+	m.items[id] = item
+	return item, nil
+}
+
+func (m *memoryDB) DeleteItem(itemID int) error {
+	delete(m.items, itemID)
+	return nil
+}
+
+func (m *memoryDB) GetUserToken(user model.User) (model.UserToken, error) {
+	m.tokenMu.Lock()
+	defer m.tokenMu.Unlock()
+
+	token, ok := m.tokens[user.Id]
+	if !ok {
+		return model.UserToken{UserID: user.Id, Username: user.Login}, nil
+	}
+
+	return token, nil
+}
+
+func (m *memoryDB) SaveUserToken(token model.UserToken) error {
+	m.tokenMu.Lock()
+	defer m.tokenMu.Unlock()
+
+	if token.UserID == 0 {
+		return errors.New("user token without a user")
+	}
+	m.tokens[token.UserID] = token
+	return nil
+}
+
+func (m *memoryDB) DeleteUserToken(user model.User) error {
+	m.tokenMu.Lock()
+	defer m.tokenMu.Unlock()
+
+	delete(m.tokens, user.Id)
+	return nil
+}
+
+func (m *memoryDB) UpsertUser(user model.User) (model.User, error) {
+	for i, u := range m.users {
+		if u.GithubID == user.GithubID {
+			user.Id, user.CreatedAt = u.Id, u.CreatedAt
+			m.users[i] = user
+			return user, nil
+		}
+	}
+
+	user.Id = len(m.users) + 1
+	m.users = append(m.users, user)
+	return user, nil
+}
+
+func (m *memoryDB) GetUser(id int) (model.User, error) {
+	if id < 1 || id > len(m.users) {
+		return model.User{}, errors.New("user not found")
+	}
+
+	return m.users[id-1], nil
+}
+
+func (m *memoryDB) GetUserByLogin(login string) (model.User, error) {
+	found := model.User{Login: login}
+	for _, u := range m.users {
+		if strings.EqualFold(u.Login, login) && (found.Id == 0 || !u.LastSeenAt.Before(found.LastSeenAt)) {
+			found = u
+		}
+	}
+
+	return found, nil
+}
+
+func (m *memoryDB) TouchUser(id int, lastSeenAt time.Time) error {
+	if id < 1 || id > len(m.users) {
+		return errors.New("user not found")
+	}
+
+	m.users[id-1].LastSeenAt = lastSeenAt
+	return nil
+}
+
+func (m *memoryDB) LinkProjectMembers(user model.User) error {
+	for projID, members := range m.members {
+		for i, member := range members {
+			if member.UserID == user.Id || (member.UserID == 0 && strings.EqualFold(member.Username, user.Login)) {
+				m.members[projID][i].UserID = user.Id
+				m.members[projID][i].Username = user.Login
+			}
+		}
+	}
+	return nil
+}
+
+func (m *memoryDB) CreateSession(session model.Session) error {
+	m.sessions[session.Id] = session
+	return nil
+}
+
+func (m *memoryDB) GetSession(id string) (model.Session, error) {
+	session, ok := m.sessions[id]
+	if !ok {
+		return model.Session{}, errors.New("session not found")
+	}
+
+	return session, nil
+}
+
+func (m *memoryDB) GetSessionsForUser(user model.User) ([]model.Session, error) {
+	sessions := make([]model.Session, 0)
+	for _, session := range m.sessions {
+		if user.Owns(session.UserID, session.Username) && session.RevokedAt.IsZero() {
+			sessions = append(sessions, session)
+		}
+	}
+
+	return sessions, nil
+}
+
+func (m *memoryDB) TouchSession(id string, lastSeenAt time.Time) error {
+	session := m.sessions[id]
+	session.LastSeenAt = lastSeenAt
+	m.sessions[id] = session
+	return nil
+}
+
+func (m *memoryDB) ExtendSession(id string, expiresAt time.Time) error {
+	session := m.sessions[id]
+	session.ExpiresAt = expiresAt
+	m.sessions[id] = session
+	return nil
+}
+
+func (m *memoryDB) RevokeSession(id string, revokedAt time.Time) error {
+	session, ok := m.sessions[id]
+	if ok && session.RevokedAt.IsZero() {
+		session.RevokedAt = revokedAt
+		m.sessions[id] = session
+	}
+	return nil
+}
+
+func (m *memoryDB) RevokeSessionsForUser(user model.User, revokedAt time.Time) error {
+	for id, session := range m.sessions {
+		if user.Owns(session.UserID, session.Username) {
+			m.RevokeSession(id, revokedAt)
+		}
+	}
+	return nil
+}
+
+func (m *memoryDB) DeleteSessionsExpiredBefore(before time.Time) error {
+	for id, session := range m.sessions {
+		if !session.ExpiresAt.After(before) {
+			delete(m.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (m *memoryDB) CreateAPIToken(token model.APIToken) (model.APIToken, error) {
+	token.Id = len(m.apiTokens) + 1
+	m.apiTokens = append(m.apiTokens, token)
+	return token, nil
+}
+
+func (m *memoryDB) GetAPITokenByHash(hash string) (model.APIToken, error) {
+	for _, token := range m.apiTokens {
+		if token.TokenHash == hash {
+			return token, nil
+		}
+	}
+
+	return model.APIToken{}, errors.New("API token not found")
+}
+
+func (m *memoryDB) GetAPITokensForUser(user model.User) ([]model.APIToken, error) {
+	tokens := make([]model.APIToken, 0)
+	for _, token := range m.apiTokens {
+		if user.Owns(token.UserID, token.Username) && token.RevokedAt.IsZero() {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens, nil
+}
+
+func (m *memoryDB) TouchAPIToken(id int, lastUsedAt time.Time) error {
+	m.apiTokens[id-1].LastUsedAt = lastUsedAt
+	return nil
+}
+
+func (m *memoryDB) RevokeAPIToken(id int, user model.User, revokedAt time.Time) error {
+	if id < 1 || id > len(m.apiTokens) || !user.Owns(m.apiTokens[id-1].UserID, m.apiTokens[id-1].Username) {
+		return errors.New("API token not found")
+	}
+
+	m.apiTokens[id-1].RevokedAt = revokedAt
+	return nil
+}
+
+func (m *memoryDB) RevokeAPITokensForUser(user model.User, revokedAt time.Time) error {
+	for i, token := range m.apiTokens {
+		if user.Owns(token.UserID, token.Username) && token.RevokedAt.IsZero() {
+			m.apiTokens[i].RevokedAt = revokedAt
+		}
+	}
+	return nil
+}
+
+func newTestGithub(srv *githubtest.Server) github.GithubService {
+	return github.NewWithConfig(github.Config{
+		AppId:        "1",
+		ClientId:     githubtest.ClientId,
+		ClientSecret: githubtest.ClientSecret,
+		PrivateKey:   srv.PrivateKey,
+		ApiUrl:       srv.URL,
+		WebUrl:       srv.URL,
+		Client:       srv.Client(),
+	})
+}
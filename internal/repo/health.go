@@ -0,0 +1,171 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"go-track/internal/db"
+	"go-track/internal/github"
+	"go-track/internal/model"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// readinessCacheFor keeps load balancers polling often from minting an
+	// installation token on every request.
+	readinessCacheFor = 10 * time.Second
+	// dependencyTimeout is how long a dependency may take before it is
+	// considered failing.
+	dependencyTimeout = 5 * time.Second
+)
+
+const (
+	DependencyDatabase          = "database"
+	DependencyGithubApp         = "githubApp"
+	DependencyInstallationToken = "githubInstallationToken"
+)
+
+type HealthRepository interface {
+	Readiness() model.Readiness
+}
+
+type healthRepo struct {
+	db db.DatabaseFacade
+	gh github.GithubService
+
+	mu     sync.Mutex
+	cached model.Readiness
+}
+
+func NewHealthRepo(db db.DatabaseFacade, gh github.GithubService) HealthRepository {
+	return &healthRepo{
+		db: db,
+		gh: gh,
+	}
+}
+
+// Readiness checks the database and GitHub at the same time. Results are
+// reused for a few seconds.
+func (r *healthRepo) Readiness() model.Readiness {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if !r.cached.CheckedAt.IsZero() && now.Sub(r.cached.CheckedAt) < readinessCacheFor {
+		return r.cached
+	}
+
+	checks := map[string]func() (string, error){
+		DependencyDatabase:          r.checkDatabase,
+		DependencyGithubApp:         r.checkGithubApp,
+		DependencyInstallationToken: r.checkInstallationToken,
+	}
+
+	var wg sync.WaitGroup
+	var resultsMu sync.Mutex
+	readiness := model.Readiness{
+		Status:       model.HealthOK,
+		CheckedAt:    now,
+		Dependencies: make(map[string]model.DependencyHealth),
+	}
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			health := runCheck(check)
+			if health.Status != model.HealthOK {
+				log.Printf("Readiness check %s failed: %s\n", name, health.Error)
+			}
+
+			resultsMu.Lock()
+			defer resultsMu.Unlock()
+			readiness.Dependencies[name] = health
+			if health.Status != model.HealthOK {
+				readiness.Status = model.HealthFailing
+			}
+		}()
+	}
+	wg.Wait()
+
+	r.cached = readiness
+	return readiness
+}
+
+type checkResult struct {
+	detail string
+	err    error
+}
+
+// runCheck gives up on checks taking longer than dependencyTimeout. The check
+// is left to finish on its own.
+func runCheck(check func() (string, error)) model.DependencyHealth {
+	start := time.Now()
+	done := make(chan checkResult, 1)
+	go func() {
+		detail, err := check()
+		done <- checkResult{detail, err}
+	}()
+
+	var result checkResult
+	select {
+	case result = <-done:
+	case <-time.After(dependencyTimeout):
+		result.err = errors.New(fmt.Sprintf("The check timed out after %s", dependencyTimeout))
+	}
+
+	health := model.DependencyHealth{
+		Status:     model.HealthOK,
+		Detail:     result.detail,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if result.err != nil {
+		health.Status = model.HealthFailing
+		health.Error = result.err.Error()
+	}
+
+	return health
+}
+
+// checkDatabase does not pass on the error of the driver, which may contain
+// the connection string. It is logged instead.
+func (r *healthRepo) checkDatabase() (string, error) {
+	if err := r.db.Ping(); err != nil {
+		log.Printf("Pinging the database failed: %s\n", err)
+		return "", errors.New("Pinging the database failed")
+	}
+
+	return "", nil
+}
+
+// checkGithubApp signs an app JWT with the private key, and has GitHub
+// accept it.
+func (r *healthRepo) checkGithubApp() (string, error) {
+	if err := r.gh.CheckPrivateKey(); err != nil {
+		return "", err
+	}
+
+	app, err := r.gh.GetApp()
+	if err != nil {
+		return "", err
+	}
+
+	return app.Name, nil
+}
+
+func (r *healthRepo) checkInstallationToken() (string, error) {
+	installations, err := r.gh.ListAppInstallations()
+	if err != nil {
+		return "", err
+	}
+	if len(installations) == 0 {
+		return "The GitHub App has no installations", nil
+	}
+
+	installation := installations[0]
+	if err := r.gh.CheckInstallationToken(installation); err != nil {
+		return "", err
+	}
+
+	return "Minted a token for " + installation.Account.Login, nil
+}
@@ -0,0 +1,56 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"go-track/internal/github/githubtest"
+	"go-track/internal/model"
+	"testing"
+)
+
+func TestReadiness(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	installation := srv.AddInstallation("TobiasTheDanish", githubtest.AccountUser)
+	tokenPath := fmt.Sprintf("/app/installations/%d/access_tokens", installation.Id)
+
+	db := newMemoryDB()
+	health := NewHealthRepo(db, newTestGithub(srv))
+
+	readiness := health.Readiness()
+	if !readiness.Ready() {
+		t.Fatalf("Expected to be ready, got %+v\n", readiness)
+	}
+	for _, name := range []string{DependencyDatabase, DependencyGithubApp, DependencyInstallationToken} {
+		if readiness.Dependencies[name].Status != model.HealthOK {
+			t.Errorf("Expected %s to be ok, got %+v\n", name, readiness.Dependencies[name])
+		}
+	}
+	if srv.RequestCount("POST", tokenPath) != 1 {
+		t.Errorf("Expected an installation token to be minted\n")
+	}
+
+	// results are reused for a while, so GitHub is not asked again
+	health.Readiness()
+	if srv.RequestCount("POST", tokenPath) != 1 {
+		t.Errorf("Expected the readiness to be cached\n")
+	}
+
+	db.pingErr = errors.New("dial tcp: libsql://secret@host")
+	srv.Fail("POST", tokenPath, 401, 1)
+	health = NewHealthRepo(db, newTestGithub(srv))
+
+	readiness = health.Readiness()
+	if readiness.Ready() {
+		t.Fatalf("Expected not to be ready, got %+v\n", readiness)
+	}
+	if dep := readiness.Dependencies[DependencyDatabase]; dep.Status != model.HealthFailing || dep.Error != "Pinging the database failed" {
+		t.Errorf("Expected the database to fail without the driver error, got %+v\n", dep)
+	}
+	if dep := readiness.Dependencies[DependencyInstallationToken]; dep.Status != model.HealthFailing {
+		t.Errorf("Expected minting an installation token to fail, got %+v\n", dep)
+	}
+	if dep := readiness.Dependencies[DependencyGithubApp]; dep.Status != model.HealthOK {
+		t.Errorf("Expected the app to be ok, got %+v\n", dep)
+	}
+}
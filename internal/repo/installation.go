@@ -0,0 +1,107 @@
+package repo
+
+import (
+	"go-track/internal/github"
+	"go-track/internal/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+// repositoryCacheFor keeps the settings page from listing every installation
+// again on every view and save.
+const repositoryCacheFor = time.Minute
+
+type InstallationRepository interface {
+	ListRepositories(user model.User, userToken string) ([]model.Repository, error)
+	GetInstallUrl() (string, error)
+}
+
+type cachedRepositories struct {
+	repositories []model.Repository
+	expiresAt    time.Time
+}
+
+type installationRepo struct {
+	gh github.GithubService
+
+	mu           sync.Mutex
+	repositories map[string]cachedRepositories
+	installUrl   string
+}
+
+func NewInstallationRepo(gh github.GithubService) InstallationRepository {
+	return &installationRepo{
+		gh:           gh,
+		repositories: make(map[string]cachedRepositories),
+	}
+}
+
+// ListRepositories returns the repositories the GitHub App is installed on,
+// that the user has access to through the user token. The listing is cached
+// per user for a minute.
+func (r *installationRepo) ListRepositories(user model.User, userToken string) ([]model.Repository, error) {
+	key := userKey(user)
+	now := time.Now()
+
+	r.mu.Lock()
+	cached, ok := r.repositories[key]
+	r.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.repositories, nil
+	}
+
+	installations, err := r.gh.GetUserInstallations(userToken)
+	if err != nil {
+		return nil, err
+	}
+
+	repositories := make([]model.Repository, 0)
+	for _, installation := range installations {
+		repoDTOs, err := r.gh.ListUserInstallationRepositories(userToken, installation.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dto := range repoDTOs {
+			repositories = append(repositories, model.Repository{
+				Owner:         dto.Owner.Login,
+				Name:          dto.Name,
+				DefaultBranch: dto.DefaultBranch,
+				Private:       dto.Private,
+				Url:           dto.HtmlUrl,
+			})
+		}
+	}
+
+	sort.Slice(repositories, func(i, j int) bool {
+		return repositories[i].FullName() < repositories[j].FullName()
+	})
+
+	r.mu.Lock()
+	r.repositories[key] = cachedRepositories{
+		repositories: repositories,
+		expiresAt:    now.Add(repositoryCacheFor),
+	}
+	r.mu.Unlock()
+
+	return repositories, nil
+}
+
+// GetInstallUrl only asks GitHub for the app once, as its url does not change.
+func (r *installationRepo) GetInstallUrl() (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.installUrl != "" {
+		return r.installUrl, nil
+	}
+
+	app, err := r.gh.GetApp()
+	if err != nil {
+		return "", err
+	}
+
+	r.installUrl = app.HtmlUrl + "/installations/new"
+	return r.installUrl, nil
+}
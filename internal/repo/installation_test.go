@@ -0,0 +1,51 @@
+package repo
+
+import (
+	"go-track/internal/auth"
+	"go-track/internal/github/githubtest"
+	"testing"
+)
+
+func TestListRepositories(t *testing.T) {
+	t.Setenv("TOKEN_ENCRYPTION_KEY", "key")
+
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	srv.AddInstallation("go-track-org", githubtest.AccountOrganization)
+	srv.AddInstallation("other-org", githubtest.AccountOrganization)
+	srv.AddRepo("go-track-org", "go-track")
+	srv.AddRepo("go-track-org", "website")
+	srv.AddRepo("other-org", "secret")
+	srv.AddUser("member", "go-track-org")
+
+	gh := newTestGithub(srv)
+	authRepo := NewAuthRepo(newMemoryDB(), gh)
+	installRepo := NewInstallationRepo(gh)
+
+	state, _ := auth.NewOAuthState("/")
+	member, err := authRepo.AuthorizeUser(srv.NewCode("member", state.CodeChallenge()), state.Verifier)
+	if err != nil {
+		t.Fatalf("Could not authorize member: %s\n", err)
+	}
+	token, err := authRepo.GetUserToken(member)
+	if err != nil {
+		t.Fatalf("Could not get the user token: %s\n", err)
+	}
+
+	repositories, err := installRepo.ListRepositories(member, token)
+	if err != nil {
+		t.Fatalf("Could not list repositories: %s\n", err)
+	}
+	if len(repositories) != 2 || repositories[0].FullName() != "go-track-org/go-track" || repositories[1].FullName() != "go-track-org/website" {
+		t.Fatalf("Expected only the repositories of go-track-org, got %v\n", repositories)
+	}
+
+	count := srv.RequestCount("GET", "/user/installations")
+	if _, err := installRepo.ListRepositories(member, token); err != nil {
+		t.Fatalf("Could not list repositories again: %s\n", err)
+	}
+	if srv.RequestCount("GET", "/user/installations") != count {
+		t.Fatal("Expected the second listing to be cached")
+	}
+}
@@ -2,8 +2,10 @@ package repo
 
 import (
 	"errors"
+	"fmt"
 	"go-track/internal/db"
 	"go-track/internal/github"
+	"go-track/internal/metrics"
 	"go-track/internal/model"
 	"strings"
 )
@@ -11,12 +13,21 @@ import (
 type ItemRepository interface {
 	Move(projID, itemID int, dir string) (model.Item, error)
 	Get(itemID int) (model.Item, error)
+	GetPage(projID, colID, limit, offset int) ([]model.Item, int, error)
 	CreateIssue(owner, repo string, item model.Item) (model.Item, error)
 	CreateBranch(owner, repo, branchName, branchSha string, itemID int) (model.Item, error)
-	CreatePullRequest(owner, repo, headBranch, baseBranch string, itemID int) (model.Item, error)
-	MergePullRequest(owner, repo, title, message string, pullNumber int, deleteBranch bool, itemID int) (model.Item, error)
+	CreatePullRequest(owner, repo, headBranch, baseBranch string, draft bool, itemID int) (model.Item, error)
+	StartDraftPullRequest(owner, repo, baseBranch string, itemID int) (model.Item, error)
+	CreateBranchWithDraftPullRequest(owner, repo, branchName, branchSha, baseBranch string, itemID int) (model.Item, error)
+	MarkReadyForReview(owner, repo string, itemID int) (model.Item, error)
+	LinkBranch(owner, repo, branchName string, itemID int) (model.Item, error)
+	LinkPullRequest(owner, repo string, pullNumber int, itemID int) (model.Item, error)
+	MergePullRequest(owner, repo, title, message, method, sha string, pullNumber int, deleteBranch bool, itemID int) (model.Item, error)
+	EnterColumn(projID int, item model.Item) (model.Item, string, error)
 }
 
+var ErrNoRepository = errors.New("No GitHub repository has been picked for the project, pick one in the project settings")
+
 type itemRepo struct {
 	db db.DatabaseFacade
 	gh github.GithubService
@@ -33,6 +44,12 @@ func (r *itemRepo) Get(itemID int) (model.Item, error) {
 	return r.db.GetItem(itemID)
 }
 
+// GetPage returns limit items of the project from offset, ordered by column
+// and position, and the number of items. A colID of -1 is every column.
+func (r *itemRepo) GetPage(projID, colID, limit, offset int) ([]model.Item, int, error) {
+	return r.db.GetItemsPage(projID, colID, limit, offset)
+}
+
 func (r *itemRepo) CreateIssue(owner, repo string, item model.Item) (model.Item, error) {
 	issue, err := r.gh.CreateIssue(owner, repo, item.Name)
 	if err != nil {
@@ -53,7 +70,7 @@ func (r *itemRepo) CreateBranch(owner, repo, branchName, branchSha string, itemI
 		return model.Item{}, err
 	}
 
-	branch, err := r.gh.CreateBranch("TobiasTheDanish", "go-track", branchName, branchSha)
+	branch, err := r.gh.CreateBranch(owner, repo, branchName, branchSha)
 	if err != nil {
 		return model.Item{}, err
 	}
@@ -63,13 +80,13 @@ func (r *itemRepo) CreateBranch(owner, repo, branchName, branchSha string, itemI
 	return r.db.UpdateItem(itemID, item)
 }
 
-func (r *itemRepo) CreatePullRequest(owner, repo, headBranch, baseBranch string, itemID int) (model.Item, error) {
+func (r *itemRepo) CreatePullRequest(owner, repo, headBranch, baseBranch string, draft bool, itemID int) (model.Item, error) {
 	item, err := r.Get(itemID)
 	if err != nil {
 		return model.Item{}, err
 	}
 
-	pr, err := r.gh.CreatePullRequest(owner, repo, headBranch, baseBranch, item.IssueNumber)
+	pr, err := r.gh.CreatePullRequest(owner, repo, headBranch, baseBranch, item.IssueNumber, draft)
 	if err != nil {
 		return model.Item{}, err
 	}
@@ -83,16 +100,165 @@ func (r *itemRepo) CreatePullRequest(owner, repo, headBranch, baseBranch string,
 	return r.db.UpdateItem(itemID, item)
 }
 
-func (r *itemRepo) MergePullRequest(owner, repo, title, message string, pullNumber int, deleteBranch bool, itemID int) (model.Item, error) {
+// StartDraftPullRequest opens a draft pull request for the branch of the item.
+// The branch is usually brand new, and GitHub does not open pull requests
+// without commits, so an empty commit is added to it first. Drafts are only
+// opened when the user asks for one.
+func (r *itemRepo) StartDraftPullRequest(owner, repo, baseBranch string, itemID int) (model.Item, error) {
+	item, err := r.Get(itemID)
+	if err != nil {
+		return model.Item{}, err
+	}
+
+	if item.BranchName == "" {
+		return model.Item{}, errors.New("Item has no branch to open a pull request for")
+	}
+
+	_, err = r.gh.CreateEmptyCommit(owner, repo, item.BranchName, fmt.Sprintf("Start work on '%s'", item.Name))
+	if err != nil {
+		return model.Item{}, err
+	}
+
+	return r.CreatePullRequest(owner, repo, item.BranchName, baseBranch, true, itemID)
+}
+
+// CreateBranchWithDraftPullRequest creates the branch and opens a draft pull
+// request for it. The new branch is deleted and unlinked again when the pull
+// request can not be opened, so the item is left as it was.
+func (r *itemRepo) CreateBranchWithDraftPullRequest(owner, repo, branchName, branchSha, baseBranch string, itemID int) (model.Item, error) {
+	item, err := r.CreateBranch(owner, repo, branchName, branchSha, itemID)
+	if err != nil {
+		return model.Item{}, err
+	}
+
+	started, err := r.StartDraftPullRequest(owner, repo, baseBranch, itemID)
+	if err == nil {
+		return started, nil
+	}
+
+	if deleteErr := r.gh.DeleteBranch(owner, repo, branchName); deleteErr != nil {
+		return model.Item{}, errors.Join(errors.New(fmt.Sprintf("The draft pull request could not be opened, and deleting branch '%s' failed", branchName)), err, deleteErr)
+	}
+
+	item.BranchName = ""
+	if _, updateErr := r.db.UpdateItem(itemID, item); updateErr != nil {
+		return model.Item{}, errors.Join(err, updateErr)
+	}
+
+	return model.Item{}, errors.Join(errors.New(fmt.Sprintf("The draft pull request could not be opened, so branch '%s' was deleted again", branchName)), err)
+}
+
+func (r *itemRepo) MarkReadyForReview(owner, repo string, itemID int) (model.Item, error) {
 	item, err := r.Get(itemID)
+	if err != nil {
+		return model.Item{}, err
+	}
 
-	_, err = r.gh.MergePullRequest("TobiasTheDanish", "go-track", title, message, pullNumber)
+	pr, err := r.gh.GetPullRequest(owner, repo, item.PullRequestNumber)
+	if err != nil {
+		return model.Item{}, err
+	}
+
+	if !pr.Draft {
+		return item, nil
+	}
+
+	err = r.gh.MarkPullRequestReadyForReview(owner, repo, pr.NodeId)
+	if err != nil {
+		return model.Item{}, err
+	}
+
+	return item, nil
+}
+
+// LinkBranch attaches an existing branch to the item without creating anything.
+func (r *itemRepo) LinkBranch(owner, repo, branchName string, itemID int) (model.Item, error) {
+	item, err := r.Get(itemID)
+	if err != nil {
+		return model.Item{}, err
+	}
+
+	branch, err := r.gh.GetBranch(owner, repo, branchName)
+	if err != nil {
+		return model.Item{}, err
+	}
+
+	item.BranchName = branch.Name
+
+	return r.db.UpdateItem(itemID, item)
+}
+
+// LinkPullRequest attaches an existing open pull request to the item. The head
+// branch of the pull request is linked as well when the item has no branch,
+// and pull requests of another branch than the one of the item are rejected.
+func (r *itemRepo) LinkPullRequest(owner, repo string, pullNumber int, itemID int) (model.Item, error) {
+	item, err := r.Get(itemID)
+	if err != nil {
+		return model.Item{}, err
+	}
+
+	pr, err := r.gh.GetPullRequest(owner, repo, pullNumber)
+	if err != nil {
+		return model.Item{}, err
+	}
+
+	if pr.State != "open" {
+		return model.Item{}, errors.New(fmt.Sprintf("Pull request #%d is %s", pr.Number, pr.State))
+	}
+	if item.BranchName != "" && item.BranchName != pr.Head.Ref {
+		return model.Item{}, errors.New(fmt.Sprintf("Pull request #%d is for branch '%s', but the item is linked to '%s'", pr.Number, pr.Head.Ref, item.BranchName))
+	}
+
+	item.PullRequestID = pr.Id
+	item.PullRequestNumber = pr.Number
+	if item.BranchName == "" {
+		item.BranchName = pr.Head.Ref
+	}
+
+	return r.db.UpdateItem(itemID, item)
+}
+
+// projectMergeMethod returns the merge method of the project of the item.
+func (r *itemRepo) projectMergeMethod(item model.Item) (string, error) {
+	col, err := r.db.GetColumn(item.ColumnID)
+	if err != nil {
+		return "", err
+	}
+
+	settings, err := r.db.GetProjectSettings(col.ProjectID)
+	if err != nil {
+		return "", err
+	}
+
+	return settings.MergeMethod, nil
+}
+
+func (r *itemRepo) MergePullRequest(owner, repo, title, message, method, sha string, pullNumber int, deleteBranch bool, itemID int) (model.Item, error) {
+	item, err := r.Get(itemID)
+	if err != nil {
+		return model.Item{}, err
+	}
+
+	if method == "" {
+		method, err = r.projectMergeMethod(item)
+		if err != nil {
+			return model.Item{}, err
+		}
+	}
+	if !model.IsMergeMethod(method) {
+		return model.Item{}, errors.New("Invalid merge method")
+	}
+	if sha == "" {
+		return model.Item{}, errors.New("The head SHA of the pull request is required")
+	}
+
+	_, err = r.gh.MergePullRequest(owner, repo, title, message, method, sha, pullNumber)
 	if err != nil {
 		return model.Item{}, err
 	}
 
 	if deleteBranch {
-		err = r.gh.DeleteBranch("TobiasTheDanish", "go-track", item.BranchName)
+		err = r.gh.DeleteBranch(owner, repo, item.BranchName)
 		if err != nil {
 			return model.Item{}, err
 		}
@@ -105,6 +271,46 @@ func (r *itemRepo) MergePullRequest(owner, repo, title, message string, pullNumb
 	return r.db.UpdateItem(itemID, item)
 }
 
+// EnterColumn runs the step of the workflow for the column the item was moved
+// into, as far as it needs no input. An issue is created in the todo column and
+// a draft pull request is marked ready for review in the review column. The
+// step is returned, so the board can ask for what the other steps need.
+func (r *itemRepo) EnterColumn(projID int, item model.Item) (model.Item, string, error) {
+	col, err := r.db.GetColumn(item.ColumnID)
+	if err != nil {
+		return model.Item{}, model.StepNone, err
+	}
+
+	step := model.WorkflowStep(col.Name)
+	if step == model.StepNone {
+		return item, step, nil
+	}
+
+	settings, err := r.db.GetProjectSettings(projID)
+	if err != nil {
+		return model.Item{}, step, err
+	}
+	if !settings.HasRepository() {
+		return model.Item{}, step, ErrNoRepository
+	}
+
+	switch step {
+	case model.StepIssue:
+		if item.IssueID == -1 {
+			item, err = r.CreateIssue(settings.Owner, settings.Repo, item)
+		}
+	case model.StepReview:
+		if item.PullRequestNumber != -1 {
+			item, err = r.MarkReadyForReview(settings.Owner, settings.Repo, item.Id)
+		}
+	}
+	if err != nil {
+		return model.Item{}, step, err
+	}
+
+	return item, step, nil
+}
+
 func (r *itemRepo) Move(projID, itemID int, dir string) (model.Item, error) {
 	item, err := r.db.GetItem(itemID)
 	if err != nil {
@@ -200,21 +406,13 @@ func (h *itemRepo) moveItemRight(projID int, item model.Item) (model.Item, error
 		}
 	}
 
-	if colIndex >= len(proj.Columns)+1 {
+	if colIndex+1 >= len(proj.Columns) {
 		return model.Item{}, errors.New("Could not move item right")
 	}
 
 	newCol := proj.Columns[colIndex+1]
 
-	colOrder, err := h.db.GetNextItemColumnOrder(newCol.Id)
-	if err != nil {
-		return model.Item{}, err
-	}
-
-	item.ColumnID = newCol.Id
-	item.ColumnOrder = colOrder
-
-	return h.db.UpdateItem(item.Id, item)
+	return h.moveToColumn(item, newCol)
 }
 
 func (h *itemRepo) moveItemLeft(projID int, item model.Item) (model.Item, error) {
@@ -237,13 +435,25 @@ func (h *itemRepo) moveItemLeft(projID int, item model.Item) (model.Item, error)
 
 	newCol := proj.Columns[colIndex-1]
 
-	colOrder, err := h.db.GetNextItemColumnOrder(newCol.Id)
+	return h.moveToColumn(item, newCol)
+}
+
+// moveToColumn puts the item last in the column, and counts the transition
+// into the column.
+func (h *itemRepo) moveToColumn(item model.Item, col model.Column) (model.Item, error) {
+	colOrder, err := h.db.GetNextItemColumnOrder(col.Id)
 	if err != nil {
 		return model.Item{}, err
 	}
 
-	item.ColumnID = newCol.Id
+	item.ColumnID = col.Id
 	item.ColumnOrder = colOrder
 
-	return h.db.UpdateItem(item.Id, item)
+	moved, err := h.db.UpdateItem(item.Id, item)
+	if err != nil {
+		return model.Item{}, err
+	}
+	metrics.WorkflowTransitions.WithLabelValues(strings.ToLower(col.Name)).Inc()
+
+	return moved, nil
 }
@@ -0,0 +1,186 @@
+package repo
+
+import (
+	"encoding/json"
+	"go-track/internal/github/githubtest"
+	"go-track/internal/model"
+	"net/http"
+	"testing"
+)
+
+func TestMergePullRequest(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	srv.AddInstallation("TobiasTheDanish", githubtest.AccountUser)
+	ghRepo := srv.AddRepo("TobiasTheDanish", "go-track")
+	ghRepo.AddBranch("feature/1-merge", "main")
+	reviewed := ghRepo.Push("feature/1-merge", "Add merge")
+	pull := ghRepo.AddPull("Add merge", "feature/1-merge", "main")
+
+	db := newMemoryDB()
+	items := NewItemRepo(db, newTestGithub(srv))
+	item, _ := db.AddItemToColumn("Add merge", 1)
+	item.BranchName = "feature/1-merge"
+	item.PullRequestNumber = pull.Number
+	db.UpdateItem(item.Id, item)
+
+	if _, err := items.MergePullRequest("TobiasTheDanish", "go-track", "", "", "fast-forward", reviewed, pull.Number, false, item.Id); err == nil {
+		t.Fatalf("Expected an unknown merge method to be rejected\n")
+	}
+	if _, err := items.MergePullRequest("TobiasTheDanish", "go-track", "", "", model.MergeMethodSquash, "", pull.Number, false, item.Id); err == nil {
+		t.Fatalf("Expected a merge without a head SHA to be rejected\n")
+	}
+
+	ghRepo.Push("feature/1-merge", "Not reviewed")
+	if _, err := items.MergePullRequest("TobiasTheDanish", "go-track", "", "", model.MergeMethodSquash, reviewed, pull.Number, false, item.Id); err == nil {
+		t.Fatalf("Expected a merge of a changed head to be refused\n")
+	}
+	if ghRepo.Pull(pull.Number).Merged {
+		t.Fatalf("Expected the pr to not be merged\n")
+	}
+
+	head := ghRepo.Branch("feature/1-merge")
+	item, err := items.MergePullRequest("TobiasTheDanish", "go-track", "", "", model.MergeMethodRebase, head, pull.Number, false, item.Id)
+	if err != nil {
+		t.Fatalf("Could not merge pr: %s\n", err)
+	}
+	if item.PullRequestNumber != -1 || item.BranchName != "feature/1-merge" {
+		t.Fatalf("Expected the pr to be unlinked and the branch kept, got %+v\n", item)
+	}
+
+	requests := srv.Requests()
+	merge := requests[len(requests)-1]
+	var body struct {
+		MergeMethod string `json:"merge_method"`
+		Sha         string `json:"sha"`
+	}
+	json.Unmarshal(merge.Body, &body)
+	if merge.Method != http.MethodPut || body.MergeMethod != model.MergeMethodRebase || body.Sha != head {
+		t.Fatalf("Expected a rebase of %s, got %s %s\n", head, merge.Method, merge.Body)
+	}
+}
+
+func TestMergePullRequestDefaultsToProjectMethod(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	srv.AddInstallation("TobiasTheDanish", githubtest.AccountUser)
+	ghRepo := srv.AddRepo("TobiasTheDanish", "go-track")
+	ghRepo.AddBranch("feature/1-merge", "main")
+	head := ghRepo.Push("feature/1-merge", "Add merge")
+	pull := ghRepo.AddPull("Add merge", "feature/1-merge", "main")
+
+	db := newMemoryDB()
+	db.columns[1] = model.Column{Id: 1, Name: "Done", ProjectID: 1}
+	settings := model.DefaultProjectSettings(1)
+	settings.MergeMethod = model.MergeMethodSquash
+	db.UpdateProjectSettings(settings)
+
+	items := NewItemRepo(db, newTestGithub(srv))
+	item, _ := db.AddItemToColumn("Add merge", 1)
+	item.BranchName = "feature/1-merge"
+	item.PullRequestNumber = pull.Number
+	db.UpdateItem(item.Id, item)
+
+	if _, err := items.MergePullRequest("TobiasTheDanish", "go-track", "", "", "", head, pull.Number, false, item.Id); err != nil {
+		t.Fatalf("Could not merge pr without a method: %s\n", err)
+	}
+
+	requests := srv.Requests()
+	var body struct {
+		MergeMethod string `json:"merge_method"`
+	}
+	json.Unmarshal(requests[len(requests)-1].Body, &body)
+	if body.MergeMethod != model.MergeMethodSquash {
+		t.Fatalf("Expected the merge method of the project, got %s\n", body.MergeMethod)
+	}
+}
+
+func TestUpdateSettingsMergeMethod(t *testing.T) {
+	projects := NewProjectRepo(newMemoryDB())
+
+	settings := model.DefaultProjectSettings(1)
+	settings.MergeMethod = "fast-forward"
+	if _, err := projects.UpdateSettings(settings); err == nil {
+		t.Fatalf("Expected an unknown merge method to be rejected\n")
+	}
+
+	settings.MergeMethod = model.MergeMethodSquash
+	if _, err := projects.UpdateSettings(settings); err != nil {
+		t.Fatalf("Could not update settings: %s\n", err)
+	}
+
+	saved, err := projects.GetSettings(1)
+	if err != nil {
+		t.Fatalf("Could not get settings: %s\n", err)
+	}
+	if saved.MergeMethod != model.MergeMethodSquash {
+		t.Fatalf("Expected the merge method to be saved, got '%s'\n", saved.MergeMethod)
+	}
+}
+
+func TestCreateBranchWithDraftPullRequest(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	srv.AddInstallation("TobiasTheDanish", githubtest.AccountUser)
+	ghRepo := srv.AddRepo("TobiasTheDanish", "go-track")
+	main := ghRepo.Branch("main")
+
+	db := newMemoryDB()
+	items := NewItemRepo(db, newTestGithub(srv))
+	item, _ := db.AddItemToColumn("Add drafts", 1)
+
+	srv.Fail(http.MethodPost, "/repos/TobiasTheDanish/go-track/pulls", http.StatusUnprocessableEntity, 1)
+	if _, err := items.CreateBranchWithDraftPullRequest("TobiasTheDanish", "go-track", "feature/1-add-drafts", main, "main", item.Id); err == nil {
+		t.Fatalf("Expected the draft pull request to fail\n")
+	}
+	if ghRepo.Branch("feature/1-add-drafts") != "" {
+		t.Fatalf("Expected the branch to be deleted again\n")
+	}
+	if item, _ := db.GetItem(item.Id); item.BranchName != "" {
+		t.Fatalf("Expected the branch to be unlinked, got '%s'\n", item.BranchName)
+	}
+
+	item, err := items.CreateBranchWithDraftPullRequest("TobiasTheDanish", "go-track", "feature/1-add-drafts", main, "main", item.Id)
+	if err != nil {
+		t.Fatalf("Could not create branch with draft: %s\n", err)
+	}
+	if item.BranchName != "feature/1-add-drafts" || item.PullRequestNumber == -1 || !ghRepo.Pull(item.PullRequestNumber).Draft {
+		t.Fatalf("Expected a draft pr for the branch, got %+v\n", item)
+	}
+}
+
+func TestLinkPullRequest(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	srv.AddInstallation("TobiasTheDanish", githubtest.AccountUser)
+	ghRepo := srv.AddRepo("TobiasTheDanish", "go-track")
+	ghRepo.AddBranch("feature/1-link", "main")
+	ghRepo.Push("feature/1-link", "Link")
+	ghRepo.AddBranch("feature/2-other", "main")
+	ghRepo.Push("feature/2-other", "Other")
+	pull := ghRepo.AddPull("Link", "feature/1-link", "main")
+	other := ghRepo.AddPull("Other", "feature/2-other", "main")
+
+	db := newMemoryDB()
+	items := NewItemRepo(db, newTestGithub(srv))
+	item, _ := db.AddItemToColumn("Link", 1)
+
+	item, err := items.LinkPullRequest("TobiasTheDanish", "go-track", pull.Number, item.Id)
+	if err != nil {
+		t.Fatalf("Could not link pr: %s\n", err)
+	}
+	if item.PullRequestNumber != pull.Number || item.BranchName != "feature/1-link" {
+		t.Fatalf("Expected the pr and its branch to be linked, got %+v\n", item)
+	}
+
+	if _, err := items.LinkPullRequest("TobiasTheDanish", "go-track", other.Number, item.Id); err == nil {
+		t.Fatalf("Expected a pr for another branch than the one of the item to be rejected\n")
+	}
+	if item, _ := db.GetItem(item.Id); item.PullRequestNumber != pull.Number {
+		t.Fatalf("Expected the first pr to stay linked, got %+v\n", item)
+	}
+}
@@ -0,0 +1,185 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"go-track/internal/db"
+	"go-track/internal/github"
+	"go-track/internal/model"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// repositoryRoleTTL is how long a role derived from a GitHub permission is
+// cached, as it is checked on every request.
+const repositoryRoleTTL = 5 * time.Minute
+
+type MemberRepository interface {
+	GetRole(projID int, user model.User) (string, error)
+	List(projID int) ([]model.Member, error)
+	Save(member model.Member) (model.Member, error)
+	Remove(projID int, username string) error
+}
+
+type repositoryRole struct {
+	role      string
+	expiresAt time.Time
+}
+
+type memberRepo struct {
+	db db.DatabaseFacade
+	gh github.GithubService
+
+	rolesMu sync.Mutex
+	roles   map[string]repositoryRole
+}
+
+func NewMemberRepo(db db.DatabaseFacade, gh github.GithubService) MemberRepository {
+	return &memberRepo{
+		db:    db,
+		gh:    gh,
+		roles: make(map[string]repositoryRole),
+	}
+}
+
+// IsInstanceAdmin reports whether the user is listed in GO_TRACK_ADMINS. These
+// users are admins of every project, so projects without members can be managed.
+func IsInstanceAdmin(username string) bool {
+	for _, admin := range strings.Split(os.Getenv("GO_TRACK_ADMINS"), ",") {
+		if strings.EqualFold(strings.TrimSpace(admin), username) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetRole returns the role of the user in the project, or an empty string when
+// the user has no access. The role is the highest of the membership of the user
+// and, if enabled for the project, the permission of the user on the repository.
+// When GitHub can not be asked for the permission, only the membership counts.
+// Memberships are looked up by the id of the user, so a member who renamed
+// their GitHub account does not pass their role on to whoever takes the login.
+func (r *memberRepo) GetRole(projID int, user model.User) (string, error) {
+	username := user.Login
+	if IsInstanceAdmin(username) {
+		return model.RoleAdmin, nil
+	}
+
+	member, err := r.db.GetProjectMember(projID, user)
+	if err != nil {
+		return "", err
+	}
+
+	settings, err := r.db.GetProjectSettings(projID)
+	if err != nil {
+		return "", err
+	}
+
+	if !settings.RolesFromRepository || !settings.HasRepository() || member.Role == model.RoleAdmin {
+		return member.Role, nil
+	}
+
+	repoRole, err := r.repositoryRole(settings, username)
+	if err != nil {
+		log.Printf("Getting the repository role of %s failed: %s\n", username, err)
+		return member.Role, nil
+	}
+
+	return model.HighestRole(member.Role, repoRole), nil
+}
+
+func (r *memberRepo) repositoryRole(settings model.ProjectSettings, username string) (string, error) {
+	key := strings.ToLower(fmt.Sprintf("%s/%s/%s", settings.Owner, settings.Repo, username))
+
+	r.rolesMu.Lock()
+	cached, ok := r.roles[key]
+	r.rolesMu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.role, nil
+	}
+
+	permission, err := r.gh.GetCollaboratorPermission(settings.Owner, settings.Repo, username)
+	if err != nil {
+		return "", err
+	}
+
+	role := ""
+	switch permission.Permission {
+	case "admin":
+		role = model.RoleAdmin
+	case "write":
+		role = model.RoleEditor
+	case "read":
+		role = model.RoleViewer
+	}
+
+	r.rolesMu.Lock()
+	r.roles[key] = repositoryRole{role: role, expiresAt: time.Now().Add(repositoryRoleTTL)}
+	r.rolesMu.Unlock()
+
+	return role, nil
+}
+
+func (r *memberRepo) List(projID int) ([]model.Member, error) {
+	return r.db.GetProjectMembers(projID)
+}
+
+func (r *memberRepo) Save(member model.Member) (model.Member, error) {
+	member.Username = strings.TrimSpace(member.Username)
+	if member.Username == "" {
+		return model.Member{}, errors.New("Username is required")
+	}
+	if !model.IsRole(member.Role) {
+		return model.Member{}, errors.New("Invalid role")
+	}
+
+	if member.Role != model.RoleAdmin {
+		err := r.keepAnAdmin(member.ProjectID, member.Username)
+		if err != nil {
+			return model.Member{}, err
+		}
+	}
+
+	return r.db.SaveProjectMember(member)
+}
+
+func (r *memberRepo) Remove(projID int, username string) error {
+	err := r.keepAnAdmin(projID, username)
+	if err != nil {
+		return err
+	}
+
+	return r.db.DeleteProjectMember(projID, username)
+}
+
+// keepAnAdmin fails when username is the last admin of the project, so a
+// project can not be left without anyone able to manage it.
+func (r *memberRepo) keepAnAdmin(projID int, username string) error {
+	members, err := r.db.GetProjectMembers(projID)
+	if err != nil {
+		return err
+	}
+
+	isAdmin := false
+	otherAdmins := 0
+	for _, m := range members {
+		if m.Role != model.RoleAdmin {
+			continue
+		}
+		if strings.EqualFold(m.Username, username) {
+			isAdmin = true
+		} else {
+			otherAdmins++
+		}
+	}
+
+	if isAdmin && otherAdmins == 0 {
+		return errors.New(fmt.Sprintf("%s is the last admin of the project", username))
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package repo
+
+import (
+	"go-track/internal/github/githubtest"
+	"go-track/internal/model"
+	"net/http"
+	"testing"
+)
+
+func TestGetRole(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	srv.AddInstallation("go-track-org", githubtest.AccountOrganization)
+	ghRepo := srv.AddRepo("go-track-org", "board")
+	srv.AddUser("writer")
+	srv.AddUser("reader", "go-track-org")
+	srv.AddUser("outsider")
+	ghRepo.AddCollaborator("writer", "write")
+
+	db := newMemoryDB()
+	members := NewMemberRepo(db, newTestGithub(srv))
+	t.Setenv("GO_TRACK_ADMINS", "root")
+
+	if _, err := members.Save(model.Member{ProjectID: 1, Username: "reader", Role: model.RoleAdmin}); err != nil {
+		t.Fatalf("Could not save member: %s\n", err)
+	}
+
+	expectRole := func(username, expected string) {
+		t.Helper()
+
+		role, err := members.GetRole(1, model.User{Login: username})
+		if err != nil {
+			t.Fatalf("Could not get role of %s: %s\n", username, err)
+		}
+		if role != expected {
+			t.Fatalf("Expected %s to be '%s', got '%s'\n", username, expected, role)
+		}
+	}
+
+	expectRole("root", model.RoleAdmin)
+	expectRole("reader", model.RoleAdmin)
+	expectRole("writer", "")
+
+	db.UpdateProjectSettings(model.ProjectSettings{ProjectID: 1, Owner: "go-track-org", Repo: "board", RolesFromRepository: true})
+
+	expectRole("writer", model.RoleEditor)
+	expectRole("outsider", "")
+
+	if _, err := members.Save(model.Member{ProjectID: 1, Username: "viewer", Role: model.RoleViewer}); err != nil {
+		t.Fatalf("Could not save member: %s\n", err)
+	}
+	srv.Fail(http.MethodGet, "/repos/go-track-org/board/collaborators/viewer/permission", http.StatusBadGateway, 1)
+	expectRole("viewer", model.RoleViewer)
+
+	if err := members.Remove(1, "reader"); err == nil {
+		t.Fatalf("Expected removing the last admin to fail\n")
+	}
+	if _, err := members.Save(model.Member{ProjectID: 1, Username: "reader", Role: model.RoleViewer}); err == nil {
+		t.Fatalf("Expected demoting the last admin to fail\n")
+	}
+}
@@ -0,0 +1,151 @@
+package repo
+
+import (
+	"fmt"
+	"go-track/internal/github"
+	"go-track/internal/model"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type PullRequestRepository interface {
+	GetMergeReadiness(owner, repo string, pullNumber int) (model.MergeReadiness, error)
+	Search(owner, repo, query string) ([]model.PullRequest, error)
+}
+
+type pullRequestRepo struct {
+	gh github.GithubService
+}
+
+func NewPullRequestRepo(gh github.GithubService) PullRequestRepository {
+	return &pullRequestRepo{
+		gh: gh,
+	}
+}
+
+func (r *pullRequestRepo) GetMergeReadiness(owner, repo string, pullNumber int) (model.MergeReadiness, error) {
+	pr, err := r.gh.GetPullRequest(owner, repo, pullNumber)
+	if err != nil {
+		return model.MergeReadiness{}, err
+	}
+
+	reviews, err := r.gh.GetPullRequestReviews(owner, repo, pullNumber)
+	if err != nil {
+		return model.MergeReadiness{}, err
+	}
+
+	base, err := r.gh.GetBranch(owner, repo, pr.Base.Ref)
+	if err != nil {
+		return model.MergeReadiness{}, err
+	}
+
+	checks, err := getCheckStatus(r.gh, owner, repo, pr.Head.Sha)
+	if err != nil {
+		return model.MergeReadiness{}, err
+	}
+
+	reasons := make([]string, 0)
+	if pr.State != "open" {
+		reasons = append(reasons, fmt.Sprintf("The pull request is %s.", pr.State))
+	}
+	if pr.Mergeable != nil && !*pr.Mergeable {
+		reasons = append(reasons, fmt.Sprintf("The branch has conflicts with '%s' that must be resolved.", pr.Base.Ref))
+	}
+	reasons = append(reasons, reviewBlockedReasons(reviews)...)
+	reasons = append(reasons, requiredCheckBlockedReasons(base.Protection.RequiredStatusChecks.Contexts, checks)...)
+
+	return model.MergeReadiness{
+		HeadSha:        pr.Head.Sha,
+		Checks:         checks,
+		BlockedReasons: reasons,
+	}, nil
+}
+
+// Search returns the open pull requests where the query matches the number,
+// title or head branch.
+func (r *pullRequestRepo) Search(owner, repo, query string) ([]model.PullRequest, error) {
+	prs, err := r.gh.ListPullRequests(owner, repo, "open")
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(query), "#"))
+	matches := make([]model.PullRequest, 0)
+	for _, pr := range prs {
+		if strings.Contains(strconv.Itoa(pr.Number), query) ||
+			strings.Contains(strings.ToLower(pr.Title), query) ||
+			strings.Contains(strings.ToLower(pr.Head.Ref), query) {
+			matches = append(matches, model.PullRequest{
+				Id:         pr.Id,
+				Number:     pr.Number,
+				Title:      pr.Title,
+				HeadBranch: pr.Head.Ref,
+				Url:        pr.Url,
+			})
+		}
+	}
+
+	return matches, nil
+}
+
+// reviewBlockedReasons uses the latest review of every reviewer, as GitHub does,
+// and requires at least one approval and no outstanding change requests.
+func reviewBlockedReasons(reviews []github.ReviewDTO) []string {
+	latest := make(map[string]string)
+	for _, review := range reviews {
+		switch review.State {
+		case "APPROVED", "CHANGES_REQUESTED", "DISMISSED":
+			latest[review.User.Login] = review.State
+		}
+	}
+
+	logins := make([]string, 0, len(latest))
+	for login := range latest {
+		logins = append(logins, login)
+	}
+	sort.Strings(logins)
+
+	reasons := make([]string, 0)
+	approved := false
+	for _, login := range logins {
+		switch latest[login] {
+		case "APPROVED":
+			approved = true
+		case "CHANGES_REQUESTED":
+			reasons = append(reasons, fmt.Sprintf("%s has requested changes.", login))
+		}
+	}
+
+	if !approved {
+		reasons = append(reasons, "The pull request has not been approved.")
+	}
+
+	return reasons
+}
+
+func requiredCheckBlockedReasons(required []string, status model.CheckStatus) []string {
+	reasons := make([]string, 0)
+	for _, name := range required {
+		state := ""
+		for _, check := range status.Checks {
+			if check.Name == name {
+				state = check.State
+				break
+			}
+		}
+
+		switch state {
+		case model.CheckStateSuccess:
+			continue
+		case model.CheckStateFailure:
+			reasons = append(reasons, fmt.Sprintf("Required check '%s' is failing.", name))
+		case model.CheckStatePending:
+			reasons = append(reasons, fmt.Sprintf("Required check '%s' is still running.", name))
+		default:
+			reasons = append(reasons, fmt.Sprintf("Required check '%s' has not been reported.", name))
+		}
+	}
+
+	return reasons
+}
@@ -0,0 +1,103 @@
+package repo
+
+import (
+	"go-track/internal/github"
+	"go-track/internal/github/githubtest"
+	"go-track/internal/model"
+	"slices"
+	"testing"
+)
+
+func review(login, state string) github.ReviewDTO {
+	var r github.ReviewDTO
+	r.User.Login = login
+	r.State = state
+	return r
+}
+
+func TestReviewBlockedReasons(t *testing.T) {
+	tests := []struct {
+		reviews  []github.ReviewDTO
+		expected []string
+	}{
+		{nil, []string{"The pull request has not been approved."}},
+		{[]github.ReviewDTO{review("alice", "COMMENTED")}, []string{"The pull request has not been approved."}},
+		{[]github.ReviewDTO{review("alice", "APPROVED")}, []string{}},
+		{
+			[]github.ReviewDTO{review("alice", "APPROVED"), review("bob", "CHANGES_REQUESTED")},
+			[]string{"bob has requested changes."},
+		},
+		{
+			// the latest review of a reviewer counts, and comments do not replace it
+			[]github.ReviewDTO{review("bob", "CHANGES_REQUESTED"), review("bob", "APPROVED"), review("bob", "COMMENTED")},
+			[]string{},
+		},
+		{
+			[]github.ReviewDTO{review("alice", "APPROVED"), review("alice", "DISMISSED")},
+			[]string{"The pull request has not been approved."},
+		},
+	}
+
+	for _, test := range tests {
+		if reasons := reviewBlockedReasons(test.reviews); !slices.Equal(reasons, test.expected) {
+			t.Errorf("Expected %v to be blocked by %v, got %v\n", test.reviews, test.expected, reasons)
+		}
+	}
+}
+
+func TestRequiredCheckBlockedReasons(t *testing.T) {
+	status := model.CheckStatus{
+		Checks: []model.Check{
+			{Name: "build", State: model.CheckStateSuccess},
+			{Name: "test", State: model.CheckStateFailure},
+			{Name: "lint", State: model.CheckStatePending},
+			{Name: "optional", State: model.CheckStateFailure},
+		},
+	}
+
+	reasons := requiredCheckBlockedReasons([]string{"build", "test", "lint", "deploy"}, status)
+	expected := []string{
+		"Required check 'test' is failing.",
+		"Required check 'lint' is still running.",
+		"Required check 'deploy' has not been reported.",
+	}
+	if !slices.Equal(reasons, expected) {
+		t.Fatalf("Expected %v, got %v\n", expected, reasons)
+	}
+
+	if reasons := requiredCheckBlockedReasons(nil, status); len(reasons) != 0 {
+		t.Fatalf("Expected failing checks that are not required to not block, got %v\n", reasons)
+	}
+}
+
+func TestMergeReadinessConflicts(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	srv.AddInstallation("TobiasTheDanish", githubtest.AccountUser)
+	ghRepo := srv.AddRepo("TobiasTheDanish", "go-track")
+	ghRepo.AddBranch("feature/1-conflicts", "main")
+	ghRepo.Push("feature/1-conflicts", "Change everything")
+	pull := ghRepo.AddPull("Change everything", "feature/1-conflicts", "main")
+	ghRepo.AddReview(pull.Number, "reviewer", "APPROVED")
+	prs := NewPullRequestRepo(newTestGithub(srv))
+
+	readiness, err := prs.GetMergeReadiness("TobiasTheDanish", "go-track", pull.Number)
+	if err != nil {
+		t.Fatalf("Could not get merge readiness: %s\n", err)
+	}
+	if readiness.Blocked() {
+		t.Fatalf("Expected the merge to be ready, got %v\n", readiness.BlockedReasons)
+	}
+
+	ghRepo.SetMergeable(pull.Number, false)
+
+	readiness, err = prs.GetMergeReadiness("TobiasTheDanish", "go-track", pull.Number)
+	if err != nil {
+		t.Fatalf("Could not get merge readiness: %s\n", err)
+	}
+	expected := []string{"The branch has conflicts with 'main' that must be resolved."}
+	if !slices.Equal(readiness.BlockedReasons, expected) {
+		t.Fatalf("Expected %v, got %v\n", expected, readiness.BlockedReasons)
+	}
+}
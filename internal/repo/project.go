@@ -1,12 +1,17 @@
 package repo
 
 import (
+	"errors"
 	"go-track/internal/db"
 	"go-track/internal/model"
+	"strings"
 )
 
 type ProjectRepository interface {
 	GetProject(id int) (model.Project, error)
+	GetAll() ([]model.Project, error)
+	GetSettings(id int) (model.ProjectSettings, error)
+	UpdateSettings(settings model.ProjectSettings) (model.ProjectSettings, error)
 }
 
 type projectRepo struct {
@@ -22,3 +27,23 @@ func NewProjectRepo(db db.DatabaseFacade) ProjectRepository {
 func (r *projectRepo) GetProject(id int) (model.Project, error) {
 	return r.db.GetProject(id)
 }
+
+func (r *projectRepo) GetSettings(id int) (model.ProjectSettings, error) {
+	return r.db.GetProjectSettings(id)
+}
+
+func (r *projectRepo) UpdateSettings(settings model.ProjectSettings) (model.ProjectSettings, error) {
+	if !model.IsMergeMethod(settings.MergeMethod) {
+		return model.ProjectSettings{}, errors.New("Invalid merge method")
+	}
+
+	if !strings.Contains(settings.BranchTemplate, "{slug}") && !strings.Contains(settings.BranchTemplate, "{item_id}") {
+		return model.ProjectSettings{}, errors.New("Branch template must contain {slug} or {item_id}, so branch names are unique")
+	}
+
+	return r.db.UpdateProjectSettings(settings)
+}
+
+func (r *projectRepo) GetAll() ([]model.Project, error) {
+	return r.db.GetProjects()
+}
@@ -0,0 +1,184 @@
+package repo
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"go-track/internal/auth"
+	"go-track/internal/db"
+	"go-track/internal/model"
+	"time"
+)
+
+// sessionTouchInterval limits how often the last seen time of a session is
+// written, as sessions are validated on every request.
+const sessionTouchInterval = 5 * time.Minute
+
+var errSessionRevoked = errors.New("The session has been signed out")
+
+type SessionRepository interface {
+	Create(user model.User, userAgent string) (string, error)
+	Validate(jwtString string) (auth.AuthSession, string, error)
+	List(user model.User) ([]model.Session, error)
+	Revoke(user model.User, sessionID string) error
+	RevokeAll(user model.User) error
+}
+
+type sessionRepo struct {
+	db db.DatabaseFacade
+}
+
+func NewSessionRepo(db db.DatabaseFacade) SessionRepository {
+	return &sessionRepo{
+		db: db,
+	}
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Create stores a new session for the user and returns the signed value of the
+// session cookie.
+func (r *sessionRepo) Create(user model.User, userAgent string) (string, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	err = r.db.DeleteSessionsExpiredBefore(now)
+	if err != nil {
+		return "", err
+	}
+
+	expiresAt := now.Add(auth.SessionLifetime())
+	err = r.db.CreateSession(model.Session{
+		Id:         id,
+		UserID:     user.Id,
+		Username:   user.Login,
+		UserAgent:  userAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  expiresAt,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return auth.SignAuthSession(auth.AuthSession{
+		SessionID: id,
+		UserID:    user.Id,
+		Username:  user.Login,
+	}, expiresAt)
+}
+
+// Validate checks the signature of the session cookie and that the session has
+// not been revoked or expired. Sessions past half of their lifetime are renewed,
+// the renewed value of the session cookie is returned when that happens.
+func (r *sessionRepo) Validate(jwtString string) (auth.AuthSession, string, error) {
+	session, err := auth.ParseAuthJWT(jwtString)
+	if err != nil {
+		return auth.AuthSession{}, "", err
+	}
+
+	if session.SessionID == "" {
+		return auth.AuthSession{}, "", errSessionRevoked
+	}
+
+	stored, err := r.db.GetSession(session.SessionID)
+	if err != nil {
+		return auth.AuthSession{}, "", errors.Join(errSessionRevoked, err)
+	}
+
+	now := time.Now()
+	if !stored.Active(now) || !session.User().Owns(stored.UserID, stored.Username) {
+		return auth.AuthSession{}, "", errSessionRevoked
+	}
+
+	if now.Sub(stored.LastSeenAt) > sessionTouchInterval {
+		err = r.db.TouchSession(stored.Id, now)
+		if err != nil {
+			return auth.AuthSession{}, "", err
+		}
+
+		if stored.UserID != 0 {
+			err = r.db.TouchUser(stored.UserID, now)
+			if err != nil {
+				return auth.AuthSession{}, "", err
+			}
+		}
+	}
+
+	lifetime := auth.SessionLifetime()
+	if stored.ExpiresAt.Sub(now) > lifetime/2 {
+		return session, "", nil
+	}
+
+	expiresAt := now.Add(lifetime)
+	err = r.db.ExtendSession(stored.Id, expiresAt)
+	if err != nil {
+		return auth.AuthSession{}, "", err
+	}
+
+	renewed, err := auth.SignAuthSession(session, expiresAt)
+	if err != nil {
+		return auth.AuthSession{}, "", err
+	}
+
+	return session, renewed, nil
+}
+
+func (r *sessionRepo) List(user model.User) ([]model.Session, error) {
+	sessions, err := r.db.GetSessionsForUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	active := make([]model.Session, 0, len(sessions))
+	for _, s := range sessions {
+		if s.Active(now) {
+			active = append(active, s)
+		}
+	}
+
+	return active, nil
+}
+
+// Revoke signs out one of the sessions of the user.
+func (r *sessionRepo) Revoke(user model.User, sessionID string) error {
+	session, err := r.db.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if !user.Owns(session.UserID, session.Username) {
+		return errors.New("The session belongs to another user")
+	}
+
+	return r.db.RevokeSession(sessionID, time.Now())
+}
+
+// RevokeAll signs the user out everywhere. The API tokens of the user are
+// revoked and the GitHub token is deleted as well, so nothing can act as the
+// user until they sign in again.
+func (r *sessionRepo) RevokeAll(user model.User) error {
+	now := time.Now()
+	err := r.db.RevokeSessionsForUser(user, now)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.RevokeAPITokensForUser(user, now)
+	if err != nil {
+		return err
+	}
+
+	return r.db.DeleteUserToken(user)
+}
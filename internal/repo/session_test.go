@@ -0,0 +1,95 @@
+package repo
+
+import (
+	"go-track/internal/model"
+	"testing"
+	"time"
+)
+
+func TestSessionRevocation(t *testing.T) {
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("JWT_ISSUER", "go-track")
+
+	db := newMemoryDB()
+	sessions := NewSessionRepo(db)
+	user := model.User{Id: 1, Login: "octocat"}
+
+	first, err := sessions.Create(user, "laptop")
+	if err != nil {
+		t.Fatalf("Could not create session: %s\n", err)
+	}
+	second, err := sessions.Create(user, "phone")
+	if err != nil {
+		t.Fatalf("Could not create session: %s\n", err)
+	}
+
+	session, _, err := sessions.Validate(first)
+	if err != nil {
+		t.Fatalf("Expected session to be valid: %s\n", err)
+	}
+
+	if err := sessions.Revoke(model.User{Id: 2, Login: "someone-else"}, session.SessionID); err == nil {
+		t.Fatal("Expected revoking the session of another user to fail")
+	}
+
+	if err := sessions.Revoke(user, session.SessionID); err != nil {
+		t.Fatalf("Could not revoke session: %s\n", err)
+	}
+	if _, _, err := sessions.Validate(first); err == nil {
+		t.Fatal("Expected revoked session to be rejected")
+	}
+	if _, _, err := sessions.Validate(second); err != nil {
+		t.Fatalf("Expected other session to stay valid: %s\n", err)
+	}
+
+	db.SaveUserToken(model.UserToken{UserID: 1, Username: "octocat", AccessToken: "token"})
+	db.CreateAPIToken(model.APIToken{Username: "octocat", Name: "script"})
+	db.CreateAPIToken(model.APIToken{Username: "someone-else", Name: "script"})
+	if err := sessions.RevokeAll(user); err != nil {
+		t.Fatalf("Could not revoke sessions: %s\n", err)
+	}
+	if _, _, err := sessions.Validate(second); err == nil {
+		t.Fatal("Expected every session to be rejected")
+	}
+	if _, ok := db.tokens[1]; ok {
+		t.Fatal("Expected the GitHub token to be deleted")
+	}
+	if db.apiTokens[0].RevokedAt.IsZero() {
+		t.Fatal("Expected the API tokens of the user to be revoked")
+	}
+	if !db.apiTokens[1].RevokedAt.IsZero() {
+		t.Fatal("Expected the API tokens of other users to be kept")
+	}
+}
+
+func TestSessionRenewal(t *testing.T) {
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("JWT_SESSION_LIFETIME", "1h")
+
+	db := newMemoryDB()
+	sessions := NewSessionRepo(db)
+
+	jwtString, err := sessions.Create(model.User{Id: 1, Login: "octocat"}, "laptop")
+	if err != nil {
+		t.Fatalf("Could not create session: %s\n", err)
+	}
+
+	session, renewed, err := sessions.Validate(jwtString)
+	if err != nil || renewed != "" {
+		t.Fatalf("Expected a new session to be valid without renewal: %q %s\n", renewed, err)
+	}
+
+	halfway := time.Now().Add(20 * time.Minute)
+	db.ExtendSession(session.SessionID, halfway)
+
+	_, renewed, err = sessions.Validate(jwtString)
+	if err != nil || renewed == "" {
+		t.Fatalf("Expected the session to be renewed: %s\n", err)
+	}
+	if !db.sessions[session.SessionID].ExpiresAt.After(halfway.Add(30 * time.Minute)) {
+		t.Fatal("Expected the stored session to be extended")
+	}
+	if _, _, err := sessions.Validate(renewed); err != nil {
+		t.Fatalf("Expected the renewed cookie to be valid: %s\n", err)
+	}
+}
@@ -0,0 +1,130 @@
+package repo
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-track/internal/db"
+	"go-track/internal/model"
+	"strings"
+	"time"
+)
+
+// apiTokenPrefix makes tokens easy to recognise, for example by secret scanners.
+const apiTokenPrefix = "gt_"
+
+var errInvalidAPIToken = errors.New("The API token is invalid, expired or revoked")
+
+type APITokenRepository interface {
+	Create(token model.APIToken, lifetimeDays int) (string, model.APIToken, error)
+	Validate(secret string) (model.APIToken, error)
+	List(user model.User) ([]model.APIToken, error)
+	Revoke(user model.User, id int) error
+}
+
+type apiTokenRepo struct {
+	db db.DatabaseFacade
+}
+
+func NewAPITokenRepo(db db.DatabaseFacade) APITokenRepository {
+	return &apiTokenRepo{
+		db: db,
+	}
+}
+
+func hashAPIToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func isAPITokenLifetime(days int) bool {
+	for _, d := range model.APITokenLifetimes {
+		if d == days {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Create stores a new token and returns its secret, which is not stored and
+// can not be shown again.
+func (r *apiTokenRepo) Create(token model.APIToken, lifetimeDays int) (string, model.APIToken, error) {
+	token.Name = strings.TrimSpace(token.Name)
+	if token.Name == "" {
+		return "", model.APIToken{}, errors.New("Give the token a name")
+	}
+	if !model.IsScope(token.Scope) {
+		return "", model.APIToken{}, errors.New(fmt.Sprintf("Unknown scope: '%s'", token.Scope))
+	}
+	if !isAPITokenLifetime(lifetimeDays) {
+		return "", model.APIToken{}, errors.New(fmt.Sprintf("Tokens can not expire in %d days", lifetimeDays))
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", model.APIToken{}, err
+	}
+	secret := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	now := time.Now()
+	token.TokenHash = hashAPIToken(secret)
+	token.CreatedAt = now
+	token.ExpiresAt = now.AddDate(0, 0, lifetimeDays)
+
+	created, err := r.db.CreateAPIToken(token)
+	if err != nil {
+		return "", model.APIToken{}, err
+	}
+
+	return secret, created, nil
+}
+
+func (r *apiTokenRepo) Validate(secret string) (model.APIToken, error) {
+	if !strings.HasPrefix(secret, apiTokenPrefix) {
+		return model.APIToken{}, errInvalidAPIToken
+	}
+
+	token, err := r.db.GetAPITokenByHash(hashAPIToken(secret))
+	if err != nil {
+		return model.APIToken{}, errors.Join(errInvalidAPIToken, err)
+	}
+
+	now := time.Now()
+	if !token.Active(now) {
+		return model.APIToken{}, errInvalidAPIToken
+	}
+
+	if now.Sub(token.LastUsedAt) > sessionTouchInterval {
+		err = r.db.TouchAPIToken(token.Id, now)
+		if err != nil {
+			return model.APIToken{}, err
+		}
+	}
+
+	return token, nil
+}
+
+func (r *apiTokenRepo) List(user model.User) ([]model.APIToken, error) {
+	tokens, err := r.db.GetAPITokensForUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	active := make([]model.APIToken, 0, len(tokens))
+	for _, t := range tokens {
+		if t.Active(now) {
+			active = append(active, t)
+		}
+	}
+
+	return active, nil
+}
+
+func (r *apiTokenRepo) Revoke(user model.User, id int) error {
+	return r.db.RevokeAPIToken(id, user, time.Now())
+}
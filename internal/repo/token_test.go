@@ -0,0 +1,47 @@
+package repo
+
+import (
+	"go-track/internal/model"
+	"testing"
+)
+
+func TestAPITokens(t *testing.T) {
+	db := newMemoryDB()
+	tokens := NewAPITokenRepo(db)
+
+	secret, created, err := tokens.Create(model.APIToken{UserID: 1, Username: "octocat", Name: "ci", ProjectID: 1, Scope: model.ScopeRead}, 30)
+	if err != nil {
+		t.Fatalf("Could not create token: %s\n", err)
+	}
+	if created.TokenHash == secret || created.TokenHash == "" {
+		t.Fatal("Expected only the hash of the token to be stored")
+	}
+
+	token, err := tokens.Validate(secret)
+	if err != nil {
+		t.Fatalf("Expected token to be valid: %s\n", err)
+	}
+	if token.Username != "octocat" || token.MaxRole() != model.RoleViewer {
+		t.Fatalf("Unexpected token: %+v\n", token)
+	}
+
+	if _, err := tokens.Validate(secret + "x"); err == nil {
+		t.Fatal("Expected unknown token to be rejected")
+	}
+	if _, _, err := tokens.Create(model.APIToken{Username: "octocat", Name: "ci", ProjectID: 1, Scope: model.ScopeRead}, 10000); err == nil {
+		t.Fatal("Expected unsupported lifetime to be rejected")
+	}
+
+	if err := tokens.Revoke(model.User{Id: 2, Login: "someone-else"}, token.Id); err == nil {
+		t.Fatal("Expected revoking the token of another user to fail")
+	}
+	if err := tokens.Revoke(model.User{Id: 3, Login: "octocat"}, token.Id); err == nil {
+		t.Fatal("Expected revoking the token of another user with the same login to fail")
+	}
+	if err := tokens.Revoke(model.User{Id: 1, Login: "octocat"}, token.Id); err != nil {
+		t.Fatalf("Could not revoke token: %s\n", err)
+	}
+	if _, err := tokens.Validate(secret); err == nil {
+		t.Fatal("Expected revoked token to be rejected")
+	}
+}
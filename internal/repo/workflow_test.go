@@ -0,0 +1,80 @@
+package repo
+
+import (
+	"go-track/internal/github/githubtest"
+	"go-track/internal/model"
+	"testing"
+)
+
+func TestItemWorkflow(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	srv.AddInstallation("TobiasTheDanish", githubtest.AccountUser)
+	ghRepo := srv.AddRepo("TobiasTheDanish", "go-track")
+	ghRepo.Protect("main", "ci/build")
+
+	gh := newTestGithub(srv)
+	db := newMemoryDB()
+	items := NewItemRepo(db, gh)
+	branches := NewBranchRepo(gh)
+	prs := NewPullRequestRepo(gh)
+
+	item, _ := db.AddItemToColumn("Add login page", 1)
+
+	item, err := items.CreateIssue("TobiasTheDanish", "go-track", item)
+	if err != nil {
+		t.Fatalf("Could not create issue: %s\n", err)
+	}
+
+	main, err := branches.Get("TobiasTheDanish", "go-track", "main")
+	if err != nil {
+		t.Fatalf("Could not get main: %s\n", err)
+	}
+	item, err = items.CreateBranch("TobiasTheDanish", "go-track", "feature/1-add-login-page", main.Sha, item.Id)
+	if err != nil {
+		t.Fatalf("Could not create branch: %s\n", err)
+	}
+
+	item, err = items.StartDraftPullRequest("TobiasTheDanish", "go-track", "main", item.Id)
+	if err != nil {
+		t.Fatalf("Could not start draft pr: %s\n", err)
+	}
+	if item.PullRequestNumber != 1 || item.IssueNumber != -1 {
+		t.Fatalf("Expected issue #1 to become pr #1, got %+v\n", item)
+	}
+
+	if _, err := items.MarkReadyForReview("TobiasTheDanish", "go-track", item.Id); err != nil {
+		t.Fatalf("Could not mark ready for review: %s\n", err)
+	}
+
+	readiness, err := prs.GetMergeReadiness("TobiasTheDanish", "go-track", item.PullRequestNumber)
+	if err != nil {
+		t.Fatalf("Could not get merge readiness: %s\n", err)
+	}
+	if len(readiness.BlockedReasons) != 2 {
+		t.Fatalf("Expected the merge to be blocked on approval and 'ci/build', got %v\n", readiness.BlockedReasons)
+	}
+
+	ghRepo.AddReview(item.PullRequestNumber, "reviewer", "APPROVED")
+	ghRepo.AddStatus(readiness.HeadSha, githubtest.Status{Context: "ci/build", State: "success"})
+
+	readiness, err = prs.GetMergeReadiness("TobiasTheDanish", "go-track", item.PullRequestNumber)
+	if err != nil {
+		t.Fatalf("Could not get merge readiness: %s\n", err)
+	}
+	if readiness.Blocked() {
+		t.Fatalf("Expected the merge to be ready, got %v\n", readiness.BlockedReasons)
+	}
+
+	item, err = items.MergePullRequest("TobiasTheDanish", "go-track", "Add login page", "", model.MergeMethodSquash, readiness.HeadSha, item.PullRequestNumber, true, item.Id)
+	if err != nil {
+		t.Fatalf("Could not merge pr: %s\n", err)
+	}
+	if item.PullRequestNumber != -1 || item.BranchName != "" {
+		t.Fatalf("Expected the pr and branch to be unlinked, got %+v\n", item)
+	}
+	if !ghRepo.Pull(1).Merged || ghRepo.Branch("feature/1-add-login-page") != "" {
+		t.Fatalf("Expected the pr to be merged and the branch deleted\n")
+	}
+}
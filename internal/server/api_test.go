@@ -0,0 +1,514 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"go-track/cmd/web"
+	"go-track/internal/db"
+	"go-track/internal/github"
+	"go-track/internal/github/githubtest"
+	"go-track/internal/model"
+	"go-track/internal/repo"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// boardDB keeps two projects in memory, the first with a backlog and a todo
+// column. Methods the API does not use are left to the nil facade.
+type boardDB struct {
+	db.DatabaseFacade
+
+	columns   map[int]model.Column
+	items     map[int]model.Item
+	settings  map[int]model.ProjectSettings
+	members   map[int][]model.Member
+	apiTokens []model.APIToken
+
+	sessions   map[string]model.Session
+	userTokens map[string]model.UserToken
+	revoked    []string
+}
+
+func newBoardDB() *boardDB {
+	return &boardDB{
+		columns: map[int]model.Column{
+			1: {Id: 1, Name: "Backlog", ProjectID: 1},
+			2: {Id: 2, Name: "Todo", ProjectID: 1},
+			3: {Id: 3, Name: "Backlog", ProjectID: 2},
+		},
+		items: map[int]model.Item{
+			1: {Id: 1, Name: "Add login page", ColumnID: 1, IssueID: -1, IssueNumber: -1, PullRequestID: -1, PullRequestNumber: -1},
+			2: {Id: 2, Name: "Add sign up page", ColumnID: 1, ColumnOrder: 1, IssueID: -1, IssueNumber: -1, PullRequestID: -1, PullRequestNumber: -1},
+			3: {Id: 3, Name: "Secret plans", ColumnID: 3, IssueID: -1, IssueNumber: -1, PullRequestID: -1, PullRequestNumber: -1},
+		},
+		settings:   make(map[int]model.ProjectSettings),
+		sessions:   make(map[string]model.Session),
+		userTokens: make(map[string]model.UserToken),
+		members: map[int][]model.Member{
+			1: {
+				{ProjectID: 1, Username: "editor", Role: model.RoleEditor},
+				{ProjectID: 1, Username: "viewer", Role: model.RoleViewer},
+			},
+		},
+	}
+}
+
+func (m *boardDB) GetProjects() ([]model.Project, error) {
+	return []model.Project{{Id: 1, Name: "go-track"}, {Id: 2, Name: "secret"}}, nil
+}
+
+func (m *boardDB) GetProject(id int) (model.Project, error) {
+	cols, _ := m.GetColumnsForProject(id)
+	return model.Project{Id: id, Name: "go-track", Columns: cols}, nil
+}
+
+func (m *boardDB) GetProjectSettings(projectID int) (model.ProjectSettings, error) {
+	settings, ok := m.settings[projectID]
+	if !ok {
+		return model.DefaultProjectSettings(projectID), nil
+	}
+
+	return settings, nil
+}
+
+func (m *boardDB) GetColumnsForProject(projectID int) ([]model.Column, error) {
+	cols := make([]model.Column, 0)
+	for id := 1; id <= len(m.columns); id++ {
+		if col := m.columns[id]; col.ProjectID == projectID {
+			col, _ = m.GetColumn(id)
+			cols = append(cols, col)
+		}
+	}
+
+	return cols, nil
+}
+
+func (m *boardDB) GetColumnsPage(projectID, limit, offset int) ([]model.Column, int, error) {
+	cols, _ := m.GetColumnsForProject(projectID)
+	return pageOf(cols, limit, offset), len(cols), nil
+}
+
+func (m *boardDB) GetItemsPage(projectID, columnID, limit, offset int) ([]model.Item, int, error) {
+	cols, _ := m.GetColumnsForProject(projectID)
+	items := make([]model.Item, 0)
+	for _, col := range cols {
+		if columnID == -1 || col.Id == columnID {
+			items = append(items, col.Items...)
+		}
+	}
+
+	return pageOf(items, limit, offset), len(items), nil
+}
+
+func pageOf[T any](all []T, limit, offset int) []T {
+	start := min(offset, len(all))
+	return all[start:min(start+limit, len(all))]
+}
+
+func (m *boardDB) GetColumn(id int) (model.Column, error) {
+	col, ok := m.columns[id]
+	if !ok {
+		return model.Column{}, errors.New("column not found")
+	}
+	for itemID := 1; itemID <= len(m.items); itemID++ {
+		if item := m.items[itemID]; item.ColumnID == id {
+			col.Items = append(col.Items, item)
+		}
+	}
+
+	return col, nil
+}
+
+func (m *boardDB) GetNextItemColumnOrder(columnID int) (int, error) {
+	col, _ := m.GetColumn(columnID)
+	return len(col.Items), nil
+}
+
+func (m *boardDB) GetItem(id int) (model.Item, error) {
+	item, ok := m.items[id]
+	if !ok {
+		return model.Item{}, errors.New("item not found")
+	}
+
+	return item, nil
+}
+
+func (m *boardDB) UpdateItem(id int, item model.Item) (model.Item, error) {
+	m.items[id] = item
+	return item, nil
+}
+
+func (m *boardDB) GetProjectMember(projectID int, user model.User) (model.Member, error) {
+	for _, member := range m.members[projectID] {
+		if user.Owns(member.UserID, member.Username) {
+			return member, nil
+		}
+	}
+
+	return model.Member{ProjectID: projectID, Username: user.Login}, nil
+}
+
+func (m *boardDB) CreateAPIToken(token model.APIToken) (model.APIToken, error) {
+	token.Id = len(m.apiTokens) + 1
+	m.apiTokens = append(m.apiTokens, token)
+	return token, nil
+}
+
+func (m *boardDB) GetAPITokenByHash(hash string) (model.APIToken, error) {
+	for _, token := range m.apiTokens {
+		if token.TokenHash == hash {
+			return token, nil
+		}
+	}
+
+	return model.APIToken{}, errors.New("token not found")
+}
+
+func (m *boardDB) TouchAPIToken(id int, lastUsedAt time.Time) error {
+	return nil
+}
+
+func (m *boardDB) GetUserToken(user model.User) (model.UserToken, error) {
+	return m.userTokens[user.Login], nil
+}
+
+func (m *boardDB) DeleteSessionsExpiredBefore(before time.Time) error {
+	return nil
+}
+
+func (m *boardDB) CreateSession(session model.Session) error {
+	m.sessions[session.Id] = session
+	return nil
+}
+
+func (m *boardDB) GetSession(id string) (model.Session, error) {
+	session, ok := m.sessions[id]
+	if !ok {
+		return model.Session{}, errors.New("session not found")
+	}
+
+	return session, nil
+}
+
+func (m *boardDB) RevokeSession(id string, revokedAt time.Time) error {
+	session := m.sessions[id]
+	session.RevokedAt = revokedAt
+	m.sessions[id] = session
+	return nil
+}
+
+func (m *boardDB) RevokeSessionsForUser(user model.User, revokedAt time.Time) error {
+	m.revoked = append(m.revoked, user.Login)
+	return nil
+}
+
+func (m *boardDB) RevokeAPITokensForUser(user model.User, revokedAt time.Time) error {
+	return nil
+}
+
+func (m *boardDB) DeleteUserToken(user model.User) error {
+	delete(m.userTokens, user.Login)
+	return nil
+}
+
+// newAPIServer serves the routes with the board and the fake GitHub, and
+// returns a function creating API tokens.
+func newAPIServer(t *testing.T, board *boardDB, srv *githubtest.Server) (http.Handler, func(username string, projID int, scope string) string) {
+	gh := github.NewWithConfig(github.Config{
+		AppId:        "1",
+		ClientId:     githubtest.ClientId,
+		ClientSecret: githubtest.ClientSecret,
+		PrivateKey:   srv.PrivateKey,
+		ApiUrl:       srv.URL,
+		WebUrl:       srv.URL,
+		Client:       srv.Client(),
+	})
+
+	sessions := repo.NewSessionRepo(board)
+	apiTokens := repo.NewAPITokenRepo(board)
+	access := repo.NewAccessRepo(board, gh)
+	hub := NewHub()
+	s := &Server{
+		webHandler: web.NewHandler(board, gh, sessions, apiTokens, access, hub),
+		sessions:   sessions,
+		apiTokens:  apiTokens,
+		access:     access,
+		hub:        hub,
+	}
+
+	newToken := func(username string, projID int, scope string) string {
+		t.Helper()
+		secret, _, err := apiTokens.Create(model.APIToken{Username: username, Name: "script", ProjectID: projID, Scope: scope}, 7)
+		if err != nil {
+			t.Fatalf("could not create API token: %s", err)
+		}
+		return secret
+	}
+
+	return s.RegisterRoutes(), newToken
+}
+
+func apiRequest(handler http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	return res
+}
+
+func apiError(t *testing.T, res *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	var body struct {
+		Error struct {
+			Status  int    `json:"status"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+		t.Fatalf("expected a JSON error, got %s", res.Body.String())
+	}
+	if body.Error.Status != res.Code || body.Error.Message == "" {
+		t.Errorf("expected the error to have status %d and a message, got %s", res.Code, res.Body.String())
+	}
+
+	return body.Error.Message
+}
+
+func TestAPIErrorBodies(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	handler, newToken := newAPIServer(t, newBoardDB(), srv)
+	token := newToken("editor", 1, model.ScopeReadWrite)
+
+	for _, tc := range []struct {
+		method, path, token, body string
+		status                    int
+	}{
+		{http.MethodGet, "/api/v1/projects/1/items", "", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/v1/projects/1/items", "gt_invalid", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/v1/projects/1/items/99", token, "", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/projects/1/items", token, `{"name": " ", "columnId": 1}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/projects/1/items/1/move", token, `{"direction": "left"}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/projects/1/items/1/issue", token, "", http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		if tc.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		if res.Code != tc.status {
+			t.Errorf("expected %s %s to fail with %d, got %d %s", tc.method, tc.path, tc.status, res.Code, res.Body.String())
+			continue
+		}
+		apiError(t, res)
+	}
+}
+
+func TestAPIPaginationBounds(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	handler, newToken := newAPIServer(t, newBoardDB(), srv)
+	token := newToken("viewer", 1, model.ScopeRead)
+
+	for query, status := range map[string]int{
+		"?page=1&perPage=1":                   http.StatusOK,
+		"?page=2&perPage=1":                   http.StatusOK,
+		"?page=3&perPage=1":                   http.StatusBadRequest,
+		"?column=2":                           http.StatusOK,
+		"?column=2&page=2":                    http.StatusBadRequest,
+		"?page=6917529027641081857&perPage=2": http.StatusBadRequest,
+		"?page=0":                             http.StatusBadRequest,
+		"?perPage=1000":                       http.StatusBadRequest,
+	} {
+		res := apiRequest(handler, http.MethodGet, "/api/v1/projects/1/items"+query, token, "")
+		if res.Code != status {
+			t.Errorf("expected %s to answer %d, got %d %s", query, status, res.Code, res.Body.String())
+			continue
+		}
+		if status != http.StatusOK {
+			apiError(t, res)
+		}
+	}
+
+	res := apiRequest(handler, http.MethodGet, "/api/v1/projects/1/items?page=2&perPage=1", token, "")
+	var body struct {
+		Data  []web.APIItem `json:"data"`
+		Total int           `json:"total"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+		t.Fatalf("could not read the page: %s", err)
+	}
+	if len(body.Data) != 1 || body.Data[0].Id != 2 || body.Total != 2 {
+		t.Errorf("expected the last page to hold the second item, got %s", res.Body.String())
+	}
+}
+
+func TestAPIRoleEnforcement(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	handler, newToken := newAPIServer(t, newBoardDB(), srv)
+
+	viewer := newToken("viewer", 1, model.ScopeReadWrite)
+	readOnly := newToken("editor", 1, model.ScopeRead)
+	outsider := newToken("outsider", 1, model.ScopeReadWrite)
+	otherProject := newToken("editor", 2, model.ScopeReadWrite)
+
+	for _, tc := range []struct {
+		name, method, path, token, body string
+		status                          int
+	}{
+		{"viewer reading", http.MethodGet, "/api/v1/projects/1/items", viewer, "", http.StatusOK},
+		{"viewer moving", http.MethodPost, "/api/v1/projects/1/items/1/move", viewer, `{"direction": "down"}`, http.StatusForbidden},
+		{"read token moving", http.MethodPost, "/api/v1/projects/1/items/1/move", readOnly, `{"direction": "down"}`, http.StatusForbidden},
+		{"outsider reading", http.MethodGet, "/api/v1/projects/1/items", outsider, "", http.StatusForbidden},
+		{"token of another project", http.MethodGet, "/api/v1/projects/1/items", otherProject, "", http.StatusForbidden},
+		{"item of another project", http.MethodGet, "/api/v1/projects/1/items/3", viewer, "", http.StatusNotFound},
+	} {
+		res := apiRequest(handler, tc.method, tc.path, tc.token, tc.body)
+		if res.Code != tc.status {
+			t.Errorf("%s: expected %d, got %d %s", tc.name, tc.status, res.Code, res.Body.String())
+			continue
+		}
+		if tc.status != http.StatusOK {
+			apiError(t, res)
+		}
+	}
+
+	res := apiRequest(handler, http.MethodGet, "/api/v1/projects", outsider, "")
+	if res.Code != http.StatusOK || !strings.Contains(res.Body.String(), `"data":[]`) {
+		t.Errorf("expected an outsider to see no projects, got %d %s", res.Code, res.Body.String())
+	}
+}
+
+func TestAPIMoveItemRunsWorkflow(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	srv.AddInstallation("TobiasTheDanish", githubtest.AccountUser)
+	ghRepo := srv.AddRepo("TobiasTheDanish", "go-track")
+
+	board := newBoardDB()
+	board.settings[1] = model.ProjectSettings{ProjectID: 1, Owner: "TobiasTheDanish", Repo: "go-track"}
+	handler, newToken := newAPIServer(t, board, srv)
+	token := newToken("editor", 1, model.ScopeReadWrite)
+
+	res := apiRequest(handler, http.MethodPost, "/api/v1/projects/1/items/1/move", token, `{"direction": "right"}`)
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected the item to be moved, got %d %s", res.Code, res.Body.String())
+	}
+
+	var item web.APIItem
+	if err := json.Unmarshal(res.Body.Bytes(), &item); err != nil {
+		t.Fatalf("could not read the item: %s", err)
+	}
+	if item.ColumnID != 2 || item.Issue == nil {
+		t.Fatalf("expected the item to enter todo with an issue, got %s", res.Body.String())
+	}
+	if issue := ghRepo.Issue(item.Issue.Number); issue == nil {
+		t.Errorf("expected issue #%d to be opened on GitHub", item.Issue.Number)
+	}
+
+	res = apiRequest(handler, http.MethodPost, "/api/v1/projects/1/items/1/move", token, `{"direction": "right"}`)
+	if res.Code != http.StatusBadRequest {
+		t.Errorf("expected moving right from the last column to fail, got %d %s", res.Code, res.Body.String())
+	}
+}
+
+func TestItemChecksOfDeletedBranch(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	srv.AddInstallation("TobiasTheDanish", githubtest.AccountUser)
+	srv.AddRepo("TobiasTheDanish", "go-track")
+
+	board := newBoardDB()
+	board.settings[1] = model.ProjectSettings{ProjectID: 1, Owner: "TobiasTheDanish", Repo: "go-track"}
+	item := board.items[1]
+	item.BranchName = "feature/1-merged"
+	board.items[1] = item
+	handler, newToken := newAPIServer(t, board, srv)
+
+	res := apiRequest(handler, http.MethodGet, "/project/1/items/1/checks", newToken("viewer", 1, model.ScopeRead), "")
+	if res.Code != http.StatusOK || res.Body.String() != "" {
+		t.Errorf("expected no badge for a deleted branch, got %d %s", res.Code, res.Body.String())
+	}
+}
+
+func TestMergeOnlyThePullRequestOfTheItem(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	srv.AddInstallation("TobiasTheDanish", githubtest.AccountUser)
+	ghRepo := srv.AddRepo("TobiasTheDanish", "go-track")
+	ghRepo.AddBranch("feature/1-login", "main")
+	ghRepo.AddBranch("feature/2-other", "main")
+	own := ghRepo.AddPull("Add login page", "feature/1-login", "main")
+	other := ghRepo.AddPull("Someone else's work", "feature/2-other", "main")
+	ghRepo.AddReview(other.Number, "reviewer", "APPROVED")
+
+	board := newBoardDB()
+	board.settings[1] = model.ProjectSettings{ProjectID: 1, Owner: "TobiasTheDanish", Repo: "go-track", MergeMethod: model.MergeMethodMerge}
+	item := board.items[1]
+	item.BranchName = "feature/1-login"
+	item.PullRequestNumber = own.Number
+	board.items[1] = item
+	handler, newToken := newAPIServer(t, board, srv)
+
+	form := "pull-number=" + strconv.Itoa(other.Number) + "&head-sha=" + ghRepo.Branch("feature/2-other") + "&merge-method=merge"
+	req := httptest.NewRequest(http.MethodPost, "/project/1/items/1/merge", strings.NewReader(form))
+	req.Header.Set("Authorization", "Bearer "+newToken("editor", 1, model.ScopeReadWrite))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	if res.Code != http.StatusConflict || !strings.Contains(res.Body.String(), "#"+strconv.Itoa(own.Number)) {
+		t.Errorf("expected merging another pull request through the item to be refused, got %d %s", res.Code, res.Body.String())
+	}
+	if ghRepo.Pull(other.Number).Merged || board.items[1].PullRequestNumber != own.Number {
+		t.Errorf("expected the other pull request to stay open and the item to keep its pull request")
+	}
+}
+
+func TestMergeMethodOfTheProject(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	srv.AddInstallation("TobiasTheDanish", githubtest.AccountUser)
+	ghRepo := srv.AddRepo("TobiasTheDanish", "go-track")
+	ghRepo.AddBranch("feature/1-login", "main")
+	pull := ghRepo.AddPull("Add login page", "feature/1-login", "main")
+	ghRepo.AddReview(pull.Number, "reviewer", "APPROVED")
+
+	board := newBoardDB()
+	board.settings[1] = model.ProjectSettings{ProjectID: 1, Owner: "TobiasTheDanish", Repo: "go-track", MergeMethod: model.MergeMethodSquash}
+	item := board.items[1]
+	item.BranchName = "feature/1-login"
+	item.PullRequestNumber = pull.Number
+	board.items[1] = item
+	handler, newToken := newAPIServer(t, board, srv)
+	token := newToken("editor", 1, model.ScopeReadWrite)
+
+	res := apiRequest(handler, http.MethodPost, "/api/v1/projects/1/items/1/merge", token, `{"method": "rebase", "sha": "`+ghRepo.Branch("feature/1-login")+`"}`)
+	if res.Code != http.StatusForbidden {
+		t.Fatalf("expected an editor to be refused another merge method, got %d %s", res.Code, res.Body.String())
+	}
+	apiError(t, res)
+	if ghRepo.Pull(pull.Number).Merged {
+		t.Fatalf("expected the pull request to stay open")
+	}
+
+	res = apiRequest(handler, http.MethodPost, "/api/v1/projects/1/items/1/merge", token, `{"sha": "`+ghRepo.Branch("feature/1-login")+`"}`)
+	if res.Code != http.StatusOK || !ghRepo.Pull(pull.Number).Merged {
+		t.Errorf("expected the pull request to be merged with the method of the project, got %d %s", res.Code, res.Body.String())
+	}
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"errors"
+	"go-track/cmd/web"
+	"go-track/internal/auth"
+	"go-track/internal/model"
+	"go-track/internal/repo"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+)
+
+// publicRoutes are the only routes reachable without signing in. They are
+// matched against the route pattern, not the request path.
+var publicRoutes = map[string]bool{
+	"/sign-in":        true,
+	"/auth/callback":  true,
+	"/not-authorized": true,
+	"/assets/*":       true,
+	web.OpenAPIPath:   true,
+	"/healthz":        true,
+	"/readyz":         true,
+	metricsPath:       true,
+}
+
+// browserOnlyRoutes manage the account of the user, so they can not be used
+// with an API token.
+var browserOnlyRoutes = map[string]bool{
+	"/sign-out":            true,
+	"/sign-out/everywhere": true,
+	"/sessions":            true,
+	"/sessions/:sessionID": true,
+	"/users/sessions":      true,
+	"/tokens":              true,
+	"/tokens/:tokenID":     true,
+}
+
+// requireSession validates the session of every request to a route that is not
+// public, and puts it in the request context for the handlers. Sessions are
+// checked against the database, so revoked sessions are rejected right away.
+// Requests with an Authorization header are authenticated with an API token.
+func (s *Server) requireSession(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if publicRoutes[c.Path()] {
+			return next(c)
+		}
+
+		if c.Request().Header.Get("Authorization") != "" {
+			return s.requireAPIToken(c, next)
+		}
+
+		jwtCookie, err := c.Request().Cookie(auth.SessionCookie)
+		if err != nil {
+			return redirectToSignIn(c)
+		}
+
+		session, renewed, err := s.sessions.Validate(jwtCookie.Value)
+		if err != nil {
+			log.Printf("Error when validating session: %s\n", err)
+			return redirectToSignIn(c)
+		}
+		if renewed != "" {
+			http.SetCookie(c.Response().Writer, auth.NewSessionCookie(renewed, int(auth.SessionLifetime().Seconds()), web.SecureCookies(c)))
+		}
+
+		err = s.hasAccess(session.User())
+		if errors.Is(err, repo.ErrSignInAgain) {
+			// only this session ends, the user is not known to be denied
+			if err := s.sessions.Revoke(session.User(), session.SessionID); err != nil {
+				log.Printf("Signing out session of %s failed: %s\n", session.Username, err)
+			}
+			http.SetCookie(c.Response().Writer, auth.NewSessionCookie("", -1, web.SecureCookies(c)))
+			return redirectToSignIn(c)
+		}
+		if err != nil {
+			http.SetCookie(c.Response().Writer, auth.NewSessionCookie("", -1, web.SecureCookies(c)))
+			return redirectTo(c, "/not-authorized")
+		}
+
+		ctx := auth.WithSession(c.Request().Context(), session)
+		c.SetRequest(c.Request().WithContext(ctx))
+
+		return next(c)
+	}
+}
+
+// requireAPIToken authenticates scripts. The scope and project of the token
+// are enforced by the role middleware of the handlers.
+func (s *Server) requireAPIToken(c echo.Context, next echo.HandlerFunc) error {
+	secret, err := auth.GetJWTString(c.Request().Header)
+	if err != nil {
+		return web.Fail(c, http.StatusUnauthorized, err.Error())
+	}
+
+	token, err := s.apiTokens.Validate(secret)
+	if err != nil {
+		log.Printf("Error when validating API token: %s\n", err)
+		return web.Fail(c, http.StatusUnauthorized, "The API token is invalid, expired or revoked")
+	}
+
+	if browserOnlyRoutes[c.Path()] {
+		return web.Fail(c, http.StatusForbidden, "API tokens can not be used for this")
+	}
+
+	session := auth.AuthSession{UserID: token.UserID, Username: token.Username}
+	if err := s.hasAccess(session.User()); err != nil {
+		return web.Fail(c, http.StatusForbidden, err.Error())
+	}
+
+	ctx := auth.WithSession(c.Request().Context(), session)
+	ctx = auth.WithAPIToken(ctx, token)
+	c.SetRequest(c.Request().WithContext(ctx))
+
+	return next(c)
+}
+
+// hasAccess rechecks the allowlist now and then, and signs users out everywhere
+// once they are no longer allowed. Users are let through when the check fails,
+// so an unavailable GitHub does not sign everyone out. ErrSignInAgain is
+// returned when the user must sign in again before they can be checked.
+func (s *Server) hasAccess(user model.User) error {
+	err := s.access.Recheck(user)
+	if errors.Is(err, repo.ErrAccessDenied) {
+		if err := s.sessions.RevokeAll(user); err != nil {
+			log.Printf("Signing out %s failed: %s\n", user.Login, err)
+		}
+		return err
+	}
+	if errors.Is(err, repo.ErrSignInAgain) {
+		return err
+	}
+	if err != nil {
+		log.Printf("Checking access of %s failed: %s\n", user.Login, err)
+	}
+
+	return nil
+}
+
+// redirectTo sends the browser to the page, with an HX-Redirect for HTMX
+// requests so the whole page is replaced. API requests are forbidden instead.
+func redirectTo(c echo.Context, path string) error {
+	if web.IsAPIRequest(c) {
+		return web.Fail(c, http.StatusForbidden, repo.ErrAccessDenied.Error())
+	}
+
+	if c.Request().Header.Get("HX-Request") == "true" {
+		c.Response().Header().Set("HX-Redirect", path)
+		return c.NoContent(http.StatusForbidden)
+	}
+
+	return c.Redirect(http.StatusSeeOther, path)
+}
+
+// redirectToSignIn sends the user to the sign in page, and back to the page
+// they were on afterwards. HTMX requests fetch fragments, so they get an
+// HX-Redirect for the page shown in the browser instead of a redirect. API
+// requests are not redirected, they are unauthorized.
+func redirectToSignIn(c echo.Context) error {
+	req := c.Request()
+
+	if web.IsAPIRequest(c) {
+		return web.Fail(c, http.StatusUnauthorized, "Sign in or use an API token")
+	}
+
+	if req.Header.Get("HX-Request") == "true" {
+		redirect := ""
+		if current, err := url.Parse(req.Header.Get("HX-Current-URL")); err == nil {
+			redirect = current.RequestURI()
+		}
+		c.Response().Header().Set("HX-Redirect", signInUrl(redirect))
+		return c.NoContent(http.StatusUnauthorized)
+	}
+
+	if req.Method != http.MethodGet {
+		return c.Redirect(http.StatusSeeOther, signInUrl(""))
+	}
+
+	return c.Redirect(http.StatusTemporaryRedirect, signInUrl(req.URL.RequestURI()))
+}
+
+func signInUrl(redirect string) string {
+	if redirect == "" || redirect == "/" || !auth.IsLocalRedirect(redirect) {
+		return "/sign-in"
+	}
+
+	return "/sign-in?redirect=" + url.QueryEscape(redirect)
+}
+
+// requireCSRF rejects requests changing state without the CSRF token of the
+// session. The token is sent by HTMX in a header, set up in the base layout.
+func (s *Server) requireCSRF(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		switch req.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return next(c)
+		}
+
+		if publicRoutes[c.Path()] {
+			return next(c)
+		}
+
+		// API tokens are not sent by browsers on their own, so they can not
+		// be used cross-site.
+		if _, ok := auth.APITokenFromContext(req.Context()); ok {
+			return next(c)
+		}
+
+		session, _ := auth.SessionFromContext(req.Context())
+		token := req.Header.Get(auth.CSRFHeader)
+		if token == "" {
+			token = c.FormValue(auth.CSRFField)
+		}
+
+		if auth.ValidCSRFToken(session.SessionID, token) {
+			return next(c)
+		}
+
+		// The page was most likely rendered for an earlier session, reloading
+		// it gives HTMX the current token.
+		if req.Header.Get("HX-Request") == "true" {
+			c.Response().Header().Set("HX-Refresh", "true")
+		}
+
+		return web.Fail(c, http.StatusForbidden, "The request could not be verified, reload the page and try again")
+	}
+}
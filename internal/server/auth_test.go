@@ -0,0 +1,183 @@
+package server
+
+import (
+	"go-track/cmd/web"
+	"go-track/internal/auth"
+	"go-track/internal/github/githubtest"
+	"go-track/internal/model"
+	"go-track/internal/repo"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestRequireSessionRedirectsToSignIn(t *testing.T) {
+	handler := (&Server{}).RegisterRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/project/1/settings?tab=members", nil)
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	if res.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("expected a redirect without a session cookie, got %d", res.Code)
+	}
+	if location := res.Header().Get("Location"); location != "/sign-in?redirect=%2Fproject%2F1%2Fsettings%3Ftab%3Dmembers" {
+		t.Errorf("expected to be sent back to the page after signing in, got %s", location)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/project/1/settings", nil)
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	if res.Code != http.StatusSeeOther || res.Header().Get("Location") != "/sign-in" {
+		t.Errorf("expected a form post to be redirected to the sign in page, got %d %s", res.Code, res.Header().Get("Location"))
+	}
+}
+
+func TestRequireSessionHTMXRequest(t *testing.T) {
+	handler := (&Server{}).RegisterRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/1/columns", nil)
+	req.Header.Set("HX-Request", "true")
+	req.Header.Set("HX-Current-URL", "http://localhost:8080/1")
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	if res.Code != http.StatusUnauthorized {
+		t.Fatalf("expected an HTMX request without a session to be unauthorized, got %d", res.Code)
+	}
+	if redirect := res.Header().Get("HX-Redirect"); redirect != "/sign-in?redirect=%2F1" {
+		t.Errorf("expected the page shown in the browser to be redirected, got %s", redirect)
+	}
+	if res.Header().Get("Location") != "" {
+		t.Errorf("expected no redirect of the fragment request, got %s", res.Header().Get("Location"))
+	}
+}
+
+func TestPublicRoutesPassThrough(t *testing.T) {
+	handler := (&Server{}).RegisterRoutes()
+
+	for path, status := range map[string]int{
+		"/assets/js/htmx.min.js": http.StatusOK,
+		"/healthz":               http.StatusOK,
+		"/not-authorized":        http.StatusForbidden,
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		if res.Code != status || res.Header().Get("Location") != "" {
+			t.Errorf("expected %s to be served without signing in, got %d %s", path, res.Code, res.Header().Get("Location"))
+		}
+	}
+}
+
+func TestUnknownRoutesLeakNothing(t *testing.T) {
+	handler := (&Server{}).RegisterRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/no/such/page", nil)
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	if res.Code != http.StatusTemporaryRedirect || !strings.HasPrefix(res.Header().Get("Location"), "/sign-in") {
+		t.Errorf("expected an unknown page to be treated like any other page, got %d %s", res.Code, res.Header().Get("Location"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/no/such/route", nil)
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	if res.Code != http.StatusUnauthorized {
+		t.Errorf("expected an unknown API route to be unauthorized, got %d", res.Code)
+	}
+	if body := res.Body.String(); strings.Contains(body, "Not Found") {
+		t.Errorf("expected nothing about the route to be revealed, got %s", body)
+	}
+}
+
+func TestSecureCookiesBehindProxy(t *testing.T) {
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/sign-in", nil)
+	if web.SecureCookies(e.NewContext(req, httptest.NewRecorder())) {
+		t.Errorf("expected cookies over plain HTTP to not be secure")
+	}
+
+	req.Header.Set("X-Forwarded-Proto", "https")
+	if !web.SecureCookies(e.NewContext(req, httptest.NewRecorder())) {
+		t.Errorf("expected cookies to be secure when the proxy terminated TLS")
+	}
+
+	t.Setenv("COOKIE_SECURE", "true")
+	req = httptest.NewRequest(http.MethodGet, "/sign-in", nil)
+	if !web.SecureCookies(e.NewContext(req, httptest.NewRecorder())) {
+		t.Errorf("expected COOKIE_SECURE to make cookies secure")
+	}
+}
+
+func TestAccessRecheckFailureKeepsSessions(t *testing.T) {
+	t.Setenv("TOKEN_ENCRYPTION_KEY", "key")
+	t.Setenv("GO_TRACK_ALLOWED_ORGS", "go-track-org")
+
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	srv.AddUser("editor", "go-track-org")
+
+	board := newBoardDB()
+	accessToken, _ := auth.Encrypt("editor-token")
+	board.userTokens["editor"] = model.UserToken{Username: "editor", AccessToken: accessToken}
+	handler, newToken := newAPIServer(t, board, srv)
+	token := newToken("editor", 1, model.ScopeRead)
+
+	// GitHub answers a rate limited request with a 403
+	srv.Fail(http.MethodGet, "/user/memberships/orgs/go-track-org", http.StatusForbidden, 1)
+	res := apiRequest(handler, http.MethodGet, "/api/v1/projects/1/items", token, "")
+
+	if res.Code != http.StatusOK {
+		t.Errorf("expected the request to be let through while GitHub is unavailable, got %d %s", res.Code, res.Body.String())
+	}
+	if len(board.revoked) != 0 {
+		t.Errorf("expected no sessions to be revoked, got %v", board.revoked)
+	}
+}
+
+func TestMissingUserTokenEndsOnlyTheSession(t *testing.T) {
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("GO_TRACK_ALLOWED_ORGS", "go-track-org")
+
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	board := newBoardDB()
+	handler, newToken := newAPIServer(t, board, srv)
+	sessions := repo.NewSessionRepo(board)
+	editor := model.User{Login: "editor"}
+	current, _ := sessions.Create(editor, "browser")
+	other, _ := sessions.Create(editor, "laptop")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: auth.SessionCookie, Value: current})
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	if res.Code != http.StatusTemporaryRedirect || res.Header().Get("Location") != "/sign-in" {
+		t.Errorf("expected to be sent to the sign in page, got %d %s", res.Code, res.Header().Get("Location"))
+	}
+	if _, _, err := sessions.Validate(current); err == nil {
+		t.Error("expected the session to be signed out")
+	}
+	if _, _, err := sessions.Validate(other); err != nil {
+		t.Errorf("expected the other session to be kept: %s", err)
+	}
+
+	res = apiRequest(handler, http.MethodGet, "/api/v1/projects/1/items", newToken("editor", 1, model.ScopeRead), "")
+	if res.Code != http.StatusForbidden || apiError(t, res) != repo.ErrSignInAgain.Error() {
+		t.Errorf("expected API tokens to ask for signing in again, got %d %s", res.Code, res.Body.String())
+	}
+	if len(board.revoked) != 0 {
+		t.Errorf("expected nothing to be revoked for the user, got %v", board.revoked)
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// eventsKeepAlive keeps proxies from closing idle streams.
+	eventsKeepAlive = 30 * time.Second
+	// eventsStreamLifetime ends streams now and then, so the browser reconnects
+	// through the session checks and revoked sessions stop getting updates.
+	eventsStreamLifetime = 5 * time.Minute
+)
+
+// projectEventsHandler streams the board updates of the project as
+// Server-Sent Events.
+func (s *Server) projectEventsHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	// the server write timeout is meant for ordinary responses
+	err = http.NewResponseController(c.Response()).SetWriteDeadline(time.Time{})
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	events, unsubscribe := s.hub.Subscribe(id)
+	defer unsubscribe()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+	lifetime := time.NewTimer(eventsStreamLifetime)
+	defer lifetime.Stop()
+
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-lifetime.C:
+			return nil
+		case <-keepAlive.C:
+			if _, err := io.WriteString(res, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+		case ev, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := writeEvent(res, ev); err != nil {
+				return nil
+			}
+		}
+		res.Flush()
+	}
+}
+
+// writeEvent writes the event in the text/event-stream format, where every
+// line of the data needs its own field.
+func writeEvent(w io.Writer, ev Event) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "event: %s\n", ev.Name)
+	for _, line := range strings.Split(ev.Data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,73 @@
+package server
+
+import "sync"
+
+// subscriberBuffer is how many events a subscriber may fall behind before it
+// is dropped. Dropped subscribers reconnect and load the whole board again.
+const subscriberBuffer = 16
+
+type Event struct {
+	Name string
+	Data string
+}
+
+// Hub passes board updates to everyone with the project open. It only lives in
+// this process, so updates are not shared between instances of the app.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[int]map[chan Event]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[int]map[chan Event]struct{}),
+	}
+}
+
+// Subscribe returns the events of the project, and a function to call once
+// they are no longer read. The channel is closed when the subscriber is
+// dropped for falling behind.
+func (h *Hub) Subscribe(projID int) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[projID] == nil {
+		h.subscribers[projID] = make(map[chan Event]struct{})
+	}
+	h.subscribers[projID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.remove(projID, ch)
+	}
+}
+
+// Broadcast sends the event to the subscribers of the project without waiting
+// for them.
+func (h *Hub) Broadcast(projID int, name, data string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers[projID] {
+		select {
+		case ch <- Event{Name: name, Data: data}:
+		default:
+			h.remove(projID, ch)
+		}
+	}
+}
+
+// remove must be called with the lock held.
+func (h *Hub) remove(projID int, ch chan Event) {
+	if _, ok := h.subscribers[projID][ch]; !ok {
+		return
+	}
+
+	delete(h.subscribers[projID], ch)
+	if len(h.subscribers[projID]) == 0 {
+		delete(h.subscribers, projID)
+	}
+	close(ch)
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestHub(t *testing.T) {
+	hub := NewHub()
+
+	events, unsubscribe := hub.Subscribe(1)
+	other, unsubscribeOther := hub.Subscribe(2)
+	defer unsubscribeOther()
+
+	hub.Broadcast(1, "column", "<div></div>")
+
+	select {
+	case ev := <-events:
+		if ev.Name != "column" || ev.Data != "<div></div>" {
+			t.Errorf("unexpected event %+v", ev)
+		}
+	default:
+		t.Fatal("expected the subscriber of the project to get the event")
+	}
+
+	select {
+	case ev := <-other:
+		t.Errorf("expected subscribers of other projects not to get the event, got %+v", ev)
+	default:
+	}
+
+	unsubscribe()
+	if _, ok := <-events; ok {
+		t.Error("expected the channel to be closed after unsubscribing")
+	}
+	unsubscribe()
+
+	// a subscriber that falls behind is dropped instead of blocking the others
+	for i := 0; i <= subscriberBuffer; i++ {
+		hub.Broadcast(2, "column", "")
+	}
+	for range other {
+	}
+	if len(hub.subscribers) != 0 {
+		t.Errorf("expected the slow subscriber to be dropped, got %v", hub.subscribers)
+	}
+}
+
+func TestProjectEventsStream(t *testing.T) {
+	s := &Server{hub: NewHub()}
+	e := echo.New()
+	e.GET("/project/:id/events", s.projectEventsHandler)
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/project/1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if ct := res.Header.Get(echo.HeaderContentType); ct != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %s", ct)
+	}
+
+	// the headers are flushed before subscribing is certain, so wait for it
+	for i := 0; ; i++ {
+		s.hub.mu.Lock()
+		subscribed := len(s.hub.subscribers[1]) > 0
+		s.hub.mu.Unlock()
+		if subscribed {
+			break
+		}
+		if i == 100 {
+			t.Fatal("the stream did not subscribe to the project")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	s.hub.Broadcast(1, "column", "<div id=\"column-1\">\n</div>")
+
+	reader := bufio.NewReader(res.Body)
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line == "\n" {
+			break
+		}
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+
+	expected := []string{"event: column", `data: <div id="column-1">`, "data: </div>"}
+	if strings.Join(lines, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+}
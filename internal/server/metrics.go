@@ -0,0 +1,29 @@
+package server
+
+import (
+	"crypto/subtle"
+	"go-track/internal/metrics"
+	"net/http"
+	"os"
+
+	"github.com/labstack/echo/v4"
+)
+
+const metricsPath = "/metrics"
+
+// metricsHandler serves the metrics for Prometheus. The route is public, so
+// when METRICS_TOKEN is set scrapers must send it as a bearer token.
+func metricsHandler() echo.HandlerFunc {
+	token := os.Getenv("METRICS_TOKEN")
+	handler := metrics.Handler()
+
+	return func(c echo.Context) error {
+		auth := c.Request().Header.Get("Authorization")
+		if token != "" && subtle.ConstantTimeCompare([]byte(auth), []byte("Bearer "+token)) != 1 {
+			return c.String(http.StatusUnauthorized, "The metrics token is missing or wrong")
+		}
+
+		handler.ServeHTTP(c.Response(), c.Request())
+		return nil
+	}
+}
@@ -1,11 +1,12 @@
 package server
 
 import (
-	"log"
 	"net/http"
 
 	"go-track/cmd/web"
-	"go-track/internal/auth"
+	"go-track/internal/metrics"
+	"go-track/internal/model"
+	"go-track/internal/openapi"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -13,40 +14,86 @@ import (
 
 func (s *Server) RegisterRoutes() http.Handler {
 	e := echo.New()
-	e.Use(middleware.Logger())
+	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
+		// load balancers poll these every few seconds
+		Skipper: func(c echo.Context) bool {
+			return c.Path() == "/healthz" || c.Path() == "/readyz" || c.Path() == metricsPath
+		},
+	}))
+	e.Use(metrics.Middleware(metricsPath))
 	e.Use(middleware.Recover())
+	e.Use(s.requireSession)
+	e.Use(s.requireCSRF)
 	fileServer := http.FileServer(http.FS(web.Files))
 	e.GET("/assets/*", echo.WrapHandler(fileServer))
 
-	e.GET("/", func(c echo.Context) error {
-		jwtCookie, err := c.Request().Cookie("authSession")
-		if err != nil {
-			log.Printf("Could not get cookie with name authSession: %s\n", err)
-			return c.Redirect(http.StatusTemporaryRedirect, "/sign-in?error=Authorization%20failed")
-		}
-
-		_, err = auth.ParseAuthJWT(jwtCookie.Value)
-		if err != nil {
-			log.Printf("Error when parsing jwt: %s\n", err)
-			return c.Redirect(http.StatusTemporaryRedirect, "/sign-in")
-		}
+	e.GET("/healthz", s.webHandler.HealthzHandler)
+	e.GET("/readyz", s.webHandler.ReadyzHandler)
+	e.GET(metricsPath, metricsHandler())
 
+	e.GET("/", func(c echo.Context) error {
 		return c.Redirect(http.StatusTemporaryRedirect, "/1")
 	})
 
 	e.GET("/sign-in", s.webHandler.SignInHandler)
 	e.GET("/auth/callback", s.webHandler.GithubAuthCallbackHandler)
+	e.GET("/not-authorized", s.webHandler.NotAuthorizedHandler)
+	e.POST("/sign-out", s.webHandler.SignOutHandler)
+	e.POST("/sign-out/everywhere", s.webHandler.SignOutEverywhereHandler)
+
+	e.GET("/sessions", s.webHandler.SessionsHandler)
+	e.DELETE("/sessions/:sessionID", s.webHandler.RevokeSessionHandler)
+	e.DELETE("/users/sessions", s.webHandler.RevokeUserSessionsHandler)
+
+	e.GET("/tokens", s.webHandler.APITokensHandler)
+	e.POST("/tokens", s.webHandler.CreateAPITokenHandler)
+	e.DELETE("/tokens/:tokenID", s.webHandler.RevokeAPITokenHandler)
+
+	viewer := s.webHandler.RequireRole(model.RoleViewer)
+	editor := s.webHandler.RequireRole(model.RoleEditor)
+	admin := s.webHandler.RequireRole(model.RoleAdmin)
+
+	e.GET("/:id", s.webHandler.ProjectPageHandler, viewer)
+	e.GET("/:id/columns", s.webHandler.ProjectColumnsHandler, viewer)
+
+	e.GET("/project/:id/settings", s.webHandler.ProjectSettingsHandler, admin)
+	e.GET("/project/:id/members", s.webHandler.ProjectMembersHandler, admin)
+	e.GET("/project/:id/events", s.projectEventsHandler, viewer)
+	e.GET("/project/:id/items/:itemID/checks", s.webHandler.ItemChecksHandler, viewer)
+	e.GET("/project/:id/items/:itemID/link/branch", s.webHandler.LinkBranchModalHandler, editor)
+	e.GET("/project/:id/items/:itemID/link/branch/search", s.webHandler.SearchBranchesHandler, editor)
+	e.GET("/project/:id/items/:itemID/link/pr", s.webHandler.LinkPRModalHandler, editor)
+	e.GET("/project/:id/items/:itemID/link/pr/search", s.webHandler.SearchPRsHandler, editor)
+
+	e.POST("/project/:id/settings", s.webHandler.UpdateProjectSettingsHandler, admin)
+	e.POST("/project/:id/members", s.webHandler.SaveProjectMemberHandler, admin)
+	e.POST("/columns/items", s.webHandler.ProjectItemHandler, editor)
+	e.POST("/project/:id/items/:itemID/move", s.webHandler.MoveProjectItemHandler, editor)
+	e.POST("/project/:id/items/:itemID/branch", s.webHandler.CreateBranchHandler, editor)
+	e.POST("/project/:id/items/:itemID/pr", s.webHandler.CreatePRHandler, editor)
+	e.POST("/project/:id/items/:itemID/merge", s.webHandler.MergePRHandler, editor)
+	e.POST("/project/:id/items/:itemID/link/branch", s.webHandler.LinkBranchHandler, editor)
+	e.POST("/project/:id/items/:itemID/link/pr", s.webHandler.LinkPRHandler, editor)
 
-	e.GET("/:id", s.webHandler.ProjectPageHandler)
-	e.GET("/:id/columns", s.webHandler.ProjectColumnsHandler)
+	e.DELETE("/project/:id/members/:username", s.webHandler.RemoveProjectMemberHandler, admin)
+	e.DELETE("/columns/:colID/items/:itemID", s.webHandler.DeleteProjectItemHandler, editor)
 
-	e.POST("/columns/items", s.webHandler.ProjectItemHandler)
-	e.POST("/project/:id/items/:itemID/move", s.webHandler.MoveProjectItemHandler)
-	e.POST("/project/:id/items/:itemID/branch", s.webHandler.CreateBranchHandler)
-	e.POST("/project/:id/items/:itemID/pr", s.webHandler.CreatePRHandler)
-	e.POST("/project/:id/items/:itemID/merge", s.webHandler.MergePRHandler)
+	apiDoc := web.APIDocument()
+	e.GET(web.OpenAPIPath, apiDoc.Handler)
 
-	e.DELETE("/columns/:colID/items/:itemID", s.webHandler.DeleteProjectItemHandler)
+	api := e.Group("/api/v1", openapi.Validator(apiDoc, openapi.ValidatorConfig{Fail: web.Fail}))
+	api.GET("/projects", s.webHandler.APIListProjectsHandler)
+	api.GET("/projects/:id", s.webHandler.APIGetProjectHandler, viewer)
+	api.GET("/projects/:id/columns", s.webHandler.APIListColumnsHandler, viewer)
+	api.GET("/projects/:id/items", s.webHandler.APIListItemsHandler, viewer)
+	api.GET("/projects/:id/items/:itemID", s.webHandler.APIGetItemHandler, viewer)
+	api.POST("/projects/:id/items", s.webHandler.APICreateItemHandler, editor)
+	api.POST("/projects/:id/items/:itemID/move", s.webHandler.APIMoveItemHandler, editor)
+	api.POST("/projects/:id/items/:itemID/issue", s.webHandler.APICreateIssueHandler, editor)
+	api.POST("/projects/:id/items/:itemID/branch", s.webHandler.APICreateBranchHandler, editor)
+	api.POST("/projects/:id/items/:itemID/pull-request", s.webHandler.APICreatePullRequestHandler, editor)
+	api.POST("/projects/:id/items/:itemID/merge", s.webHandler.APIMergeHandler, editor)
+	api.DELETE("/projects/:id/items/:itemID", s.webHandler.APIDeleteItemHandler, editor)
 
 	return e
 }
@@ -1,39 +1,175 @@
 package server
 
 import (
-	"encoding/json"
-	"github.com/labstack/echo/v4"
+	"go-track/cmd/web"
+	"go-track/internal/openapi"
 	"net/http"
 	"net/http/httptest"
-	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/labstack/echo/v4"
 )
 
-func TestHandler(t *testing.T) {
-	e := echo.New()
-	req := httptest.NewRequest(http.MethodGet, "/", nil)
-	resp := httptest.NewRecorder()
-	c := e.NewContext(req, resp)
-	s := &Server{}
-	// Assertions
-	if err := s.HelloWorldHandler(c); err != nil {
-		t.Errorf("handler() error = %v", err)
-		return
-	}
-	if resp.Code != http.StatusOK {
-		t.Errorf("handler() wrong status code = %v", resp.Code)
-		return
-	}
-	expected := map[string]string{"message": "Hello World"}
-	var actual map[string]string
-	// Decode the response body into the actual map
-	if err := json.NewDecoder(resp.Body).Decode(&actual); err != nil {
-		t.Errorf("handler() error decoding response body: %v", err)
-		return
-	}
-	// Compare the decoded response with the expected value
-	if !reflect.DeepEqual(expected, actual) {
-		t.Errorf("handler() wrong response body. expected = %v, actual = %v", expected, actual)
-		return
+// undescribedRoutes are the routes left out of the OpenAPI document. Every
+// other route must be described, so a new JSON route can not be missed.
+var undescribedRoutes = map[string]bool{
+	// HTML pages and HTMX fragments of the board
+	"GET /":                                             true,
+	"GET /:id":                                          true,
+	"GET /:id/columns":                                  true,
+	"POST /columns/items":                               true,
+	"DELETE /columns/:colID/items/:itemID":              true,
+	"POST /project/:id/items/:itemID/move":              true,
+	"POST /project/:id/items/:itemID/branch":            true,
+	"POST /project/:id/items/:itemID/pr":                true,
+	"POST /project/:id/items/:itemID/merge":             true,
+	"GET /project/:id/items/:itemID/checks":             true,
+	"GET /project/:id/items/:itemID/link/branch":        true,
+	"GET /project/:id/items/:itemID/link/branch/search": true,
+	"POST /project/:id/items/:itemID/link/branch":       true,
+	"GET /project/:id/items/:itemID/link/pr":            true,
+	"GET /project/:id/items/:itemID/link/pr/search":     true,
+	"POST /project/:id/items/:itemID/link/pr":           true,
+	"GET /project/:id/events":                           true,
+	"GET /project/:id/settings":                         true,
+	"POST /project/:id/settings":                        true,
+	"GET /project/:id/members":                          true,
+	"POST /project/:id/members":                         true,
+	"DELETE /project/:id/members/:username":             true,
+	// sessions and API tokens, which are managed in the browser only
+	"GET /sessions":               true,
+	"DELETE /sessions/:sessionID": true,
+	"DELETE /users/sessions":      true,
+	"GET /tokens":                 true,
+	"POST /tokens":                true,
+	"DELETE /tokens/:tokenID":     true,
+	// signing in and out with GitHub
+	"GET /sign-in":              true,
+	"GET /auth/callback":        true,
+	"GET /not-authorized":       true,
+	"POST /sign-out":            true,
+	"POST /sign-out/everywhere": true,
+	// static assets
+	"GET /assets/*": true,
+	// probes and metrics for the operators
+	"GET /healthz": true,
+	"GET /readyz":  true,
+	"GET /metrics": true,
+}
+
+// TestAPIRoutesAreDescribed fails when a route is registered without being
+// described in the OpenAPI document or listed in undescribedRoutes, or when
+// the document describes a route that is not registered.
+func TestAPIRoutesAreDescribed(t *testing.T) {
+	e := (&Server{}).RegisterRoutes().(*echo.Echo)
+	doc := web.APIDocument()
+
+	registered := make(map[string]bool)
+	for _, route := range e.Routes() {
+		if route.Method == echo.RouteNotFound {
+			continue
+		}
+
+		key := route.Method + " " + route.Path
+		registered[key] = true
+		if undescribedRoutes[key] {
+			if doc.Operation(route.Method, route.Path) != nil {
+				t.Errorf("%s is described in the OpenAPI document, remove it from undescribedRoutes", key)
+			}
+			continue
+		}
+
+		if doc.Operation(route.Method, route.Path) == nil {
+			t.Errorf("%s is not described in the OpenAPI document", key)
+		}
+		registered[route.Method+" "+openapi.PathOf(route.Path)] = true
+	}
+
+	for key := range undescribedRoutes {
+		if !registered[key] {
+			t.Errorf("%s is in undescribedRoutes but not registered", key)
+		}
+	}
+
+	for path, item := range doc.Paths {
+		for method := range item {
+			key := strings.ToUpper(method) + " " + path
+			if !registered[key] {
+				t.Errorf("%s is described in the OpenAPI document but not registered", key)
+			}
+		}
+	}
+
+	if len(registered) == 0 {
+		t.Fatal("no routes are registered")
+	}
+}
+
+func TestOpenAPIDocumentIsPublic(t *testing.T) {
+	handler := (&Server{}).RegisterRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, web.OpenAPIPath, nil)
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected the document to be served without signing in, got %d", res.Code)
+	}
+	if !strings.Contains(res.Body.String(), `"openapi":"3.0.3"`) {
+		t.Errorf("expected an OpenAPI 3 document, got %s", res.Body.String())
+	}
+}
+
+func TestAssetsAreServed(t *testing.T) {
+	handler := (&Server{}).RegisterRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/assets/js/htmx.min.js", nil)
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	if res.Code != http.StatusOK {
+		t.Errorf("expected the assets to be served, got %d", res.Code)
+	}
+}
+
+func TestHealthzIsPublic(t *testing.T) {
+	handler := (&Server{}).RegisterRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	if res.Code != http.StatusOK || !strings.Contains(res.Body.String(), `"status":"ok"`) {
+		t.Errorf("expected the process to be reported live without signing in, got %d %s", res.Code, res.Body.String())
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	t.Setenv("METRICS_TOKEN", "scrape")
+	handler := (&Server{}).RegisterRoutes()
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/no/such/page", nil))
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if res.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the metrics to need the token, got %d", res.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer scrape")
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	body := res.Body.String()
+	for _, expected := range []string{
+		`gotrack_http_requests_total{method="GET",route="/healthz",status="200"}`,
+		`route="unmatched"`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected the metrics to contain %s, got %s", expected, body)
+		}
 	}
 }
@@ -3,8 +3,10 @@ package server
 import (
 	"fmt"
 	"go-track/cmd/web"
+	"go-track/internal/auth"
 	"go-track/internal/db"
 	"go-track/internal/github"
+	"go-track/internal/repo"
 	"log"
 	"net/http"
 	"os"
@@ -17,6 +19,10 @@ import (
 type Server struct {
 	port       int
 	webHandler *web.Handler
+	sessions   repo.SessionRepository
+	apiTokens  repo.APITokenRepository
+	access     repo.AccessRepository
+	hub        *Hub
 }
 
 func NewServer() *http.Server {
@@ -28,13 +34,24 @@ func NewServer() *http.Server {
 	if err != nil {
 		log.Fatalf("Creating GithubService failed! %e", err)
 	}
+	if err := auth.CheckCSRFSecret(); err != nil {
+		log.Fatalf("Checking the CSRF secret failed! %s", err)
+	}
 
-	webHandler := web.NewHandler(db, gh)
+	sessions := repo.NewSessionRepo(db)
+	apiTokens := repo.NewAPITokenRepo(db)
+	access := repo.NewAccessRepo(db, gh)
+	hub := NewHub()
+	webHandler := web.NewHandler(db, gh, sessions, apiTokens, access, hub)
 
 	port, _ := strconv.Atoi(os.Getenv("PORT"))
 	NewServer := &Server{
 		port:       port,
 		webHandler: webHandler,
+		sessions:   sessions,
+		apiTokens:  apiTokens,
+		access:     access,
+		hub:        hub,
 	}
 
 	// Declare Server config